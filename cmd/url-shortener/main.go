@@ -1,22 +1,31 @@
 package main
 
 import (
-	"github.com/go-chi/chi"
-	"github.com/go-chi/chi/middleware"
+	"context"
+	"errors"
 	"golang-url-shortener/internal/config"
 	"golang-url-shortener/internal/constants"
-	"golang-url-shortener/internal/http-server/handlers/redirect"
-	"golang-url-shortener/internal/http-server/handlers/url/delete"
-	"golang-url-shortener/internal/http-server/handlers/url/save"
-	"golang-url-shortener/internal/http-server/handlers/url/update"
-	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/health"
+	"golang-url-shortener/internal/healthcheck"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/http-server/router"
+	"golang-url-shortener/internal/lib/certs"
 	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/lib/metrics"
+	"golang-url-shortener/internal/lib/tracing"
+	"golang-url-shortener/internal/screening"
 	"golang-url-shortener/internal/storage/sqlite"
+	"golang-url-shortener/internal/webhooks"
 	"golang.org/x/exp/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const defaultShutdownTimeout = 10 * time.Second
+
 func main() {
 	cfg := config.MustLoad()
 
@@ -25,45 +34,257 @@ func main() {
 	log.Info("starting url-shortener", slog.String("env", cfg.Env))
 	log.Debug("debug messages enabled")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		Headers:     cfg.Tracing.Headers,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to init tracing", sl.Err(err))
+		os.Exit(1)
+	}
+
 	storage, err := sqlite.New(cfg.StoragePath)
 	if err != nil {
 		log.Error("failed to init storage", sl.Err(err))
 		os.Exit(1)
 	}
 
-	router := chi.NewRouter()
+	screener, err := screening.New(screening.Config{
+		AllowedSchemes: cfg.Screening.AllowedSchemes,
+		BlocklistPath:  cfg.Screening.BlocklistPath,
+		AllowIPHosts:   cfg.Screening.AllowIPHosts,
+	})
+	if err != nil {
+		log.Error("failed to init screening", sl.Err(err))
+		os.Exit(1)
+	}
 
-	router.Use(middleware.RequestID)
-	router.Use(logger.New(log))
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
+	// workers is cancelled on shutdown to stop the background workers.
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
 
-	router.Route("/url", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url-shortener", map[string]string{
-			cfg.HTTPServer.Login: cfg.HTTPServer.Password,
-		}))
+	probes := health.New(cfg.Health.Timeout)
+	probes.AddCheck("database", storage.Ping)
+	probes.AddCheck("migrations", storage.CheckMigrations)
 
-		r.Post("/", save.New(log, storage))
-		r.Delete("/{alias}", delete.New(log, storage))
-		r.Put("/", update.New(log, storage))
-	})
+	if cfg.Screening.BlocklistPath != "" {
+		probes.Go("screening", func() {
+			screener.Watch(workers, log, cfg.Screening.BlocklistReload)
+		})
+	}
+
+	if cfg.HealthCheck.Enabled {
+		checker := healthcheck.New(storage, healthcheck.Options{
+			Interval:    cfg.HealthCheck.Interval,
+			Concurrency: cfg.HealthCheck.Concurrency,
+			HostDelay:   cfg.HealthCheck.HostDelay,
+			Timeout:     cfg.HealthCheck.Timeout,
+			Screener:    screener,
+		})
 
-	router.Get("/{alias}", redirect.New(log, storage))
+		probes.Go("healthcheck", func() {
+			checker.Run(workers, log)
+		})
+	}
+
+	if cfg.Webhooks.Enabled {
+		sender := webhooks.NewSender(storage, webhooks.NewDispatcher(log, storage), webhooks.Options{
+			PollInterval: cfg.Webhooks.PollInterval,
+			Timeout:      cfg.Webhooks.Timeout,
+			MaxAttempts:  cfg.Webhooks.MaxAttempts,
+			BackoffBase:  cfg.Webhooks.BackoffBase,
+			BackoffMax:   cfg.Webhooks.BackoffMax,
+			Screener:     screener,
+		})
+
+		probes.Go("webhooks", func() {
+			sender.Run(workers, log)
+		})
+	}
+
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.JWT.Enabled {
+		jwtVerifier, err = auth.NewJWTVerifier(auth.JWTOptions{
+			HMACSecret:    cfg.JWT.HMACSecret,
+			PublicKeyPath: cfg.JWT.PublicKeyPath,
+			JWKSPath:      cfg.JWT.JWKSPath,
+			Issuer:        cfg.JWT.Issuer,
+			Audience:      cfg.JWT.Audience,
+			SubjectClaim:  cfg.JWT.SubjectClaim,
+			RolesClaim:    cfg.JWT.RolesClaim,
+			RoleScopes:    cfg.JWT.RoleScopes,
+		})
+		if err != nil {
+			log.Error("failed to init jwt auth", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	handler := router.New(log, cfg, storage, screener, jwtVerifier, probes)
 
-	log.Info("starting server", slog.String("address", cfg.Address))
+	// servers are stopped on shutdown next to the main server.
+	var servers []*http.Server
+	if cfg.Metrics.Enabled && cfg.Metrics.Address != "" {
+		path := cfg.Metrics.Path
+		if path == "" {
+			path = metrics.DefaultPath
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle(path, metrics.Handler())
+
+		log.Info("starting metrics server", slog.String("address", cfg.Metrics.Address))
+
+		metricsServer := &http.Server{Addr: cfg.Metrics.Address, Handler: mux}
+		servers = append(servers, metricsServer)
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to start metrics server", sl.Err(err))
+			}
+		}()
+	}
 
 	server := &http.Server{
 		Addr:         cfg.Address,
-		Handler:      router,
+		Handler:      handler,
 		ReadTimeout:  cfg.HTTPServer.Timeout,
 		WriteTimeout: cfg.HTTPServer.Timeout,
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
-		log.Error("failed to start a server")
+	if cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "" {
+		reloader, err := certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			log.Error("failed to load tls certificate", sl.Err(err))
+			os.Exit(1)
+		}
+
+		server.TLSConfig, err = certs.Config(reloader, cfg.TLS.MinVersion, cfg.TLS.CipherSuites)
+		if err != nil {
+			log.Error("failed to init tls", sl.Err(err))
+			os.Exit(1)
+		}
+
+		probes.Go("tls", func() {
+			reloader.Watch(workers, log, cfg.TLS.Reload)
+		})
+
+		if cfg.TLS.RedirectAddress != "" {
+			log.Info("starting https redirect server", slog.String("address", cfg.TLS.RedirectAddress))
+
+			redirectServer := &http.Server{
+				Addr:        cfg.TLS.RedirectAddress,
+				Handler:     certs.RedirectHandler(cfg.Address),
+				ReadTimeout: cfg.HTTPServer.Timeout,
+				IdleTimeout: cfg.HTTPServer.IdleTimeout,
+			}
+			servers = append(servers, redirectServer)
+			go func() {
+				if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Error("failed to start https redirect server", sl.Err(err))
+				}
+			}()
+		}
+	}
+
+	log.Info("starting server", slog.String("address", cfg.Address), slog.Bool("tls", server.TLSConfig != nil))
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			// the certificate comes from TLSConfig.GetCertificate
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case <-signals.Done():
+		log.Info("shutting down")
+	case err := <-serverErr:
+		log.Error("failed to start a server", sl.Err(err))
+		exitCode = 1
+	}
+	stopSignals()
+
+	shutdown(log, cfg, shutdownParts{
+		probes:      probes,
+		server:      server,
+		servers:     servers,
+		stopWorkers: stopWorkers,
+		storage:     storage,
+		tracing:     shutdownTracing,
+	})
+
+	os.Exit(exitCode)
+}
+
+type shutdownParts struct {
+	probes      *health.Health
+	server      *http.Server
+	servers     []*http.Server
+	stopWorkers context.CancelFunc
+	storage     *sqlite.Storage
+	tracing     func(context.Context) error
+}
+
+// shutdown fails the readiness, drains the requests in flight, stops the
+// background workers, closes the storage and flushes the traces, all within
+// the shutdown timeout.
+func shutdown(log *slog.Logger, cfg *config.Config, parts shutdownParts) {
+	parts.probes.Shutdown()
+	if cfg.Health.ShutdownDelay > 0 {
+		log.Info("readiness failing, waiting for load balancers", slog.Duration("delay", cfg.Health.ShutdownDelay))
+		time.Sleep(cfg.Health.ShutdownDelay)
+	}
+
+	timeout := cfg.HTTPServer.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	log.Info("draining requests", slog.Duration("timeout", timeout))
+	if err := parts.server.Shutdown(ctx); err != nil {
+		log.Error("failed to drain requests", sl.Err(err))
+	}
+	for _, other := range parts.servers {
+		if err := other.Shutdown(ctx); err != nil {
+			log.Error("failed to stop server", slog.String("address", other.Addr), sl.Err(err))
+		}
+	}
+
+	log.Info("stopping background workers")
+	parts.stopWorkers()
+	if err := parts.probes.Wait(ctx); err != nil {
+		log.Error("failed to stop background workers", sl.Err(err))
+	}
+
+	log.Info("closing storage")
+	if err := parts.storage.Close(); err != nil {
+		log.Error("failed to close storage", sl.Err(err))
+	}
+
+	log.Info("flushing traces")
+	if err := parts.tracing(ctx); err != nil {
+		log.Error("failed to flush traces", sl.Err(err))
 	}
 
+	log.Info("stopped")
 }
 
 func setupLogger(env string) *slog.Logger {
@@ -1,41 +1,221 @@
 package config
 
 import (
-	"github.com/go-yaml/yaml"
+	"flag"
+	"fmt"
+	"github.com/ilyakaznacheev/cleanenv"
 	"log"
 	"os"
 	"time"
 )
 
 type Config struct {
-	Env         string `yaml:"env" env-default:"local"`
-	StoragePath string `yaml:"storage_path" env-required:"true"`
-	HTTPServer  `yaml:"http_server"`
+	Env         string `yaml:"env" env:"ENV" env-default:"local"`
+	StoragePath string `yaml:"storage_path" env:"STORAGE_PATH"`
+	HTTPServer  `yaml:"http_server" env-prefix:"HTTP_SERVER_"`
+	Redirect    Redirect    `yaml:"redirect" env-prefix:"REDIRECT_"`
+	Screening   Screening   `yaml:"screening" env-prefix:"SCREENING_"`
+	HealthCheck HealthCheck `yaml:"health_check" env-prefix:"HEALTH_CHECK_"`
+	JWT         JWT         `yaml:"jwt" env-prefix:"JWT_"`
+	RateLimit   RateLimit   `yaml:"rate_limit" env-prefix:"RATE_LIMIT_"`
+	Webhooks    Webhooks    `yaml:"webhooks" env-prefix:"WEBHOOKS_"`
+	Metrics     Metrics     `yaml:"metrics" env-prefix:"METRICS_"`
+	Tracing     Tracing     `yaml:"tracing" env-prefix:"TRACING_"`
+	Health      Health      `yaml:"health" env-prefix:"HEALTH_"`
 }
 
 type HTTPServer struct {
-	Address     string        `yaml:"address" env-default:"localhost:8080"`
-	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
-	Login       string        `yaml:"login"`
-	Password    string        `yaml:"password"`
+	Address     string        `yaml:"address" env:"ADDRESS" env-default:"localhost:8080"`
+	Timeout     time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"4s"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"IDLE_TIMEOUT" env-default:"60s"`
+	Login       string        `yaml:"login" env:"LOGIN"`
+	Password    string        `yaml:"password" env:"PASSWORD"`
+	BaseURL     string        `yaml:"base_url" env:"BASE_URL"`
+	// Domains are short domains served next to the host of BaseURL,
+	// each with its own aliases.
+	Domains []string `yaml:"domains" env:"DOMAINS"`
+	// ShutdownTimeout bounds draining the requests in flight and stopping
+	// the background workers on SIGINT or SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" env-default:"10s"`
+	TLS             TLS           `yaml:"tls" env-prefix:"TLS_"`
 }
 
+// TLS serves HTTPS when CertFile and KeyFile are set, both PEM. The files
+// are reloaded every Reload when they change, so certificates can be
+// rotated without a restart.
+type TLS struct {
+	CertFile string        `yaml:"cert_file" env:"CERT_FILE"`
+	KeyFile  string        `yaml:"key_file" env:"KEY_FILE"`
+	Reload   time.Duration `yaml:"reload" env:"RELOAD" env-default:"1m"`
+	// MinVersion is one of 1.0, 1.1, 1.2 and 1.3.
+	MinVersion string `yaml:"min_version" env:"MIN_VERSION" env-default:"1.2"`
+	// CipherSuites limit the TLS 1.0-1.2 suites by name, like
+	// TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256. Go's defaults when empty.
+	CipherSuites []string `yaml:"cipher_suites" env:"CIPHER_SUITES"`
+	// RedirectAddress, when set, serves plain HTTP redirecting to HTTPS.
+	RedirectAddress string `yaml:"redirect_address" env:"REDIRECT_ADDRESS"`
+}
+
+type Redirect struct {
+	DefaultType            int           `yaml:"default_type" env:"DEFAULT_TYPE" env-default:"302"`
+	CookieSecret           string        `yaml:"cookie_secret" env:"COOKIE_SECRET"`
+	PasswordCookieTTL      time.Duration `yaml:"password_cookie_ttl" env:"PASSWORD_COOKIE_TTL" env-default:"1h"`
+	PasswordAttempts       int           `yaml:"password_attempts" env:"PASSWORD_ATTEMPTS" env-default:"5"`
+	PasswordAttemptsWindow time.Duration `yaml:"password_attempts_window" env:"PASSWORD_ATTEMPTS_WINDOW" env-default:"15m"`
+	VariantCookieTTL       time.Duration `yaml:"variant_cookie_ttl" env:"VARIANT_COOKIE_TTL" env-default:"720h"`
+	NotActiveURL           string        `yaml:"not_active_url" env:"NOT_ACTIVE_URL"`
+	NotActiveStatus        int           `yaml:"not_active_status" env:"NOT_ACTIVE_STATUS" env-default:"404"`
+	// MaxChainDepth is how many short links of the service a saved url may
+	// go through before reaching its destination.
+	MaxChainDepth int `yaml:"max_chain_depth" env:"MAX_CHAIN_DEPTH" env-default:"3"`
+}
+
+type Screening struct {
+	AllowedSchemes []string `yaml:"allowed_schemes" env:"ALLOWED_SCHEMES" env-default:"http,https"`
+	// BlocklistPath is a file with a domain pattern per line, *.example.com
+	// blocks the subdomains. It's reloaded when changed.
+	BlocklistPath   string        `yaml:"blocklist_path" env:"BLOCKLIST_PATH"`
+	BlocklistReload time.Duration `yaml:"blocklist_reload" env:"BLOCKLIST_RELOAD" env-default:"30s"`
+	AllowIPHosts    bool          `yaml:"allow_ip_hosts" env:"ALLOW_IP_HOSTS"`
+}
+
+// HealthCheck configures the background checker of destination urls.
+type HealthCheck struct {
+	Enabled  bool          `yaml:"enabled" env:"ENABLED"`
+	Interval time.Duration `yaml:"interval" env:"INTERVAL" env-default:"1h"`
+	// Concurrency is how many hosts are checked at once, HostDelay is the
+	// pause between requests to the same host.
+	Concurrency int           `yaml:"concurrency" env:"CONCURRENCY" env-default:"4"`
+	HostDelay   time.Duration `yaml:"host_delay" env:"HOST_DELAY" env-default:"1s"`
+	Timeout     time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"10s"`
+}
+
+// JWT configures bearer JWTs on the management API, next to API keys and
+// the basic auth login.
+type JWT struct {
+	Enabled bool `yaml:"enabled" env:"ENABLED"`
+	// HMACSecret verifies HS256 tokens, PublicKeyPath (PEM) and JWKSPath
+	// RS256 ones. JWKS keys are picked by kid.
+	HMACSecret    string `yaml:"hmac_secret" env:"HMAC_SECRET"`
+	PublicKeyPath string `yaml:"public_key_path" env:"PUBLIC_KEY_PATH"`
+	JWKSPath      string `yaml:"jwks_path" env:"JWKS_PATH"`
+	Issuer        string `yaml:"issuer" env:"ISSUER"`
+	Audience      string `yaml:"audience" env:"AUDIENCE"`
+	SubjectClaim  string `yaml:"subject_claim" env:"SUBJECT_CLAIM" env-default:"sub"`
+	RolesClaim    string `yaml:"roles_claim" env:"ROLES_CLAIM" env-default:"roles"`
+	// RoleScopes grants API scopes such as links:write to roles.
+	RoleScopes map[string][]string `yaml:"role_scopes"`
+}
+
+// RateLimit configures the token buckets of the management API and of the
+// redirects. Auth limits the API by client IP before authentication, so
+// guessing credentials is throttled, API then limits it per API key or user.
+// Redirects are limited by client IP. A limit without requests is off.
+type RateLimit struct {
+	// TrustProxy takes the client IP from X-Forwarded-For and X-Real-IP.
+	TrustProxy bool `yaml:"trust_proxy" env:"TRUST_PROXY"`
+	// TrustedProxies are addresses or CIDR ranges of the proxies in front
+	// of the service, the headers are only read from them. Without any the
+	// peer is the proxy.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	Auth           Limit    `yaml:"auth" env-prefix:"AUTH_"`
+	API            Limit    `yaml:"api" env-prefix:"API_"`
+	Redirect       Limit    `yaml:"redirect" env-prefix:"REDIRECT_"`
+}
+
+// Limit allows Requests per Period with bursts of up to Burst requests,
+// Requests by default.
+type Limit struct {
+	Requests int           `yaml:"requests" env:"REQUESTS"`
+	Period   time.Duration `yaml:"period" env:"PERIOD" env-default:"1m"`
+	Burst    int           `yaml:"burst" env:"BURST"`
+}
+
+// Webhooks configures the delivery of link events to the subscribed urls.
+type Webhooks struct {
+	Enabled      bool          `yaml:"enabled" env:"ENABLED"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"POLL_INTERVAL" env-default:"1s"`
+	Timeout      time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"10s"`
+	// MaxAttempts before a delivery goes to the dead letters. Retries wait
+	// BackoffBase, doubled after every attempt up to BackoffMax.
+	MaxAttempts int           `yaml:"max_attempts" env:"MAX_ATTEMPTS" env-default:"8"`
+	BackoffBase time.Duration `yaml:"backoff_base" env:"BACKOFF_BASE" env-default:"10s"`
+	BackoffMax  time.Duration `yaml:"backoff_max" env:"BACKOFF_MAX" env-default:"1h"`
+}
+
+// Metrics configures the Prometheus endpoint. It's served on the API
+// listener unless Address sets a separate one.
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" env:"ENABLED"`
+	Address string `yaml:"address" env:"ADDRESS"`
+	Path    string `yaml:"path" env:"PATH" env-default:"/metrics"`
+}
+
+// Tracing configures OpenTelemetry. Exporter is otlp for an OTLP/HTTP
+// collector at Endpoint, stdout, or empty to record no spans; the W3C trace
+// context of requests is propagated either way.
+type Tracing struct {
+	Exporter    string            `yaml:"exporter" env:"EXPORTER"`
+	Endpoint    string            `yaml:"endpoint" env:"ENDPOINT" env-default:"localhost:4318"`
+	Insecure    bool              `yaml:"insecure" env:"INSECURE"`
+	Headers     map[string]string `yaml:"headers" env:"HEADERS"`
+	ServiceName string            `yaml:"service_name" env:"SERVICE_NAME" env-default:"url-shortener"`
+	SampleRatio float64           `yaml:"sample_ratio" env:"SAMPLE_RATIO" env-default:"1"`
+}
+
+// Health configures the /readyz probe, Timeout bounds its checks. On
+// shutdown the probe fails for ShutdownDelay before the server stops
+// accepting connections, so load balancers have time to notice.
+type Health struct {
+	Timeout       time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"2s"`
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+}
+
+// MustLoad loads the config from the file passed with --config, or
+// CONFIG_PATH when the flag isn't set, and exits on any problem.
 func MustLoad() *Config {
-	configPath := os.Getenv("CONFIG_PATH")
+	configPath := fetchConfigPath()
 	if configPath == "" {
-		log.Fatal("CONFIG_PATH is not set")
+		log.Fatal("config path is not set, pass --config or set CONFIG_PATH")
 	}
 
-	configFile, err := os.ReadFile(configPath)
+	cfg, err := Load(configPath)
 	if err != nil {
-		log.Fatalf("Error reading config file: %v", err.Error())
+		log.Fatalf("Error loading config: %v", err)
 	}
 
+	return cfg
+}
+
+// Load reads the yaml file at path, overrides its values with the set
+// environment variables, applies the defaults of the fields left empty and
+// validates the result. Environment variables are named after the yaml
+// keys, like HTTP_SERVER_TLS_CERT_FILE for http_server.tls.cert_file.
+func Load(path string) (*Config, error) {
+	const op = "config.Load"
+
 	var cfg Config
-	err = yaml.Unmarshal(configFile, &cfg)
-	if err != nil {
-		log.Fatalf("Error marshalling config: %v", err.Error())
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
 	}
-	return &cfg
+
+	return &cfg, nil
+}
+
+func fetchConfigPath() string {
+	var configPath string
+
+	flag.StringVar(&configPath, "config", "", "path to the config file")
+	flag.Usage = cleanenv.FUsage(flag.CommandLine.Output(), &Config{}, nil, flag.Usage)
+	flag.Parse()
+
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG_PATH")
+	}
+
+	return configPath
 }
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+env: "prod"
+storage_path: "./storage.db"
+http_server:
+  timeout: 8s
+  tls:
+    cert_file: "cert.pem"
+    key_file: "key.pem"
+tracing:
+  headers:
+    x-team: "links"
+`)
+	t.Setenv("HTTP_SERVER_ADDRESS", "0.0.0.0:8443")
+	t.Setenv("HTTP_SERVER_TLS_MIN_VERSION", "1.3")
+	t.Setenv("REDIRECT_DEFAULT_TYPE", "301")
+	t.Setenv("SCREENING_ALLOWED_SCHEMES", "https,ftp")
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	// values of the file
+	require.Equal(t, "prod", cfg.Env)
+	require.Equal(t, 8*time.Second, cfg.HTTPServer.Timeout)
+	require.Equal(t, "cert.pem", cfg.TLS.CertFile)
+	require.Equal(t, map[string]string{"x-team": "links"}, cfg.Tracing.Headers)
+
+	// environment overrides
+	require.Equal(t, "0.0.0.0:8443", cfg.Address)
+	require.Equal(t, "1.3", cfg.TLS.MinVersion)
+	require.Equal(t, 301, cfg.Redirect.DefaultType)
+	require.Equal(t, []string{"https", "ftp"}, cfg.Screening.AllowedSchemes)
+
+	// defaults
+	require.Equal(t, 60*time.Second, cfg.IdleTimeout)
+	require.Equal(t, 10*time.Second, cfg.ShutdownTimeout)
+	require.Equal(t, 404, cfg.Redirect.NotActiveStatus)
+	require.Equal(t, time.Minute, cfg.RateLimit.API.Period)
+	require.Equal(t, "/metrics", cfg.Metrics.Path)
+	require.Equal(t, 1.0, cfg.Tracing.SampleRatio)
+	require.Equal(t, 2*time.Second, cfg.Health.Timeout)
+}
+
+func TestLoadProblems(t *testing.T) {
+	path := writeConfig(t, `
+env: "staging"
+http_server:
+  address: "8080"
+  tls:
+    key_file: "key.pem"
+    min_version: "1.4"
+    cipher_suites: ["TLS_RSA_WITH_RC4_128_SHA", "TLS_AES_128_GCM_SHA256"]
+redirect:
+  default_type: 200
+tracing:
+  exporter: "jaeger"
+`)
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+
+	_, err := Load(path)
+	require.Error(t, err)
+
+	// every problem is reported, not just the first one
+	want := []string{
+		`config.Load : env: unknown env "staging", expected local, dev or prod`,
+		`storage_path: is required`,
+		`http_server.address: "8080" is not host:port`,
+		`http_server.tls: cert_file and key_file are set together`,
+		`http_server.tls: unknown min version "1.4", expected 1.0, 1.1, 1.2 or 1.3`,
+		`http_server.tls: unknown or insecure cipher suite TLS_RSA_WITH_RC4_128_SHA`,
+		`http_server.tls: cipher suite TLS_AES_128_GCM_SHA256 is TLS 1.3 only, those are always enabled`,
+		`redirect.default_type: 200 is not a redirect status, expected 301, 302, 303, 307 or 308`,
+		`tracing.exporter: unknown exporter "jaeger", expected stdout, otlp or empty`,
+		`tracing.sample_ratio: 2 is not between 0 and 1`,
+	}
+	require.Equal(t, want, strings.Split(err.Error(), "\n"))
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRepoConfig(t *testing.T) {
+	_, err := Load("../../config/local_git.yaml")
+	require.NoError(t, err)
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	return path
+}
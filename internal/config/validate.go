@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"golang-url-shortener/internal/constants"
+	"golang-url-shortener/internal/lib/certs"
+	"golang-url-shortener/internal/lib/redirecttype"
+	"golang-url-shortener/internal/lib/tracing"
+	"net"
+	"strings"
+	"time"
+)
+
+// Validate reports every problem of the config at once, joined into one
+// error, keyed by the yaml path of the values.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	switch c.Env {
+	case constants.EnvLocal, constants.EnvDev, constants.EnvProd:
+	default:
+		v.addf("env", "unknown env %q, expected %s, %s or %s", c.Env, constants.EnvLocal, constants.EnvDev, constants.EnvProd)
+	}
+
+	if strings.TrimSpace(c.StoragePath) == "" {
+		v.addf("storage_path", "is required")
+	}
+
+	v.address("http_server.address", c.Address, true)
+	v.positive("http_server.timeout", c.HTTPServer.Timeout)
+	v.notNegative("http_server.idle_timeout", c.IdleTimeout)
+	v.notNegative("http_server.shutdown_timeout", c.ShutdownTimeout)
+	if (c.Login == "") != (c.Password == "") {
+		v.addf("http_server.login", "login and password are set together")
+	}
+
+	if c.TLS.CertFile != "" || c.TLS.KeyFile != "" {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			v.addf("http_server.tls", "cert_file and key_file are set together")
+		}
+		// checked by the functions certs.Config parses them with
+		if c.TLS.MinVersion != "" {
+			if _, err := certs.MinVersion(c.TLS.MinVersion); err != nil {
+				v.addf("http_server.tls", "%v", err)
+			}
+		}
+		for _, name := range c.TLS.CipherSuites {
+			if _, err := certs.CipherSuite(name); err != nil {
+				v.addf("http_server.tls", "%v", err)
+			}
+		}
+		v.address("http_server.tls.redirect_address", c.TLS.RedirectAddress, false)
+	} else if c.TLS.RedirectAddress != "" {
+		v.addf("http_server.tls.redirect_address", "needs cert_file and key_file")
+	}
+
+	if !redirecttype.Valid(c.Redirect.DefaultType) {
+		v.addf("redirect.default_type", "%d is not a redirect status, expected 301, 302, 303, 307 or 308", c.Redirect.DefaultType)
+	}
+	if c.Redirect.NotActiveStatus < 400 || c.Redirect.NotActiveStatus > 599 {
+		v.addf("redirect.not_active_status", "%d is not an error status", c.Redirect.NotActiveStatus)
+	}
+	if c.Redirect.MaxChainDepth < 0 {
+		v.addf("redirect.max_chain_depth", "can't be negative")
+	}
+
+	if c.HealthCheck.Enabled {
+		v.positive("health_check.interval", c.HealthCheck.Interval)
+		if c.HealthCheck.Concurrency < 1 {
+			v.addf("health_check.concurrency", "must be at least 1")
+		}
+	}
+
+	if c.JWT.Enabled && c.JWT.HMACSecret == "" && c.JWT.PublicKeyPath == "" && c.JWT.JWKSPath == "" {
+		v.addf("jwt", "enabled without hmac_secret, public_key_path or jwks_path")
+	}
+
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		if cidrErr != nil && net.ParseIP(proxy) == nil {
+			v.addf("rate_limit.trusted_proxies", "%q is not an address or a CIDR range", proxy)
+		}
+	}
+	v.limit("rate_limit.auth", c.RateLimit.Auth)
+	v.limit("rate_limit.api", c.RateLimit.API)
+	v.limit("rate_limit.redirect", c.RateLimit.Redirect)
+
+	if c.Webhooks.Enabled {
+		v.positive("webhooks.poll_interval", c.Webhooks.PollInterval)
+		if c.Webhooks.MaxAttempts < 1 {
+			v.addf("webhooks.max_attempts", "must be at least 1")
+		}
+		if c.Webhooks.BackoffMax < c.Webhooks.BackoffBase {
+			v.addf("webhooks.backoff_max", "is shorter than backoff_base")
+		}
+	}
+
+	if c.Metrics.Enabled {
+		if !strings.HasPrefix(c.Metrics.Path, "/") {
+			v.addf("metrics.path", "%q doesn't start with /", c.Metrics.Path)
+		}
+		v.address("metrics.address", c.Metrics.Address, false)
+	}
+
+	if err := tracing.CheckExporter(c.Tracing.Exporter); err != nil {
+		v.addf("tracing.exporter", "%v", err)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio", "%v is not between 0 and 1", c.Tracing.SampleRatio)
+	}
+
+	v.notNegative("health.timeout", c.Health.Timeout)
+	v.notNegative("health.shutdown_delay", c.Health.ShutdownDelay)
+
+	return errors.Join(v.problems...)
+}
+
+type validator struct {
+	problems []error
+}
+
+func (v *validator) addf(key, format string, args ...any) {
+	v.problems = append(v.problems, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+}
+
+// address checks that addr is host:port, an empty addr is only a problem
+// when required.
+func (v *validator) address(key, addr string, required bool) {
+	if addr == "" {
+		if required {
+			v.addf(key, "is required")
+		}
+		return
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		v.addf(key, "%q is not host:port", addr)
+	}
+}
+
+func (v *validator) limit(key string, limit Limit) {
+	if limit.Requests < 0 || limit.Burst < 0 {
+		v.addf(key, "requests and burst can't be negative")
+	}
+	v.positive(key+".period", limit.Period)
+}
+
+func (v *validator) positive(key string, d time.Duration) {
+	if d <= 0 {
+		v.addf(key, "must be positive")
+	}
+}
+
+func (v *validator) notNegative(key string, d time.Duration) {
+	if d < 0 {
+		v.addf(key, "can't be negative")
+	}
+}
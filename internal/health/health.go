@@ -0,0 +1,217 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses of the report and its components.
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// ComponentShutdown fails the readiness once the service is shutting down.
+const ComponentShutdown = "shutdown"
+
+var (
+	errShuttingDown  = errors.New("shutting down")
+	errWorkerStopped = errors.New("worker stopped")
+)
+
+// Check returns an error when the component can't serve traffic.
+type Check func(ctx context.Context) error
+
+type Component struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency,omitempty"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Health tracks the readiness of the service: the checks of its
+// dependencies, the background workers and the shutdown. A nil *Health is
+// always ready.
+type Health struct {
+	timeout time.Duration
+
+	mu      sync.RWMutex
+	checks  map[string]Check
+	workers map[string]*atomic.Bool
+	running sync.WaitGroup
+
+	shuttingDown atomic.Bool
+}
+
+// New builds a Health whose checks time out after timeout, 2s when it's
+// not positive.
+func New(timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	return &Health{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+		workers: make(map[string]*atomic.Bool),
+	}
+}
+
+// AddCheck runs check as the component name on every readiness probe.
+func (h *Health) AddCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks[name] = check
+}
+
+// Go runs fn in a goroutine as the worker name, the readiness fails once
+// fn returns before the shutdown.
+func (h *Health) Go(name string, fn func()) {
+	running := &atomic.Bool{}
+	running.Store(true)
+
+	h.mu.Lock()
+	h.workers[name] = running
+	h.mu.Unlock()
+
+	h.running.Add(1)
+	go func() {
+		defer h.running.Done()
+		defer running.Store(false)
+		fn()
+	}()
+}
+
+// Wait blocks until the workers started with Go return or ctx is done.
+func (h *Health) Wait(ctx context.Context) error {
+	if h == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown fails the readiness from now on, so load balancers stop sending
+// traffic before the server stops.
+func (h *Health) Shutdown() {
+	if h == nil {
+		return
+	}
+
+	h.shuttingDown.Store(true)
+}
+
+// Ready runs the checks concurrently and reports each component.
+func (h *Health) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Components: make(map[string]Component)}
+	if h == nil {
+		return report
+	}
+
+	shuttingDown := h.shuttingDown.Load()
+	if shuttingDown {
+		report.add(ComponentShutdown, errShuttingDown, 0)
+	}
+
+	h.mu.RLock()
+	checks := make(map[string]Check, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	for name, running := range h.workers {
+		var err error
+		if !running.Load() && !shuttingDown {
+			err = errWorkerStopped
+		}
+		report.add("worker:"+name, err, 0)
+	}
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check(ctx)
+
+			mu.Lock()
+			report.add(name, err, time.Since(start))
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (r *Report) add(name string, err error, latency time.Duration) {
+	component := Component{Status: StatusOK}
+	if latency > 0 {
+		component.Latency = latency.String()
+	}
+	if err != nil {
+		component.Status = StatusFailing
+		component.Error = err.Error()
+		r.Status = StatusFailing
+	}
+
+	r.Components[name] = component
+}
+
+// LiveHandler answers as long as the process serves requests.
+func LiveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Report{Status: StatusOK, Components: map[string]Component{}})
+	}
+}
+
+// ReadyHandler answers 200 with the report when every component is ok and
+// 503 otherwise.
+func (h *Health) ReadyHandler(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "health.ReadyHandler"
+
+		report := h.Ready(r.Context())
+
+		if report.Status != StatusOK {
+			logger.ForRequest(log, r).Warn("not ready",
+				slog.String("op", op),
+				slog.Any("components", report.Components),
+			)
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+
+		render.JSON(w, r, report)
+	}
+}
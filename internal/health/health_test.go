@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	h := New(50 * time.Millisecond)
+	h.AddCheck("database", func(ctx context.Context) error { return nil })
+	h.AddCheck("migrations", func(ctx context.Context) error { return errors.New("schema version 3, expected 4") })
+	h.AddCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := h.Ready(context.Background())
+
+	require.Equal(t, StatusFailing, report.Status)
+	require.Equal(t, StatusOK, report.Components["database"].Status)
+	require.Equal(t, Component{Status: StatusFailing, Error: "schema version 3, expected 4"}, withoutLatency(report.Components["migrations"]))
+	require.Equal(t, Component{Status: StatusFailing, Error: context.DeadlineExceeded.Error()}, withoutLatency(report.Components["slow"]))
+}
+
+func TestWorkers(t *testing.T) {
+	h := New(0)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	h.Go("screening", func() { <-stop })
+	h.Go("webhooks", func() {
+		<-stop
+		close(stopped)
+	})
+
+	report := h.Ready(context.Background())
+	require.Equal(t, StatusOK, report.Status)
+	require.Equal(t, StatusOK, report.Components["worker:screening"].Status)
+
+	close(stop)
+	<-stopped
+	require.Eventually(t, func() bool {
+		return h.Ready(context.Background()).Components["worker:webhooks"].Status == StatusFailing
+	}, time.Second, time.Millisecond)
+
+	// workers are expected to stop once the service is shutting down
+	h.Shutdown()
+	report = h.Ready(context.Background())
+	require.Equal(t, StatusFailing, report.Status)
+	require.Equal(t, StatusOK, report.Components["worker:webhooks"].Status)
+	require.Equal(t, Component{Status: StatusFailing, Error: "shutting down"}, report.Components[ComponentShutdown])
+}
+
+func TestWait(t *testing.T) {
+	h := New(0)
+	stop := make(chan struct{})
+	h.Go("webhooks", func() { <-stop })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, h.Wait(ctx), context.DeadlineExceeded)
+
+	close(stop)
+	require.NoError(t, h.Wait(context.Background()))
+}
+
+func TestHandlers(t *testing.T) {
+	var nilHealth *Health
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		code    int
+		status  string
+	}{
+		{name: "live", handler: LiveHandler(), code: http.StatusOK, status: StatusOK},
+		{name: "ready without checks", handler: nilHealth.ReadyHandler(slogdiscard.NewDiscardLogger()), code: http.StatusOK, status: StatusOK},
+		{name: "ready", handler: New(0).ReadyHandler(slogdiscard.NewDiscardLogger()), code: http.StatusOK, status: StatusOK},
+		{name: "shutting down", handler: shuttingDown().ReadyHandler(slogdiscard.NewDiscardLogger()), code: http.StatusServiceUnavailable, status: StatusFailing},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			require.Equal(t, tc.code, rr.Code)
+
+			var report Report
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+			require.Equal(t, tc.status, report.Status)
+		})
+	}
+}
+
+func shuttingDown() *Health {
+	h := New(0)
+	h.Shutdown()
+
+	return h
+}
+
+func withoutLatency(c Component) Component {
+	c.Latency = ""
+
+	return c
+}
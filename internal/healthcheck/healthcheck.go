@@ -0,0 +1,244 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/screening"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	DestinationURLs(ctx context.Context) ([]string, error)
+	SaveCheck(ctx context.Context, check storage.Check) error
+	PruneChecks(ctx context.Context, keep []string) (int64, error)
+}
+
+type Options struct {
+	// Interval between two rounds of checks, 1h when zero.
+	Interval time.Duration
+	// Concurrency is how many hosts are checked at once, 4 when zero.
+	Concurrency int
+	// HostDelay is the pause between two requests to the same host, 1s when zero.
+	HostDelay time.Duration
+	// Timeout of a single request, 10s when zero.
+	Timeout time.Duration
+	// UserAgent sent with the requests.
+	UserAgent string
+
+	// Screener skips destinations that are no longer allowed and stops
+	// redirects to them. With a Screener the default client refuses to
+	// connect to private addresses, names resolving to them included, so
+	// the checker can't be pointed at private hosts.
+	Screener *screening.Screener
+
+	// Client replaces the default http client, e.g. in tests. Its transport
+	// is used as is.
+	Client *http.Client
+}
+
+func (o Options) withDefaults() Options {
+	if o.Interval <= 0 {
+		o.Interval = time.Hour
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.HostDelay <= 0 {
+		o.HostDelay = time.Second
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = "url-shortener-healthcheck/1.0"
+	}
+
+	return o
+}
+
+// Checker periodically requests every stored destination and records the
+// status code and latency of the response.
+type Checker struct {
+	store  Store
+	opts   Options
+	client *http.Client
+}
+
+func New(store Store, opts Options) *Checker {
+	opts = opts.withDefaults()
+
+	client := &http.Client{}
+	if opts.Client != nil {
+		*client = *opts.Client
+	} else if opts.Screener != nil {
+		client.Transport = screening.Transport()
+	}
+	client.Timeout = opts.Timeout
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after %d redirects", len(via))
+		}
+
+		return opts.Screener.Check(req.URL.String())
+	}
+
+	return &Checker{store: store, opts: opts, client: client}
+}
+
+// Run checks all destinations right away and then every interval until ctx is done.
+func (c *Checker) Run(ctx context.Context, log *slog.Logger) {
+	const op = "healthcheck.Run"
+
+	log = log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CheckAll(ctx, log); err != nil {
+			log.Error("failed to check destinations", sl.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll runs one round of checks and returns once it's done. Hosts are
+// checked in parallel up to Concurrency, the urls of a host one by one. The
+// checks of urls no link redirects to anymore are pruned at the end.
+func (c *Checker) CheckAll(ctx context.Context, log *slog.Logger) error {
+	const op = "healthcheck.CheckAll"
+
+	urls, err := c.store.DestinationURLs(ctx)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	hosts := make(map[string][]string)
+	for _, rawURL := range urls {
+		if err := c.opts.Screener.Check(rawURL); err != nil {
+			log.Debug("destination skipped", sl.Err(err))
+			continue
+		}
+
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Host)
+		hosts[host] = append(hosts[host], rawURL)
+	}
+
+	sem := make(chan struct{}, c.opts.Concurrency)
+	var wg sync.WaitGroup
+
+	for _, hostURLs := range hosts {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(hostURLs []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			c.checkHost(ctx, log, hostURLs)
+		}(hostURLs)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pruned, err := c.store.PruneChecks(ctx, urls)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	log.Info("destinations checked",
+		slog.Int("urls", len(urls)),
+		slog.Int("hosts", len(hosts)),
+		slog.Int64("pruned", pruned))
+
+	return nil
+}
+
+func (c *Checker) checkHost(ctx context.Context, log *slog.Logger, urls []string) {
+	for i, rawURL := range urls {
+		if i > 0 {
+			select {
+			case <-time.After(c.opts.HostDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		check := c.Check(ctx, rawURL)
+		if ctx.Err() != nil {
+			// an interrupted request says nothing about the destination
+			return
+		}
+
+		if check.Broken() {
+			log.Info("destination is broken",
+				slog.String("url", rawURL),
+				slog.Int("status", check.StatusCode),
+				slog.String("error", check.Error))
+		}
+
+		if err := c.store.SaveCheck(ctx, check); err != nil {
+			log.Error("failed to save check", slog.String("url", rawURL), sl.Err(err))
+		}
+	}
+}
+
+// Check requests rawURL with HEAD and retries with GET when that fails,
+// as some servers don't implement HEAD properly.
+func (c *Checker) Check(ctx context.Context, rawURL string) storage.Check {
+	check := c.request(ctx, http.MethodHead, rawURL)
+	if check.Broken() {
+		check = c.request(ctx, http.MethodGet, rawURL)
+	}
+
+	return check
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) storage.Check {
+	check := storage.Check{URL: rawURL, CheckedAt: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+
+	resp, err := c.client.Do(req)
+	check.Latency = time.Since(check.CheckedAt)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	// the body isn't needed, closing it unread drops the connection of GETs
+	resp.Body.Close()
+
+	check.StatusCode = resp.StatusCode
+
+	return check
+}
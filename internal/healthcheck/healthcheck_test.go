@@ -0,0 +1,197 @@
+package healthcheck
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/screening"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	mu     sync.Mutex
+	urls   []string
+	checks map[string]storage.Check
+}
+
+func (s *memStore) DestinationURLs(_ context.Context) ([]string, error) {
+	return s.urls, nil
+}
+
+func (s *memStore) SaveCheck(_ context.Context, check storage.Check) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checks[check.URL] = check
+	return nil
+}
+
+func (s *memStore) PruneChecks(_ context.Context, keep []string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make(map[string]bool, len(keep))
+	for _, u := range keep {
+		kept[u] = true
+	}
+
+	var pruned int64
+	for u := range s.checks {
+		if !kept[u] {
+			delete(s.checks, u)
+			pruned++
+		}
+	}
+
+	return pruned, nil
+}
+
+func TestCheckAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	store := &memStore{
+		urls: []string{
+			server.URL + "/ok",
+			server.URL + "/moved",
+			server.URL + "/no-head",
+			server.URL + "/error",
+			server.URL + "/gone",
+			down.URL + "/ok",
+		},
+		checks: map[string]storage.Check{
+			// left by a link that changed its url since the last round
+			"https://old.example.com/page": {URL: "https://old.example.com/page", StatusCode: http.StatusNotFound},
+		},
+	}
+
+	checker := New(store, Options{HostDelay: time.Millisecond})
+	require.NoError(t, checker.CheckAll(context.Background(), slogdiscard.NewDiscardLogger()))
+
+	tests := map[string]struct {
+		status int
+		broken bool
+	}{
+		server.URL + "/ok":      {status: http.StatusOK},
+		server.URL + "/moved":   {status: http.StatusOK},
+		server.URL + "/no-head": {status: http.StatusOK},
+		server.URL + "/error":   {status: http.StatusInternalServerError, broken: true},
+		server.URL + "/gone":    {status: http.StatusNotFound, broken: true},
+		down.URL + "/ok":        {broken: true},
+	}
+
+	require.Len(t, store.checks, len(tests))
+	for rawURL, tc := range tests {
+		check := store.checks[rawURL]
+		require.Equal(t, tc.status, check.StatusCode, rawURL)
+		require.Equal(t, tc.broken, check.Broken(), rawURL)
+		require.False(t, check.CheckedAt.IsZero(), rawURL)
+	}
+	require.NotEmpty(t, store.checks[down.URL+"/ok"].Error)
+	require.NotContains(t, store.checks, "https://old.example.com/page")
+}
+
+func TestCheckPrivateAddress(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
+	// Check doesn't screen the url, the address localhost resolves to is refused
+	checker := New(&memStore{}, Options{Screener: screener})
+	check := checker.Check(context.Background(), strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+
+	require.True(t, check.Broken())
+	require.Contains(t, check.Error, screening.ErrPrivateAddress.Error())
+	require.Zero(t, atomic.LoadInt32(&hits))
+}
+
+func TestCheckAllPoliteness(t *testing.T) {
+	const hostDelay = 50 * time.Millisecond
+
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	store := &memStore{
+		urls:   []string{server.URL + "/a", server.URL + "/b", server.URL + "/c"},
+		checks: make(map[string]storage.Check),
+	}
+
+	checker := New(store, Options{HostDelay: hostDelay, Concurrency: 10})
+	require.NoError(t, checker.CheckAll(context.Background(), slogdiscard.NewDiscardLogger()))
+
+	require.Len(t, times, 3)
+	for i := 1; i < len(times); i++ {
+		require.GreaterOrEqual(t, times[i].Sub(times[i-1]), hostDelay)
+	}
+}
+
+func TestCheckAllConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	store := &memStore{checks: make(map[string]storage.Check)}
+	for i := 0; i < 6; i++ {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		store.urls = append(store.urls, server.URL)
+	}
+
+	checker := New(store, Options{Concurrency: 2})
+	require.NoError(t, checker.CheckAll(context.Background(), slogdiscard.NewDiscardLogger()))
+
+	require.Len(t, store.checks, 6)
+	require.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+}
@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type Entry struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	Domain     string          `json:"domain,omitempty"`
+	Alias      string          `json:"alias"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	RemoteAddr string          `json:"remote_addr,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type Response struct {
+	response.Response
+	Entries []Entry `json:"entries"`
+}
+
+//go:generate mockgen -source=audit.go -destination=mocks/auditmock.go -package=mocks
+type AuditLister interface {
+	AuditLog(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error)
+}
+
+// New lists the audit log newest first, filtered by the actor, action,
+// domain, alias, from and until query parameters. Older pages are
+// requested with before_id set to the last id received.
+func New(log *slog.Logger, auditLister AuditLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.audit.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			log.Info("invalid filter", sl.Err(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		auditEntries, err := auditLister.AuditLog(r.Context(), filter)
+		if err != nil {
+			log.Error("failed to query audit log", sl.Err(err))
+			render.JSON(w, r, response.Error("internal error"))
+			return
+		}
+
+		entries := make([]Entry, 0, len(auditEntries))
+		for _, entry := range auditEntries {
+			entries = append(entries, Entry{
+				ID:         entry.ID,
+				Actor:      entry.Actor,
+				Action:     entry.Action,
+				Domain:     entry.Domain,
+				Alias:      entry.Alias,
+				Before:     rawJSON(entry.Before),
+				After:      rawJSON(entry.After),
+				RequestID:  entry.RequestID,
+				RemoteAddr: entry.RemoteAddr,
+				CreatedAt:  entry.CreatedAt,
+			})
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Entries:  entries,
+		})
+	}
+}
+
+func parseFilter(query url.Values) (storage.AuditFilter, error) {
+	filter := storage.AuditFilter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Domain: query.Get("domain"),
+		Alias:  query.Get("alias"),
+	}
+
+	switch filter.Action {
+	case "", storage.AuditCreate, storage.AuditUpdate, storage.AuditDelete:
+	default:
+		return storage.AuditFilter{}, errors.New("invalid action")
+	}
+
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return storage.AuditFilter{}, fmt.Errorf("invalid %s, expected RFC 3339", name)
+			}
+			*dst = &t
+		}
+	}
+
+	if v := query.Get("before_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return storage.AuditFilter{}, errors.New("invalid before_id")
+		}
+		filter.BeforeID = n
+	}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			return storage.AuditFilter{}, errors.New("invalid limit, expected 1 to 500")
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+
+	return json.RawMessage(s)
+}
@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/audit/mocks"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		query     string
+		filter    *storage.AuditFilter
+		entries   []storage.AuditEntry
+		mockError error
+		respError string
+		expected  []Entry
+	}{
+		{
+			name:     "empty",
+			filter:   &storage.AuditFilter{},
+			expected: []Entry{},
+		},
+		{
+			name:   "filtered",
+			query:  "?actor=admin&action=delete&alias=sale&from=2024-05-01T00:00:00Z&before_id=10&limit=5",
+			filter: &storage.AuditFilter{Actor: "admin", Action: "delete", Alias: "sale", From: &from, BeforeID: 10, Limit: 5},
+			entries: []storage.AuditEntry{{
+				ID: 9, Actor: "admin", Action: "delete", Alias: "sale",
+				Before:    `{"url":"https://shop.example.com/sale","alias":"sale"}`,
+				RequestID: "req-1", RemoteAddr: "192.0.2.1:1234", CreatedAt: createdAt,
+			}},
+			expected: []Entry{{
+				ID: 9, Actor: "admin", Action: "delete", Alias: "sale",
+				Before:    json.RawMessage(`{"url":"https://shop.example.com/sale","alias":"sale"}`),
+				RequestID: "req-1", RemoteAddr: "192.0.2.1:1234", CreatedAt: createdAt,
+			}},
+		},
+		{
+			name:      "invalid action",
+			query:     "?action=restore",
+			respError: "invalid action",
+		},
+		{
+			name:      "invalid from",
+			query:     "?from=yesterday",
+			respError: "invalid from, expected RFC 3339",
+		},
+		{
+			name:      "invalid limit",
+			query:     "?limit=1000",
+			respError: "invalid limit, expected 1 to 500",
+		},
+		{
+			name:      "error with db",
+			filter:    &storage.AuditFilter{},
+			mockError: errors.New("another error"),
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockAuditLister := mocks.NewMockAuditLister(ctrl)
+			if tc.filter != nil {
+				mockAuditLister.EXPECT().AuditLog(gomock.Any(), *tc.filter).Return(tc.entries, tc.mockError).Times(1)
+			}
+
+			rr := httptest.NewRecorder()
+			New(slogdiscard.NewDiscardLogger(), mockAuditLister).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/audit"+tc.query, nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				require.Equal(t, response.StatusError, resp.Status)
+				return
+			}
+
+			require.Equal(t, tc.expected, resp.Entries)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: audit.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAuditLister is a mock of AuditLister interface.
+type MockAuditLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditListerMockRecorder
+}
+
+// MockAuditListerMockRecorder is the mock recorder for MockAuditLister.
+type MockAuditListerMockRecorder struct {
+	mock *MockAuditLister
+}
+
+// NewMockAuditLister creates a new mock instance.
+func NewMockAuditLister(ctrl *gomock.Controller) *MockAuditLister {
+	mock := &MockAuditLister{ctrl: ctrl}
+	mock.recorder = &MockAuditListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditLister) EXPECT() *MockAuditListerMockRecorder {
+	return m.recorder
+}
+
+// AuditLog mocks base method.
+func (m *MockAuditLister) AuditLog(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditLog", ctx, filter)
+	ret0, _ := ret[0].([]storage.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuditLog indicates an expected call of AuditLog.
+func (mr *MockAuditListerMockRecorder) AuditLog(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditLog", reflect.TypeOf((*MockAuditLister)(nil).AuditLog), ctx, filter)
+}
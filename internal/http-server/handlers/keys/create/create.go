@@ -0,0 +1,99 @@
+package create
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/apikey"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
+)
+
+type Request struct {
+	Name      string     `json:"name" validate:"required,max=64"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=links:read links:write links:delete stats:read keys:manage audit:read webhooks:manage"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	ID int64 `json:"id,omitempty"`
+	// Key is only ever returned here, it can't be recovered later.
+	Key    string `json:"key,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+//go:generate mockgen -source=create.go -destination=mocks/createmock.go -package=mocks
+type KeyCreator interface {
+	CreateAPIKey(ctx context.Context, key storage.APIKey, hash string) (int64, error)
+}
+
+// New issues an API key, only its hash is stored.
+func New(log *slog.Logger, keyCreator KeyCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.keys.create.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to decode request body"))
+			return
+		}
+
+		log.Info("request body decoded", slog.String("name", req.Name), slog.Any("scopes", req.Scopes))
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+			render.JSON(w, r, response.ValidationError(validateErr))
+			return
+		}
+
+		now := time.Now()
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+			log.Info("expiry in the past", slog.Time("expires_at", *req.ExpiresAt))
+			render.JSON(w, r, response.Error("expires_at must be in the future"))
+			return
+		}
+
+		key, err := apikey.Generate()
+		if err != nil {
+			log.Error("failed to generate key", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to create key"))
+			return
+		}
+
+		id, err := keyCreator.CreateAPIKey(r.Context(), storage.APIKey{
+			Name:      req.Name,
+			Prefix:    apikey.Prefix(key),
+			Scopes:    req.Scopes,
+			CreatedAt: now,
+			ExpiresAt: req.ExpiresAt,
+		}, apikey.Hash(key))
+		if err != nil {
+			log.Error("failed to create key", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to create key"))
+			return
+		}
+
+		log.Info("key created", slog.Int64("id", id), slog.String("prefix", apikey.Prefix(key)))
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			ID:       id,
+			Key:      key,
+			Prefix:   apikey.Prefix(key),
+		})
+	}
+}
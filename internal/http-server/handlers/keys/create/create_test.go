@@ -0,0 +1,104 @@
+package create
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/keys/create/mocks"
+	"golang-url-shortener/internal/lib/apikey"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreate(t *testing.T) {
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	yesterday := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name      string
+		input     string
+		respError string
+		mockError error
+	}{
+		{
+			name:  "success",
+			input: `{"name": "ci", "scopes": ["links:read", "links:write"]}`,
+		},
+		{
+			name:  "with expiry",
+			input: `{"name": "ci", "scopes": ["links:read"], "expires_at": "` + tomorrow + `"}`,
+		},
+		{
+			name:      "expired",
+			input:     `{"name": "ci", "scopes": ["links:read"], "expires_at": "` + yesterday + `"}`,
+			respError: "expires_at must be in the future",
+		},
+		{
+			name:      "no scopes",
+			input:     `{"name": "ci", "scopes": []}`,
+			respError: "field Scopes is not valid",
+		},
+		{
+			name:      "unknown scope",
+			input:     `{"name": "ci", "scopes": ["links:*"]}`,
+			respError: "field Scopes[0] is not valid",
+		},
+		{
+			name:      "no name",
+			input:     `{"scopes": ["links:read"]}`,
+			respError: "field Name is not valid",
+		},
+		{
+			name:      "CreateAPIKey error",
+			input:     `{"name": "ci", "scopes": ["links:read"]}`,
+			respError: "failed to create key",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			var storedHash string
+			mockKeyCreator := mocks.NewMockKeyCreator(ctrl)
+			if tc.mockError != nil || tc.respError == "" {
+				mockKeyCreator.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, key storage.APIKey, hash string) (int64, error) {
+						require.Equal(t, "ci", key.Name)
+						require.NotEmpty(t, key.Scopes)
+						storedHash = hash
+
+						return int64(7), tc.mockError
+					}).Times(1)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/keys", bytes.NewBufferString(tc.input))
+
+			rr := httptest.NewRecorder()
+			New(slogdiscard.NewDiscardLogger(), mockKeyCreator).ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				require.Empty(t, resp.Key)
+				return
+			}
+
+			require.Equal(t, int64(7), resp.ID)
+			require.Equal(t, apikey.Hash(resp.Key), storedHash)
+			require.Equal(t, apikey.Prefix(resp.Key), resp.Prefix)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: create.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockKeyCreator is a mock of KeyCreator interface.
+type MockKeyCreator struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyCreatorMockRecorder
+}
+
+// MockKeyCreatorMockRecorder is the mock recorder for MockKeyCreator.
+type MockKeyCreatorMockRecorder struct {
+	mock *MockKeyCreator
+}
+
+// NewMockKeyCreator creates a new mock instance.
+func NewMockKeyCreator(ctrl *gomock.Controller) *MockKeyCreator {
+	mock := &MockKeyCreator{ctrl: ctrl}
+	mock.recorder = &MockKeyCreatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyCreator) EXPECT() *MockKeyCreatorMockRecorder {
+	return m.recorder
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockKeyCreator) CreateAPIKey(ctx context.Context, key storage.APIKey, hash string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key, hash)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockKeyCreatorMockRecorder) CreateAPIKey(ctx, key, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockKeyCreator)(nil).CreateAPIKey), ctx, key, hash)
+}
@@ -0,0 +1,74 @@
+package list
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
+)
+
+type Key struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	Keys []Key `json:"keys"`
+}
+
+//go:generate mockgen -source=list.go -destination=mocks/listmock.go -package=mocks
+type KeyLister interface {
+	ListAPIKeys(ctx context.Context) ([]storage.APIKey, error)
+}
+
+// New lists the API keys, revoked and expired ones included.
+func New(log *slog.Logger, keyLister KeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.keys.list.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		apiKeys, err := keyLister.ListAPIKeys(r.Context())
+		if err != nil {
+			log.Error("failed to list keys", sl.Err(err))
+			render.JSON(w, r, response.Error("internal error"))
+			return
+		}
+
+		now := time.Now()
+		keys := make([]Key, 0, len(apiKeys))
+		for _, key := range apiKeys {
+			keys = append(keys, Key{
+				ID:         key.ID,
+				Name:       key.Name,
+				Prefix:     key.Prefix,
+				Scopes:     key.Scopes,
+				Active:     key.Active(now),
+				CreatedAt:  key.CreatedAt,
+				ExpiresAt:  key.ExpiresAt,
+				LastUsedAt: key.LastUsedAt,
+				RevokedAt:  key.RevokedAt,
+			})
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Keys:     keys,
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package list
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/keys/list/mocks"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestList(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	revoked := created.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		keys      []storage.APIKey
+		mockError error
+		respError string
+		expected  []Key
+	}{
+		{
+			name:     "no keys",
+			expected: []Key{},
+		},
+		{
+			name: "keys",
+			keys: []storage.APIKey{
+				{ID: 1, Name: "ci", Prefix: "usk_abcdefgh", Scopes: []string{"links:write"}, CreatedAt: created},
+				{ID: 2, Name: "old", Prefix: "usk_12345678", Scopes: []string{"links:read"}, CreatedAt: created, RevokedAt: &revoked},
+			},
+			expected: []Key{
+				{ID: 1, Name: "ci", Prefix: "usk_abcdefgh", Scopes: []string{"links:write"}, Active: true, CreatedAt: created},
+				{ID: 2, Name: "old", Prefix: "usk_12345678", Scopes: []string{"links:read"}, CreatedAt: created, RevokedAt: &revoked},
+			},
+		},
+		{
+			name:      "error with db",
+			mockError: errors.New("another error"),
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockKeyLister := mocks.NewMockKeyLister(ctrl)
+			mockKeyLister.EXPECT().ListAPIKeys(gomock.Any()).Return(tc.keys, tc.mockError).Times(1)
+
+			rr := httptest.NewRecorder()
+			New(slogdiscard.NewDiscardLogger(), mockKeyLister).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/keys", nil))
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.expected, resp.Keys)
+			}
+		})
+	}
+}
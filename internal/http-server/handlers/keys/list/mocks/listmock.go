@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: list.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockKeyLister is a mock of KeyLister interface.
+type MockKeyLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyListerMockRecorder
+}
+
+// MockKeyListerMockRecorder is the mock recorder for MockKeyLister.
+type MockKeyListerMockRecorder struct {
+	mock *MockKeyLister
+}
+
+// NewMockKeyLister creates a new mock instance.
+func NewMockKeyLister(ctrl *gomock.Controller) *MockKeyLister {
+	mock := &MockKeyLister{ctrl: ctrl}
+	mock.recorder = &MockKeyListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyLister) EXPECT() *MockKeyListerMockRecorder {
+	return m.recorder
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockKeyLister) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx)
+	ret0, _ := ret[0].([]storage.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockKeyListerMockRecorder) ListAPIKeys(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockKeyLister)(nil).ListAPIKeys), ctx)
+}
@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: revoke.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockKeyRevoker is a mock of KeyRevoker interface.
+type MockKeyRevoker struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyRevokerMockRecorder
+}
+
+// MockKeyRevokerMockRecorder is the mock recorder for MockKeyRevoker.
+type MockKeyRevokerMockRecorder struct {
+	mock *MockKeyRevoker
+}
+
+// NewMockKeyRevoker creates a new mock instance.
+func NewMockKeyRevoker(ctrl *gomock.Controller) *MockKeyRevoker {
+	mock := &MockKeyRevoker{ctrl: ctrl}
+	mock.recorder = &MockKeyRevokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyRevoker) EXPECT() *MockKeyRevokerMockRecorder {
+	return m.recorder
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockKeyRevoker) RevokeAPIKey(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockKeyRevokerMockRecorder) RevokeAPIKey(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockKeyRevoker)(nil).RevokeAPIKey), ctx, id)
+}
@@ -0,0 +1,55 @@
+package revoke
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strconv"
+)
+
+//go:generate mockgen -source=revoke.go -destination=mocks/revokemock.go -package=mocks
+type KeyRevoker interface {
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+// New revokes the API key with the id, it stops working right away.
+func New(log *slog.Logger, keyRevoker KeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.keys.revoke.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Info("invalid key id", slog.String("id", chi.URLParam(r, "id")))
+			render.JSON(w, r, response.Error("invalid request"))
+			return
+		}
+
+		err = keyRevoker.RevokeAPIKey(r.Context(), id)
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			log.Info("key not found", slog.Int64("id", id))
+			render.JSON(w, r, response.Error("key not found"))
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to revoke key", sl.Err(err))
+			render.JSON(w, r, response.Error("internal error"))
+			return
+		}
+
+		log.Info("key revoked", slog.Int64("id", id))
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,73 @@
+package revoke
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/keys/revoke/mocks"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRevoke(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		respError string
+		mockError error
+		mockCall  bool
+	}{
+		{
+			name:     "success",
+			id:       "3",
+			mockCall: true,
+		},
+		{
+			name:      "not found",
+			id:        "3",
+			respError: "key not found",
+			mockError: storage.ErrKeyNotFound,
+			mockCall:  true,
+		},
+		{
+			name:      "error with db",
+			id:        "3",
+			respError: "internal error",
+			mockError: errors.New("another error"),
+			mockCall:  true,
+		},
+		{
+			name:      "invalid id",
+			id:        "abc",
+			respError: "invalid request",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockKeyRevoker := mocks.NewMockKeyRevoker(ctrl)
+			if tc.mockCall {
+				mockKeyRevoker.EXPECT().RevokeAPIKey(gomock.Any(), int64(3)).Return(tc.mockError).Times(1)
+			}
+
+			router := chi.NewRouter()
+			router.Delete("/admin/keys/{id}", New(slogdiscard.NewDiscardLogger(), mockKeyRevoker))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin/keys/"+tc.id, nil))
+
+			var resp response.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
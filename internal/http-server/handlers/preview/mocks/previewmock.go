@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: preview.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLinkGetter is a mock of LinkGetter interface.
+type MockLinkGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkGetterMockRecorder
+}
+
+// MockLinkGetterMockRecorder is the mock recorder for MockLinkGetter.
+type MockLinkGetterMockRecorder struct {
+	mock *MockLinkGetter
+}
+
+// NewMockLinkGetter creates a new mock instance.
+func NewMockLinkGetter(ctrl *gomock.Controller) *MockLinkGetter {
+	mock := &MockLinkGetter{ctrl: ctrl}
+	mock.recorder = &MockLinkGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkGetter) EXPECT() *MockLinkGetterMockRecorder {
+	return m.recorder
+}
+
+// CountClicks mocks base method.
+func (m *MockLinkGetter) CountClicks(ctx context.Context, urlID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountClicks", ctx, urlID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountClicks indicates an expected call of CountClicks.
+func (mr *MockLinkGetterMockRecorder) CountClicks(ctx, urlID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountClicks", reflect.TypeOf((*MockLinkGetter)(nil).CountClicks), ctx, urlID)
+}
+
+// GetLink mocks base method.
+func (m *MockLinkGetter) GetLink(ctx context.Context, domain, alias string) (storage.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLink", ctx, domain, alias)
+	ret0, _ := ret[0].(storage.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLink indicates an expected call of GetLink.
+func (mr *MockLinkGetterMockRecorder) GetLink(ctx, domain, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLink", reflect.TypeOf((*MockLinkGetter)(nil).GetLink), ctx, domain, alias)
+}
@@ -0,0 +1,141 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/domains"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//go:generate mockgen -source=preview.go -destination=mocks/previewmock.go -package=mocks
+type LinkGetter interface {
+	GetLink(ctx context.Context, domain, alias string) (storage.Link, error)
+	CountClicks(ctx context.Context, urlID int64) (int64, error)
+}
+
+var page = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta name="robots" content="noindex">
+  <title>Preview of /{{.Alias}}</title>
+</head>
+<body>
+<h1>/{{.Alias}}</h1>
+{{if .Protected}}
+<p>This link is protected by a password, its destination is hidden.</p>
+{{else if .OneTime}}
+<p>This link can only be opened once, its destination is hidden.</p>
+{{else if .Scheduled}}
+<p>This link is not active yet, its destination is hidden.</p>
+{{else}}
+<p>This link leads to:</p>
+<p><code>{{.URL}}</code></p>
+<p>Host: <b>{{.Host}}</b></p>
+{{end}}
+<p>Created: {{if .CreatedAt}}{{.CreatedAt}}{{else}}unknown{{end}}</p>
+<p>Clicks: {{.Clicks}}</p>
+{{if .Limited}}<p>Clicks left: {{.ClicksLeft}}</p>{{end}}
+{{if .ActiveFrom}}<p>Active from: {{.ActiveFrom}}</p>{{end}}
+{{if .ActiveUntil}}<p>Active until: {{.ActiveUntil}}</p>{{end}}
+<p><a href="/{{.Alias}}" rel="nofollow noreferrer">Continue</a></p>
+</body>
+</html>
+`))
+
+type pageData struct {
+	Alias      string
+	URL        string
+	Host       string
+	Protected  bool
+	Scheduled  bool
+	OneTime    bool
+	CreatedAt  string
+	Clicks     int64
+	Limited    bool
+	ClicksLeft int
+
+	ActiveFrom  string
+	ActiveUntil string
+}
+
+func New(log *slog.Logger, linkGetter LinkGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.preview.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		if alias == "" {
+			log.Info("alias is empty")
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		link, err := linkGetter.GetLink(r.Context(), domains.FromContext(r.Context()), alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found", sl.Err(err))
+			http.Error(w, "url not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		clicks, err := linkGetter.CountClicks(r.Context(), link.ID)
+		if err != nil {
+			log.Error("failed to count clicks", sl.Err(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		data := pageData{
+			Alias:      link.Alias,
+			Protected:  link.PasswordHash != "",
+			OneTime:    link.MaxClicks == 1,
+			Scheduled:  link.ActiveFrom != nil && time.Now().Before(*link.ActiveFrom),
+			Clicks:     clicks,
+			Limited:    link.MaxClicks > 0,
+			ClicksLeft: link.ClicksLeft,
+		}
+
+		if !data.Protected && !data.OneTime && !data.Scheduled {
+			data.URL = link.URL
+			if u, err := url.Parse(link.URL); err == nil {
+				data.Host = u.Hostname()
+			}
+		}
+
+		if !link.CreatedAt.IsZero() {
+			data.CreatedAt = link.CreatedAt.UTC().Format(time.RFC1123)
+		}
+		if link.ActiveFrom != nil {
+			data.ActiveFrom = link.ActiveFrom.UTC().Format(time.RFC1123)
+		}
+		if link.ActiveUntil != nil {
+			data.ActiveUntil = link.ActiveUntil.UTC().Format(time.RFC1123)
+		}
+
+		log.Info("preview rendered", slog.String("alias", alias))
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := page.Execute(w, data); err != nil {
+			log.Error("failed to render preview", sl.Err(err))
+		}
+	}
+}
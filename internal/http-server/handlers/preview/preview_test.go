@@ -0,0 +1,185 @@
+package preview
+
+import (
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/preview/mocks"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPreview(t *testing.T) {
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	activeUntil := time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)
+	activeFrom := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		path        string
+		alias       string
+		link        storage.Link
+		clicks      int64
+		mockError   error
+		respCode    int
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:  "plus suffix",
+			path:  "/youtube+",
+			alias: "youtube",
+			link: storage.Link{
+				ID:        1,
+				Alias:     "youtube",
+				URL:       "https://www.youtube.com/watch?v=1&t=2",
+				CreatedAt: createdAt,
+				LinkOptions: storage.LinkOptions{
+					ActiveUntil: &activeUntil,
+				},
+			},
+			clicks:   42,
+			respCode: http.StatusOK,
+			contains: []string{
+				"https://www.youtube.com/watch?v=1&amp;t=2",
+				"<b>www.youtube.com</b>",
+				"Fri, 01 Mar 2024 12:00:00 UTC",
+				"Clicks: 42",
+				"Active until: Sun, 31 Mar 2024 00:00:00 UTC",
+			},
+		},
+		{
+			name:  "preview path",
+			path:  "/youtube/preview",
+			alias: "youtube",
+			link: storage.Link{
+				ID:    1,
+				Alias: "youtube",
+				URL:   "https://www.youtube.com/",
+			},
+			respCode: http.StatusOK,
+			contains: []string{"Created: unknown", "Clicks: 0"},
+		},
+		{
+			name:  "html is escaped",
+			path:  "/xss+",
+			alias: "xss",
+			link: storage.Link{
+				ID:    2,
+				Alias: "xss",
+				URL:   `https://evil.example.com/"><script>alert(1)</script>`,
+			},
+			respCode:    http.StatusOK,
+			contains:    []string{"&lt;script&gt;alert(1)&lt;/script&gt;"},
+			notContains: []string{"<script>"},
+		},
+		{
+			name:  "password protected",
+			path:  "/docs+",
+			alias: "docs",
+			link: storage.Link{
+				ID:          3,
+				Alias:       "docs",
+				URL:         "https://intranet.example.com/secret",
+				LinkOptions: storage.LinkOptions{PasswordHash: "hash"},
+			},
+			respCode:    http.StatusOK,
+			contains:    []string{"protected by a password"},
+			notContains: []string{"intranet.example.com"},
+		},
+		{
+			name:  "one-time",
+			path:  "/invite+",
+			alias: "invite",
+			link: storage.Link{
+				ID:          4,
+				Alias:       "invite",
+				URL:         "https://example.com/invite?token=secret",
+				LinkOptions: storage.LinkOptions{MaxClicks: 1},
+				ClicksLeft:  1,
+			},
+			respCode:    http.StatusOK,
+			contains:    []string{"can only be opened once", "Clicks left: 1"},
+			notContains: []string{"token=secret"},
+		},
+		{
+			name:  "scheduled",
+			path:  "/launch+",
+			alias: "launch",
+			link: storage.Link{
+				ID:          6,
+				Alias:       "launch",
+				URL:         "https://example.com/unannounced-product",
+				LinkOptions: storage.LinkOptions{ActiveFrom: &activeFrom},
+			},
+			respCode:    http.StatusOK,
+			contains:    []string{"not active yet", "Active from: "},
+			notContains: []string{"unannounced-product"},
+		},
+		{
+			name:  "click-limited",
+			path:  "/download+",
+			alias: "download",
+			link: storage.Link{
+				ID:          5,
+				Alias:       "download",
+				URL:         "https://example.com/file.zip",
+				LinkOptions: storage.LinkOptions{MaxClicks: 10},
+				ClicksLeft:  7,
+			},
+			clicks:   3,
+			respCode: http.StatusOK,
+			contains: []string{"https://example.com/file.zip", "Clicks: 3", "Clicks left: 7"},
+		},
+		{
+			name:      "not found",
+			path:      "/missing+",
+			alias:     "missing",
+			mockError: storage.ErrUrlNotFound,
+			respCode:  http.StatusNotFound,
+		},
+		{
+			name:      "storage error",
+			path:      "/broken/preview",
+			alias:     "broken",
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockLinkGetter := mocks.NewMockLinkGetter(ctrl)
+
+			mockLinkGetter.EXPECT().GetLink(gomock.Any(), "", tc.alias).Return(tc.link, tc.mockError).Times(1)
+			if tc.mockError == nil {
+				mockLinkGetter.EXPECT().CountClicks(gomock.Any(), tc.link.ID).Return(tc.clicks, nil).Times(1)
+			}
+
+			handler := New(slogdiscard.NewDiscardLogger(), mockLinkGetter)
+
+			r := chi.NewRouter()
+			r.Get("/{alias}+", handler)
+			r.Get("/{alias}/preview", handler)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			body := rr.Body.String()
+			for _, s := range tc.contains {
+				require.Contains(t, body, s)
+			}
+			for _, s := range tc.notContains {
+				require.NotContains(t, body, s)
+			}
+		})
+	}
+}
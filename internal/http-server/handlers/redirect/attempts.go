@@ -0,0 +1,69 @@
+package redirect
+
+import (
+	"sync"
+	"time"
+)
+
+const maxTrackedClients = 10000
+
+// attemptLimiter counts password attempts per key in fixed windows.
+type attemptLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	attempts map[string]*attemptWindow
+}
+
+type attemptWindow struct {
+	count int
+	start time.Time
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		max:      max,
+		window:   window,
+		attempts: make(map[string]*attemptWindow),
+	}
+}
+
+// allow registers an attempt and reports whether it is within the limit.
+// When it is not, the time until the window resets is returned.
+func (l *attemptLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.attempts) >= maxTrackedClients {
+		l.sweep(now)
+	}
+
+	a, ok := l.attempts[key]
+	if !ok || now.Sub(a.start) >= l.window {
+		a = &attemptWindow{start: now}
+		l.attempts[key] = a
+	}
+
+	if a.count >= l.max {
+		return false, a.start.Add(l.window).Sub(now)
+	}
+
+	a.count++
+
+	return true, 0
+}
+
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
+
+func (l *attemptLimiter) sweep(now time.Time) {
+	for key, a := range l.attempts {
+		if now.Sub(a.start) >= l.window {
+			delete(l.attempts, key)
+		}
+	}
+}
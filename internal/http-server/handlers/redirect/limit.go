@@ -0,0 +1,96 @@
+package redirect
+
+import (
+	"errors"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"html/template"
+	"net/http"
+)
+
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta name="robots" content="noindex">
+  <title>One-time link</title>
+</head>
+<body>
+<form method="post">
+  <p>The link <b>/{{.}}</b> can only be opened once.</p>
+  <button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// isOneTime reports whether the link can be followed only once. Such links
+// are opened with a POST from a confirmation page, so link previews and
+// prefetching don't use them up.
+func isOneTime(link storage.Link) bool {
+	return link.MaxClicks == 1
+}
+
+// consumeClick takes a click from a click-limited link. It reports whether the
+// request may be redirected, otherwise the response has already been written.
+func consumeClick(log *slog.Logger, w http.ResponseWriter, r *http.Request, link storage.Link,
+	clickRecorder ClickRecorder) bool {
+	if isOneTime(link) && r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = confirmPage.Execute(w, link.Alias)
+		return false
+	}
+
+	err := clickRecorder.ConsumeClick(r.Context(), link.ID)
+	if errors.Is(err, storage.ErrClicksExhausted) {
+		log.Info("click limit reached", slog.String("alias", link.Alias))
+		renderGone(w, r, link)
+		return false
+	}
+
+	if err != nil {
+		log.Error("failed to consume click", sl.Err(err))
+		render.JSON(w, r, "internal error")
+		return false
+	}
+
+	return true
+}
+
+func renderGone(w http.ResponseWriter, r *http.Request, link storage.Link) {
+	render.Status(r, http.StatusGone)
+
+	if isOneTime(link) {
+		render.JSON(w, r, "link has already been used")
+		return
+	}
+
+	render.JSON(w, r, "link has reached its click limit")
+}
+
+// expires reports whether the link stops working at some point, its redirects
+// must not be cached then.
+func expires(link storage.Link) bool {
+	return link.MaxClicks > 0 || link.ActiveUntil != nil
+}
+
+// limitedStatus keeps browsers from caching the redirect of a link that
+// expires, a cached one would keep working past the limit.
+func limitedStatus(r *http.Request, code int) int {
+	if r.Method == http.MethodPost {
+		return http.StatusSeeOther
+	}
+
+	switch code {
+	case http.StatusMovedPermanently:
+		return http.StatusFound
+	case http.StatusPermanentRedirect:
+		return http.StatusTemporaryRedirect
+	}
+
+	return code
+}
@@ -5,6 +5,8 @@
 package mocks
 
 import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -33,17 +35,68 @@ func (m *MockURLGetter) EXPECT() *MockURLGetterMockRecorder {
 	return m.recorder
 }
 
-// GetURL mocks base method.
-func (m *MockURLGetter) GetURL(alias string) (string, error) {
+// GetLink mocks base method.
+func (m *MockURLGetter) GetLink(ctx context.Context, domain, alias string) (storage.Link, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetURL", alias)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "GetLink", ctx, domain, alias)
+	ret0, _ := ret[0].(storage.Link)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetURL indicates an expected call of GetURL.
-func (mr *MockURLGetterMockRecorder) GetURL(alias interface{}) *gomock.Call {
+// GetLink indicates an expected call of GetLink.
+func (mr *MockURLGetterMockRecorder) GetLink(ctx, domain, alias interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockURLGetter)(nil).GetURL), alias)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLink", reflect.TypeOf((*MockURLGetter)(nil).GetLink), ctx, domain, alias)
+}
+
+// MockClickRecorder is a mock of ClickRecorder interface.
+type MockClickRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockClickRecorderMockRecorder
+}
+
+// MockClickRecorderMockRecorder is the mock recorder for MockClickRecorder.
+type MockClickRecorderMockRecorder struct {
+	mock *MockClickRecorder
+}
+
+// NewMockClickRecorder creates a new mock instance.
+func NewMockClickRecorder(ctrl *gomock.Controller) *MockClickRecorder {
+	mock := &MockClickRecorder{ctrl: ctrl}
+	mock.recorder = &MockClickRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClickRecorder) EXPECT() *MockClickRecorderMockRecorder {
+	return m.recorder
+}
+
+// ConsumeClick mocks base method.
+func (m *MockClickRecorder) ConsumeClick(ctx context.Context, urlID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeClick", ctx, urlID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeClick indicates an expected call of ConsumeClick.
+func (mr *MockClickRecorderMockRecorder) ConsumeClick(ctx, urlID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeClick", reflect.TypeOf((*MockClickRecorder)(nil).ConsumeClick), ctx, urlID)
+}
+
+// RecordClick mocks base method.
+func (m *MockClickRecorder) RecordClick(ctx context.Context, click storage.Click) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordClick", ctx, click)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordClick indicates an expected call of RecordClick.
+func (mr *MockClickRecorderMockRecorder) RecordClick(ctx, click interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordClick", reflect.TypeOf((*MockClickRecorder)(nil).RecordClick), ctx, click)
 }
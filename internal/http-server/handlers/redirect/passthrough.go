@@ -0,0 +1,124 @@
+package redirect
+
+import (
+	"errors"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	errPathNotForwarded = errors.New("path suffix is not forwarded for this link")
+	errInvalidPath      = errors.New("invalid path suffix")
+)
+
+// destination builds the url to redirect to from base, appending the path suffix
+// and merging the query string of the request when the link asks for it.
+func destination(base string, link storage.Link, r *http.Request) (string, error) {
+	suffix, raw := pathSuffix(r)
+
+	if suffix != "" && !link.ForwardPath {
+		return "", errPathNotForwarded
+	}
+
+	forwardQuery := link.ForwardQuery && r.URL.RawQuery != ""
+	if suffix == "" && !forwardQuery {
+		return base, nil
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	host := u.Host
+
+	if suffix != "" {
+		if err := appendPath(u, suffix, raw); err != nil {
+			return "", err
+		}
+	}
+
+	if forwardQuery {
+		u.RawQuery = mergeQuery(u.Query(), r.URL.Query(), link.QueryConflict).Encode()
+	}
+
+	// only the path and query were touched, make sure the host stayed the same anyway
+	if u.Host != host {
+		return "", errInvalidPath
+	}
+
+	return u.String(), nil
+}
+
+// pathSuffix returns what follows the alias in the request path. It's read
+// from the request url rather than the route, since URLFormat trims the
+// extension off the routed path. raw tells whether it's still
+// percent-encoded.
+func pathSuffix(r *http.Request) (string, bool) {
+	path, raw := r.URL.Path, false
+	if r.URL.RawPath != "" {
+		path, raw = r.URL.RawPath, true
+	}
+
+	_, suffix, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+
+	return suffix, raw
+}
+
+// appendPath adds suffix to the path of u segment by segment, so encoded
+// slashes stay inside their segment and dot segments can't climb above
+// the stored path. raw tells whether suffix is still percent-encoded.
+func appendPath(u *url.URL, suffix string, raw bool) error {
+	parts := strings.Split(suffix, "/")
+
+	decoded := make([]string, 0, len(parts))
+	escaped := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		if raw {
+			var err error
+			if part, err = url.PathUnescape(part); err != nil {
+				return errInvalidPath
+			}
+		}
+
+		if part == "." || part == ".." || strings.ContainsAny(part, "\\\x00") {
+			return errInvalidPath
+		}
+
+		decoded = append(decoded, part)
+		escaped = append(escaped, url.PathEscape(part))
+	}
+
+	basePath := strings.TrimSuffix(u.Path, "/")
+	baseRaw := strings.TrimSuffix(u.EscapedPath(), "/")
+
+	u.Path = basePath + "/" + strings.Join(decoded, "/")
+	u.RawPath = baseRaw + "/" + strings.Join(escaped, "/")
+
+	return nil
+}
+
+func mergeQuery(link, request url.Values, conflict string) url.Values {
+	merged := make(url.Values, len(link)+len(request))
+	for key, values := range link {
+		merged[key] = append([]string(nil), values...)
+	}
+
+	for key, values := range request {
+		if _, exists := merged[key]; !exists {
+			merged[key] = values
+			continue
+		}
+
+		switch conflict {
+		case storage.QueryConflictRequest:
+			merged[key] = values
+		case storage.QueryConflictBoth:
+			merged[key] = append(merged[key], values...)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,149 @@
+package redirect
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"golang-url-shortener/internal/lib/password"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	passwordField      = "password"
+	accessCookiePrefix = "link_access_"
+	maxFormSize        = 4096
+)
+
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Protected link</title>
+</head>
+<body>
+<form method="post">
+  <p>The link <b>/{{.Alias}}</b> is protected by a password.</p>
+  {{if .Error}}<p style="color: #a00">{{.Error}}</p>{{end}}
+  <input type="password" name="password" autocomplete="current-password" autofocus required>
+  <button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+type passwordForm struct {
+	Alias string
+	Error string
+}
+
+// unlock guards a password protected link. It reports whether the request may be
+// redirected, otherwise the response has already been written.
+func unlock(log *slog.Logger, w http.ResponseWriter, r *http.Request, link storage.Link,
+	opts Options, attempts *attemptLimiter) bool {
+	now := time.Now()
+
+	cookie, err := r.Cookie(accessCookieName(link))
+	if err == nil && verifyAccess(opts.CookieSecret, link, cookie.Value, now) {
+		return true
+	}
+
+	if r.Method != http.MethodPost {
+		renderPasswordForm(w, http.StatusOK, link.Alias, "")
+		return false
+	}
+
+	key := clientIP(r) + "\x00" + link.Alias
+	if ok, retryAfter := attempts.allow(key, now); !ok {
+		log.Info("too many password attempts", slog.String("alias", link.Alias))
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)+1))
+		renderPasswordForm(w, http.StatusTooManyRequests, link.Alias, "too many attempts, try again later")
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil || !password.Verify(link.PasswordHash, r.PostForm.Get(passwordField)) {
+		log.Info("wrong password", slog.String("alias", link.Alias))
+
+		renderPasswordForm(w, http.StatusUnauthorized, link.Alias, "wrong password")
+		return false
+	}
+
+	attempts.reset(key)
+
+	expires := now.Add(opts.CookieTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     accessCookieName(link),
+		Value:    signAccess(opts.CookieSecret, link, expires),
+		Path:     "/" + url.PathEscape(link.Alias),
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	log.Info("password accepted", slog.String("alias", link.Alias))
+
+	// come back with the cookie so the link is followed the usual way
+	http.Redirect(w, r, r.URL.RequestURI(), http.StatusSeeOther)
+	return false
+}
+
+func renderPasswordForm(w http.ResponseWriter, status int, alias, errMsg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	_ = passwordPage.Execute(w, passwordForm{Alias: alias, Error: errMsg})
+}
+
+func accessCookieName(link storage.Link) string {
+	return accessCookiePrefix + strconv.FormatInt(link.ID, 10)
+}
+
+// signAccess returns "<expires>.<mac>" where mac binds the link, the expiry and
+// the current password hash, so changing the password revokes issued cookies.
+func signAccess(secret []byte, link storage.Link, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	return exp + "." + accessMAC(secret, link, exp)
+}
+
+func verifyAccess(secret []byte, link storage.Link, value string, now time.Time) bool {
+	exp, mac, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(mac), []byte(accessMAC(secret, link, exp)))
+}
+
+func accessMAC(secret []byte, link storage.Link, exp string) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(link.Alias + "\x00" + exp + "\x00" + link.PasswordHash))
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
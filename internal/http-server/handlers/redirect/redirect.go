@@ -1,28 +1,106 @@
 package redirect
 
 import (
+	"context"
+	"crypto/rand"
 	"errors"
 	"github.com/go-chi/chi"
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/domains"
 	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/lib/metrics"
+	"golang-url-shortener/internal/lib/redirecttype"
+	"golang-url-shortener/internal/screening"
 	"golang-url-shortener/internal/storage"
+	"golang-url-shortener/internal/webhooks"
 	"golang.org/x/exp/slog"
 	"net/http"
+	"time"
 )
 
 //go:generate mockgen -source=redirect.go -destination=mocks/redirectmock.go -package=mocks
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetLink(ctx context.Context, domain, alias string) (storage.Link, error)
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+type ClickRecorder interface {
+	RecordClick(ctx context.Context, click storage.Click) error
+	ConsumeClick(ctx context.Context, urlID int64) error
+}
+
+type Options struct {
+	// DefaultType is the redirect status for links saved without redirect_type.
+	DefaultType int
+
+	// CookieSecret signs the cookie issued after a correct password.
+	// A random one is generated when empty, so cookies don't survive restarts.
+	CookieSecret []byte
+	CookieTTL    time.Duration
+
+	PasswordAttempts       int
+	PasswordAttemptsWindow time.Duration
+
+	// VariantCookieTTL is how long a visitor stays on the same destination
+	// of a split link with sticky_split.
+	VariantCookieTTL time.Duration
+
+	// NotActiveURL is redirected to outside of the activation window of links
+	// without a fallback url, NotActiveStatus is used before the window when
+	// it's empty. After the window such links answer 410.
+	NotActiveURL    string
+	NotActiveStatus int
+
+	// Screener checks the destination again before redirecting, so links
+	// saved before their domain got blocklisted stop working.
+	Screener *screening.Screener
+
+	// Events gets link.clicked for every redirect and link.expired when a
+	// click-limited link is used up.
+	Events *webhooks.Dispatcher
+}
+
+func (o Options) withDefaults() Options {
+	if len(o.CookieSecret) == 0 {
+		o.CookieSecret = make([]byte, 32)
+		if _, err := rand.Read(o.CookieSecret); err != nil {
+			panic("redirect: failed to generate cookie secret: " + err.Error())
+		}
+	}
+	if o.CookieTTL <= 0 {
+		o.CookieTTL = time.Hour
+	}
+	if o.PasswordAttempts <= 0 {
+		o.PasswordAttempts = 5
+	}
+	if o.PasswordAttemptsWindow <= 0 {
+		o.PasswordAttemptsWindow = 15 * time.Minute
+	}
+	if o.VariantCookieTTL <= 0 {
+		o.VariantCookieTTL = 30 * 24 * time.Hour
+	}
+	if o.NotActiveStatus < 400 || o.NotActiveStatus > 599 {
+		o.NotActiveStatus = http.StatusNotFound
+	}
+
+	return o
+}
+
+// New serves GET and POST requests for an alias, POST carries the password
+// for protected links. The first matching rule of the link picks the url,
+// otherwise it's drawn from the weighted destinations when there are any.
+// When routed as /{alias}/* the rest of the path is appended to it for
+// links with forward_path. Click-limited links answer 410 once used up,
+// links outside of their activation window are handled by checkWindow.
+func New(log *slog.Logger, urlGetter URLGetter, clickRecorder ClickRecorder, opts Options) http.HandlerFunc {
+	opts = opts.withDefaults()
+	attempts := newAttemptLimiter(opts.PasswordAttempts, opts.PasswordAttemptsWindow)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.redirect.New"
 
-		log = log.With(
+		log := logger.ForRequest(log, r).With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
 		alias := chi.URLParam(r, "alias")
@@ -33,9 +111,10 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			return
 		}
 
-		url, err := urlGetter.GetURL(alias)
+		link, err := urlGetter.GetLink(r.Context(), domains.FromContext(r.Context()), alias)
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Info("url not found", sl.Err(err))
+			metrics.Redirect(metrics.RedirectMiss)
 			render.JSON(w, r, "url not found")
 			return
 		}
@@ -45,8 +124,93 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			render.JSON(w, r, "internal error")
 			return
 		}
-		log.Info("got url", slog.String("url", url))
+		if !checkWindow(log, w, r, link, opts, time.Now()) {
+			return
+		}
 
-		http.Redirect(w, r, url, http.StatusFound)
+		if link.MaxClicks > 0 && link.ClicksLeft <= 0 {
+			log.Info("click limit reached", slog.String("alias", alias))
+			renderGone(w, r, link)
+			return
+		}
+
+		if link.PasswordHash != "" && !unlock(log, w, r, link, opts, attempts) {
+			return
+		}
+
+		base, variant := pickURL(w, r, link, opts)
+
+		target, err := destination(base, link, r)
+		if errors.Is(err, errPathNotForwarded) {
+			log.Info("path suffix for link without forward_path", slog.String("alias", alias))
+			metrics.Redirect(metrics.RedirectMiss)
+			render.JSON(w, r, "url not found")
+			return
+		}
+
+		if err != nil {
+			log.Info("failed to build destination", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, "invalid request")
+			return
+		}
+
+		if err := opts.Screener.Check(target); err != nil {
+			log.Warn("destination rejected", sl.Err(err))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, "url is blocked")
+			return
+		}
+
+		if link.MaxClicks > 0 && !consumeClick(log, w, r, link, clickRecorder) {
+			return
+		}
+
+		log.Info("got url", slog.String("url", target), slog.String("variant", variant))
+
+		clickedAt := time.Now()
+
+		err = clickRecorder.RecordClick(r.Context(), storage.Click{URLID: link.ID, ClickedAt: clickedAt, Variant: variant})
+		if err != nil {
+			// losing a click is better than failing the redirect
+			log.Error("failed to record click", sl.Err(err))
+		}
+
+		opts.Events.Publish(r.Context(), webhooks.EventLinkClicked, webhooks.Clicked{
+			Domain:    link.Domain,
+			Alias:     link.Alias,
+			URL:       target,
+			Variant:   variant,
+			ClickedAt: clickedAt.UTC(),
+		})
+		if link.MaxClicks > 0 && link.ClicksLeft == 1 {
+			opts.Events.Publish(r.Context(), webhooks.EventLinkExpired, webhooks.Expired{
+				Domain: link.Domain,
+				Alias:  link.Alias,
+				Reason: webhooks.ReasonClickLimit,
+			})
+		}
+
+		code := statusCode(link.RedirectType, opts.DefaultType)
+		if expires(link) {
+			w.Header().Set("Cache-Control", "no-store")
+			code = limitedStatus(r, code)
+		}
+
+		metrics.Redirect(metrics.RedirectHit)
+
+		http.Redirect(w, r, target, code)
+	}
+}
+
+func statusCode(linkType, defaultType int) int {
+	if redirecttype.Valid(linkType) {
+		return linkType
 	}
+
+	if redirecttype.Valid(defaultType) {
+		return defaultType
+	}
+
+	return http.StatusFound
 }
@@ -1,28 +1,74 @@
 package redirect
 
 import (
+	"context"
+	"errors"
 	"github.com/go-chi/chi"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"golang-url-shortener/internal/http-server/handlers/redirect/mocks"
 	"golang-url-shortener/internal/lib/api"
 	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/lib/password"
+	"golang-url-shortener/internal/screening"
+	"golang-url-shortener/internal/storage"
+	"math/rand"
+	"net/http"
 	"net/http/httptest"
+	neturl "net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRedirect(t *testing.T) {
 	tests := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name         string
+		id           int64
+		alias        string
+		url          string
+		redirectType int
+		defaultType  int
+		respCode     int
+		respError    string
+		mockError    error
+		clickError   error
 	}{
 		{
-			name:  "correct",
-			alias: "youtube",
-			url:   "https://www.youtube.com/",
+			name:     "correct",
+			alias:    "youtube",
+			url:      "https://www.youtube.com/",
+			respCode: http.StatusFound,
+		},
+		{
+			name:         "permanent link",
+			alias:        "youtube",
+			url:          "https://www.youtube.com/",
+			redirectType: http.StatusMovedPermanently,
+			respCode:     http.StatusMovedPermanently,
+		},
+		{
+			name:         "link type overrides default",
+			alias:        "api",
+			url:          "https://api.example.com/v1",
+			redirectType: http.StatusTemporaryRedirect,
+			defaultType:  http.StatusPermanentRedirect,
+			respCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:        "configured default",
+			alias:       "youtube",
+			url:         "https://www.youtube.com/",
+			defaultType: http.StatusPermanentRedirect,
+			respCode:    http.StatusPermanentRedirect,
+		},
+		{
+			name:       "click not recorded",
+			id:         7,
+			alias:      "youtube",
+			url:        "https://www.youtube.com/",
+			respCode:   http.StatusFound,
+			clickError: errors.New("unexpected error"),
 		},
 	}
 
@@ -30,23 +76,808 @@ func TestRedirect(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 
 			ctrl := gomock.NewController(t)
-			mockUrlDeleter := mocks.NewMockURLGetter(ctrl)
+			mockUrlGetter := mocks.NewMockURLGetter(ctrl)
 
 			if tc.mockError != nil || tc.respError == "" {
-				mockUrlDeleter.EXPECT().GetURL(tc.alias).Return(tc.url, tc.mockError).Times(1)
+				mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", tc.alias).Return(storage.Link{
+					ID:          tc.id,
+					Alias:       tc.alias,
+					URL:         tc.url,
+					LinkOptions: storage.LinkOptions{RedirectType: tc.redirectType},
+				}, tc.mockError).Times(1)
+			}
+
+			mockClickRecorder := mocks.NewMockClickRecorder(ctrl)
+			if tc.respError == "" {
+				mockClickRecorder.EXPECT().RecordClick(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, click storage.Click) error {
+						require.Equal(t, tc.id, click.URLID)
+						require.False(t, click.ClickedAt.IsZero())
+						return tc.clickError
+					}).Times(1)
 			}
 
 			r := chi.NewRouter()
-			r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), mockUrlDeleter))
+			r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), mockUrlGetter, mockClickRecorder, Options{
+				DefaultType: tc.defaultType,
+			}))
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 
-			redirectedToUrl, err := api.GetRedirect(ts.URL + "/" + tc.alias)
+			redirectedToUrl, code, err := api.GetRedirectWithStatus(ts.URL + "/" + tc.alias)
 			require.NoError(t, err)
 
 			// check if we got redirected
 			require.Equal(t, redirectedToUrl, tc.url)
+			require.Equal(t, tc.respCode, code)
+		})
+	}
+}
+
+func TestRedirectPasswordProtected(t *testing.T) {
+	const (
+		alias    = "docs"
+		url      = "https://intranet.example.com/docs"
+		secret   = "secret-password"
+		attempts = 3
+	)
+
+	hash, err := password.Hash(secret)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+	mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", alias).Return(storage.Link{
+		ID:          1,
+		Alias:       alias,
+		URL:         url,
+		LinkOptions: storage.LinkOptions{PasswordHash: hash},
+	}, nil).AnyTimes()
+
+	mockClickRecorder := mocks.NewMockClickRecorder(ctrl)
+	mockClickRecorder.EXPECT().RecordClick(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	handler := New(slogdiscard.NewDiscardLogger(), mockUrlGetter, mockClickRecorder, Options{
+		CookieSecret:     []byte("cookie-secret"),
+		PasswordAttempts: attempts,
+	})
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", handler)
+	r.Post("/{alias}", handler)
+
+	submit := func(pass, remoteAddr string) *httptest.ResponseRecorder {
+		form := neturl.Values{passwordField: {pass}}
+		req := httptest.NewRequest(http.MethodPost, "/"+alias, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// without the cookie the form is shown instead of the redirect
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), `name="password"`)
+	require.Empty(t, rr.Header().Get("Location"))
+
+	rr = submit("wrong", "10.0.0.1:1234")
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	require.Contains(t, rr.Body.String(), "wrong password")
+	require.Empty(t, rr.Result().Cookies())
+
+	rr = submit(secret, "10.0.0.1:1234")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	require.Equal(t, "/"+alias, rr.Header().Get("Location"))
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.True(t, cookies[0].HttpOnly)
+
+	// with the cookie we get redirected right away
+	req := httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusFound, rr.Code)
+	require.Equal(t, url, rr.Header().Get("Location"))
+
+	// forged cookie is rejected
+	req = httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+	req.AddCookie(&http.Cookie{Name: cookies[0].Name, Value: "99999999999.forged"})
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// once attempts are exhausted even the right password is rejected
+	for i := 0; i < attempts; i++ {
+		require.Equal(t, http.StatusUnauthorized, submit("wrong", "10.0.0.2:1234").Code)
+	}
+	rr = submit(secret, "10.0.0.2:1234")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	// other clients are not affected
+	require.Equal(t, http.StatusSeeOther, submit(secret, "10.0.0.3:1234").Code)
+}
+
+func TestRedirectPassthrough(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		opts     storage.LinkOptions
+		path     string
+		respCode int
+		location string
+	}{
+		{
+			name:     "query ignored by default",
+			url:      "https://example.com/landing",
+			path:     "/promo?utm_source=x",
+			respCode: http.StatusFound,
+			location: "https://example.com/landing",
+		},
+		{
+			name:     "query merged, link wins",
+			url:      "https://example.com/landing?utm_source=link&x=1",
+			opts:     storage.LinkOptions{ForwardQuery: true},
+			path:     "/promo?utm_source=x&y=2",
+			respCode: http.StatusFound,
+			location: "https://example.com/landing?utm_source=link&x=1&y=2",
+		},
+		{
+			name:     "query merged, request wins",
+			url:      "https://example.com/landing?utm_source=link",
+			opts:     storage.LinkOptions{ForwardQuery: true, QueryConflict: storage.QueryConflictRequest},
+			path:     "/promo?utm_source=x",
+			respCode: http.StatusFound,
+			location: "https://example.com/landing?utm_source=x",
+		},
+		{
+			name:     "query merged, both kept",
+			url:      "https://example.com/landing?tag=a",
+			opts:     storage.LinkOptions{ForwardQuery: true, QueryConflict: storage.QueryConflictBoth},
+			path:     "/promo?tag=b",
+			respCode: http.StatusFound,
+			location: "https://example.com/landing?tag=a&tag=b",
+		},
+		{
+			name:     "path appended",
+			url:      "https://example.com/base/",
+			opts:     storage.LinkOptions{ForwardPath: true},
+			path:     "/promo/docs/page",
+			respCode: http.StatusFound,
+			location: "https://example.com/base/docs/page",
+		},
+		{
+			name:     "path and query",
+			url:      "https://example.com",
+			opts:     storage.LinkOptions{ForwardPath: true, ForwardQuery: true},
+			path:     "/promo/docs?q=go",
+			respCode: http.StatusFound,
+			location: "https://example.com/docs?q=go",
+		},
+		{
+			name:     "encoded slash stays in segment",
+			url:      "https://example.com/files",
+			opts:     storage.LinkOptions{ForwardPath: true},
+			path:     "/promo/a%2Fb/c%20d",
+			respCode: http.StatusFound,
+			location: "https://example.com/files/a%2Fb/c%20d",
+		},
+		{
+			name:     "host can't be changed",
+			url:      "https://example.com",
+			opts:     storage.LinkOptions{ForwardPath: true},
+			path:     "/promo/@evil.com/",
+			respCode: http.StatusFound,
+			location: "https://example.com/@evil.com/",
+		},
+		{
+			name:     "dot segments rejected",
+			url:      "https://example.com/public",
+			opts:     storage.LinkOptions{ForwardPath: true},
+			path:     "/promo/%2e%2e/admin",
+			respCode: http.StatusBadRequest,
+		},
+		{
+			name:     "path not forwarded",
+			url:      "https://example.com/landing",
+			path:     "/promo/docs/page",
+			respCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+			mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", "promo").Return(storage.Link{
+				ID:          1,
+				Alias:       "promo",
+				URL:         tc.url,
+				LinkOptions: tc.opts,
+			}, nil).Times(1)
+
+			mockClickRecorder := mocks.NewMockClickRecorder(ctrl)
+			mockClickRecorder.EXPECT().RecordClick(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			handler := New(slogdiscard.NewDiscardLogger(), mockUrlGetter, mockClickRecorder, Options{})
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", handler)
+			r.Get("/{alias}/*", handler)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+			require.Equal(t, tc.location, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectRules(t *testing.T) {
+	const (
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15"
+		android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36"
+		mac     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15"
+	)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	rules := []storage.Rule{
+		{URL: "https://apps.apple.com/app", Platforms: []string{"ios"}},
+		{URL: "https://play.google.com/app", Platforms: []string{"android"}},
+		{URL: "https://example.com/beta", Headers: map[string]string{"X-Beta": ""}},
+		{URL: "https://example.com/twitter", Query: map[string]string{"ref": "twitter"}},
+		{URL: "https://example.com/launch", From: &future},
+		{URL: "https://example.com/sale", From: &past, Until: &future, Query: map[string]string{"sale": ""}},
+		{URL: "https://example.com/de", Languages: []string{"de"}},
+		{URL: "https://example.com/fr", Languages: []string{"fr"}},
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		userAgent string
+		headers   map[string]string
+		location  string
+	}{
+		{
+			name:      "ios",
+			path:      "/app",
+			userAgent: iPhone,
+			location:  "https://apps.apple.com/app",
+		},
+		{
+			name:      "android",
+			path:      "/app",
+			userAgent: android,
+			location:  "https://play.google.com/app",
+		},
+		{
+			name:      "no rule matches",
+			path:      "/app",
+			userAgent: mac,
+			location:  "https://example.com/",
+		},
+		{
+			name:     "header present",
+			path:     "/app",
+			headers:  map[string]string{"X-Beta": "yes"},
+			location: "https://example.com/beta",
+		},
+		{
+			name:     "query value",
+			path:     "/app?ref=twitter",
+			location: "https://example.com/twitter",
+		},
+		{
+			name:     "query value differs",
+			path:     "/app?ref=facebook",
+			location: "https://example.com/",
+		},
+		{
+			name:     "inside time window",
+			path:     "/app?sale",
+			location: "https://example.com/sale",
+		},
+		{
+			name:     "language region",
+			path:     "/app",
+			headers:  map[string]string{"Accept-Language": "de-AT,de;q=0.9"},
+			location: "https://example.com/de",
+		},
+		{
+			name:     "preferred language wins over rule order",
+			path:     "/app",
+			headers:  map[string]string{"Accept-Language": "de;q=0.5, fr-CH, en;q=0.8"},
+			location: "https://example.com/fr",
+		},
+		{
+			name:     "rejected language",
+			path:     "/app",
+			headers:  map[string]string{"Accept-Language": "de;q=0, en"},
+			location: "https://example.com/",
+		},
+		{
+			name:      "platform before language",
+			path:      "/app",
+			userAgent: iPhone,
+			headers:   map[string]string{"Accept-Language": "fr"},
+			location:  "https://apps.apple.com/app",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+			mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", "app").Return(storage.Link{
+				ID:          1,
+				Alias:       "app",
+				URL:         "https://example.com/",
+				LinkOptions: storage.LinkOptions{Rules: rules},
+			}, nil).Times(1)
+
+			mockClickRecorder := mocks.NewMockClickRecorder(ctrl)
+			mockClickRecorder.EXPECT().RecordClick(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), mockUrlGetter, mockClickRecorder, Options{}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			require.Equal(t, tc.location, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X)":                  PlatformIOS,
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8)":                       PlatformAndroid,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64)":                      PlatformWindows,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)":                PlatformMacOS,
+		"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101":       PlatformLinux,
+		"Mozilla/5.0 (Windows Phone 10.0; Android 6.0.1; Microsoft; RM)": "",
+		"curl/8.4.0": "",
+	}
+
+	for userAgent, platform := range tests {
+		require.Equal(t, platform, Platform(userAgent), userAgent)
+	}
+}
+
+func TestRedirectSplit(t *testing.T) {
+	// always draw the last of the 4 weight units, which belongs to c
+	randIntn = func(n int) int { return n - 1 }
+	defer func() { randIntn = rand.Intn }()
+
+	destinations := []storage.Destination{
+		{Name: "a", URL: "https://example.com/a", Weight: 0},
+		{Name: "b", URL: "https://example.com/b", Weight: 1},
+		{Name: "c", URL: "https://example.com/c", Weight: 3},
+	}
+
+	tests := []struct {
+		name     string
+		sticky   bool
+		cookie   string
+		rules    []storage.Rule
+		location string
+		variant  string
+		setsLink bool
+	}{
+		{
+			name:     "weighted draw",
+			location: "https://example.com/c",
+			variant:  "c",
+		},
+		{
+			name:     "cookie ignored without sticky_split",
+			cookie:   "b",
+			location: "https://example.com/c",
+			variant:  "c",
+		},
+		{
+			name:     "sticky cookie",
+			sticky:   true,
+			cookie:   "b",
+			location: "https://example.com/b",
+			variant:  "b",
+		},
+		{
+			name:     "sticky cookie issued",
+			sticky:   true,
+			location: "https://example.com/c",
+			variant:  "c",
+			setsLink: true,
+		},
+		{
+			name:     "cookie for paused destination",
+			sticky:   true,
+			cookie:   "a",
+			location: "https://example.com/c",
+			variant:  "c",
+			setsLink: true,
+		},
+		{
+			name:     "rule wins over split",
+			rules:    []storage.Rule{{URL: "https://example.com/rule"}},
+			location: "https://example.com/rule",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+			mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", "landing").Return(storage.Link{
+				ID:    7,
+				Alias: "landing",
+				URL:   "https://example.com/",
+				LinkOptions: storage.LinkOptions{
+					Rules:        tc.rules,
+					Destinations: destinations,
+					StickySplit:  tc.sticky,
+				},
+			}, nil).Times(1)
+
+			mockClickRecorder := mocks.NewMockClickRecorder(ctrl)
+			mockClickRecorder.EXPECT().RecordClick(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, click storage.Click) error {
+				require.Equal(t, int64(7), click.URLID)
+				require.Equal(t, tc.variant, click.Variant)
+
+				return nil
+			}).Times(1)
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), mockUrlGetter, mockClickRecorder, Options{}))
+
+			req := httptest.NewRequest(http.MethodGet, "/landing", nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "link_variant_7", Value: tc.cookie})
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			require.Equal(t, tc.location, rr.Header().Get("Location"))
+
+			cookies := rr.Result().Cookies()
+			if tc.setsLink {
+				require.Len(t, cookies, 1)
+				require.Equal(t, "link_variant_7", cookies[0].Name)
+				require.Equal(t, tc.variant, cookies[0].Value)
+				require.Equal(t, "/landing", cookies[0].Path)
+			} else {
+				require.Empty(t, cookies)
+			}
+		})
+	}
+}
+
+func TestDrawVariant(t *testing.T) {
+	destinations := []storage.Destination{
+		{Name: "a", Weight: 2},
+		{Name: "paused", Weight: 0},
+		{Name: "b", Weight: 1},
+	}
+
+	counts := map[string]int{}
+	for n := 0; n < 3; n++ {
+		d, ok := drawVariant(destinations, func(int) int { return n })
+		require.True(t, ok)
+		counts[d.Name]++
+	}
+	require.Equal(t, map[string]int{"a": 2, "b": 1}, counts)
+
+	_, ok := drawVariant([]storage.Destination{{Name: "a"}}, func(int) int { return 0 })
+	require.False(t, ok)
+}
+
+func TestRedirectClickLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		maxClicks    int
+		clicksLeft   int
+		redirectType int
+		consume      bool
+		consumeError error
+		respCode     int
+		respBody     string
+	}{
+		{
+			name:         "clicks left",
+			method:       http.MethodGet,
+			maxClicks:    10,
+			clicksLeft:   3,
+			redirectType: http.StatusMovedPermanently,
+			consume:      true,
+			respCode:     http.StatusFound,
+		},
+		{
+			name:         "permanent redirect kept temporary",
+			method:       http.MethodGet,
+			maxClicks:    10,
+			clicksLeft:   3,
+			redirectType: http.StatusPermanentRedirect,
+			consume:      true,
+			respCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:       "exhausted",
+			method:     http.MethodGet,
+			maxClicks:  10,
+			clicksLeft: 0,
+			respCode:   http.StatusGone,
+			respBody:   "link has reached its click limit",
+		},
+		{
+			name:         "exhausted by concurrent request",
+			method:       http.MethodGet,
+			maxClicks:    10,
+			clicksLeft:   1,
+			consume:      true,
+			consumeError: storage.ErrClicksExhausted,
+			respCode:     http.StatusGone,
+			respBody:     "link has reached its click limit",
+		},
+		{
+			name:         "consume error",
+			method:       http.MethodGet,
+			maxClicks:    10,
+			clicksLeft:   1,
+			consume:      true,
+			consumeError: errors.New("unexpected error"),
+			respCode:     http.StatusOK,
+			respBody:     "internal error",
+		},
+		{
+			name:       "one-time asks for confirmation",
+			method:     http.MethodGet,
+			maxClicks:  1,
+			clicksLeft: 1,
+			respCode:   http.StatusOK,
+			respBody:   "can only be opened once",
+		},
+		{
+			name:       "one-time confirmed",
+			method:     http.MethodPost,
+			maxClicks:  1,
+			clicksLeft: 1,
+			consume:    true,
+			respCode:   http.StatusSeeOther,
+		},
+		{
+			name:       "one-time used",
+			method:     http.MethodGet,
+			maxClicks:  1,
+			clicksLeft: 0,
+			respCode:   http.StatusGone,
+			respBody:   "link has already been used",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+			mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", "invite").Return(storage.Link{
+				ID:    5,
+				Alias: "invite",
+				URL:   "https://example.com/invite",
+				LinkOptions: storage.LinkOptions{
+					RedirectType: tc.redirectType,
+					MaxClicks:    tc.maxClicks,
+				},
+				ClicksLeft: tc.clicksLeft,
+			}, nil).Times(1)
+
+			mockClickRecorder := mocks.NewMockClickRecorder(ctrl)
+			if tc.consume {
+				mockClickRecorder.EXPECT().ConsumeClick(gomock.Any(), int64(5)).Return(tc.consumeError).Times(1)
+			}
+			if tc.consume && tc.consumeError == nil {
+				mockClickRecorder.EXPECT().RecordClick(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			}
+
+			r := chi.NewRouter()
+			handler := New(slogdiscard.NewDiscardLogger(), mockUrlGetter, mockClickRecorder, Options{})
+			r.Get("/{alias}", handler)
+			r.Post("/{alias}", handler)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(tc.method, "/invite", nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+			require.Contains(t, rr.Body.String(), tc.respBody)
+
+			if tc.consume && tc.consumeError == nil {
+				require.Equal(t, "https://example.com/invite", rr.Header().Get("Location"))
+				require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestRedirectActiveWindow(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		opts         storage.LinkOptions
+		redirectOpts Options
+		respCode     int
+		location     string
+		respBody     string
+		retryAfter   bool
+	}{
+		{
+			name:     "inside window",
+			opts:     storage.LinkOptions{ActiveFrom: &past, ActiveUntil: &future, RedirectType: http.StatusMovedPermanently},
+			respCode: http.StatusFound,
+			location: "https://example.com/sale",
+		},
+		{
+			name:       "not active yet",
+			opts:       storage.LinkOptions{ActiveFrom: &future},
+			respCode:   http.StatusNotFound,
+			respBody:   "link is not active yet",
+			retryAfter: true,
+		},
+		{
+			name:         "not active yet, configured status",
+			opts:         storage.LinkOptions{ActiveFrom: &future},
+			redirectOpts: Options{NotActiveStatus: http.StatusForbidden},
+			respCode:     http.StatusForbidden,
+			respBody:     "link is not active yet",
+			retryAfter:   true,
+		},
+		{
+			name:     "expired",
+			opts:     storage.LinkOptions{ActiveUntil: &past},
+			respCode: http.StatusGone,
+			respBody: "link has expired",
+		},
+		{
+			name:     "link fallback",
+			opts:     storage.LinkOptions{ActiveFrom: &future, FallbackURL: "https://example.com/soon"},
+			respCode: http.StatusFound,
+			location: "https://example.com/soon",
+		},
+		{
+			name:         "configured fallback",
+			opts:         storage.LinkOptions{ActiveUntil: &past},
+			redirectOpts: Options{NotActiveURL: "https://example.com/over"},
+			respCode:     http.StatusFound,
+			location:     "https://example.com/over",
+		},
+		{
+			name:         "blocked link fallback",
+			opts:         storage.LinkOptions{ActiveFrom: &future, FallbackURL: "http://10.0.0.1/admin"},
+			redirectOpts: Options{Screener: screener},
+			respCode:     http.StatusForbidden,
+			respBody:     "url is blocked",
+		},
+		{
+			name:         "blocked configured fallback",
+			opts:         storage.LinkOptions{ActiveUntil: &past},
+			redirectOpts: Options{NotActiveURL: "http://localhost/over", Screener: screener},
+			respCode:     http.StatusForbidden,
+			respBody:     "url is blocked",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+			mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", "sale").Return(storage.Link{
+				ID:          1,
+				Alias:       "sale",
+				URL:         "https://example.com/sale",
+				LinkOptions: tc.opts,
+			}, nil).Times(1)
+
+			mockClickRecorder := mocks.NewMockClickRecorder(ctrl)
+			if tc.location == "https://example.com/sale" {
+				mockClickRecorder.EXPECT().RecordClick(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), mockUrlGetter, mockClickRecorder, tc.redirectOpts))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/sale", nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+			require.Equal(t, tc.location, rr.Header().Get("Location"))
+			require.Contains(t, rr.Body.String(), tc.respBody)
+			require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			require.Equal(t, tc.retryAfter, rr.Header().Get("Retry-After") != "")
+		})
+	}
+}
+
+func TestRedirectScreening(t *testing.T) {
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		url      string
+		respCode int
+	}{
+		{
+			name:     "allowed",
+			url:      "https://example.com/sale",
+			respCode: http.StatusFound,
+		},
+		{
+			name:     "private network",
+			url:      "http://10.0.0.1/admin",
+			respCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+			mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", "sale").Return(storage.Link{
+				ID:          1,
+				Alias:       "sale",
+				URL:         tc.url,
+				LinkOptions: storage.LinkOptions{MaxClicks: 5},
+				ClicksLeft:  5,
+			}, nil).Times(1)
+
+			// rejected links neither use up a click nor record one
+			mockClickRecorder := mocks.NewMockClickRecorder(ctrl)
+			if tc.respCode == http.StatusFound {
+				mockClickRecorder.EXPECT().ConsumeClick(gomock.Any(), int64(1)).Return(nil).Times(1)
+				mockClickRecorder.EXPECT().RecordClick(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), mockUrlGetter, mockClickRecorder, Options{Screener: screener}))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/sale", nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+			if tc.respCode == http.StatusForbidden {
+				require.Empty(t, rr.Header().Get("Location"))
+				require.Equal(t, `"url is blocked"`, strings.TrimSpace(rr.Body.String()))
+			}
 		})
 	}
 }
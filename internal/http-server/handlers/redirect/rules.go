@@ -0,0 +1,183 @@
+package redirect
+
+import (
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Platforms that rules can match on.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+)
+
+// matchRules returns the url of the first rule of the link matching the
+// request. Accepted languages are tried in order of preference, so a visitor
+// preferring fr over de gets the fr rule even when the de rule comes first.
+func matchRules(link storage.Link, r *http.Request, now time.Time) (string, bool) {
+	if len(link.Rules) == 0 {
+		return "", false
+	}
+
+	platform := Platform(r.UserAgent())
+
+	languages := acceptLanguages(r.Header.Get("Accept-Language"))
+	if len(languages) == 0 {
+		languages = []string{""}
+	}
+
+	for _, lang := range languages {
+		for _, rule := range link.Rules {
+			if ruleMatches(rule, r, platform, lang, now) {
+				return rule.URL, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func ruleMatches(rule storage.Rule, r *http.Request, platform, lang string, now time.Time) bool {
+	if rule.From != nil && now.Before(*rule.From) {
+		return false
+	}
+	if rule.Until != nil && !now.Before(*rule.Until) {
+		return false
+	}
+
+	if len(rule.Platforms) > 0 && !contains(rule.Platforms, platform) {
+		return false
+	}
+
+	if len(rule.Languages) > 0 && !languageMatches(rule.Languages, lang) {
+		return false
+	}
+
+	query := r.URL.Query()
+	for key, value := range rule.Query {
+		if !valueMatches(query[key], value) {
+			return false
+		}
+	}
+
+	for key, value := range rule.Headers {
+		if !valueMatches(r.Header.Values(key), value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// valueMatches reports whether want is among values, an empty want only
+// requires the key to be present.
+func valueMatches(values []string, want string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	if want == "" {
+		return true
+	}
+
+	return contains(values, want)
+}
+
+// languageMatches compares language tags case-insensitively, a rule for "de"
+// also matches "de-AT" but a rule for "de-AT" doesn't match plain "de".
+func languageMatches(ruleLanguages []string, lang string) bool {
+	if lang == "" {
+		return false
+	}
+
+	for _, rl := range ruleLanguages {
+		rl = strings.ToLower(rl)
+		if lang == rl || strings.HasPrefix(lang, rl+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Platform guesses the operating system from a user agent, it returns an
+// empty string when it's unknown.
+func Platform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "Windows Phone"):
+		return ""
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return PlatformIOS
+	case strings.Contains(userAgent, "Android"):
+		return PlatformAndroid
+	case strings.Contains(userAgent, "Windows"):
+		return PlatformWindows
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return PlatformMacOS
+	case strings.Contains(userAgent, "Linux"), strings.Contains(userAgent, "X11"):
+		return PlatformLinux
+	}
+
+	return ""
+}
+
+// acceptLanguages returns the lower-cased tags of an Accept-Language header
+// sorted by quality, tags with q=0 and the wildcard are dropped.
+func acceptLanguages(header string) []string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || lang == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				parsed = 0
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		tags = append(tags, tag{lang: lang, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	languages := make([]string, 0, len(tags))
+	for _, t := range tags {
+		languages = append(languages, t.lang)
+	}
+
+	return languages
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+
+	return false
+}
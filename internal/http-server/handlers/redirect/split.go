@@ -0,0 +1,97 @@
+package redirect
+
+import (
+	"golang-url-shortener/internal/storage"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const variantCookiePrefix = "link_variant_"
+
+// randIntn is replaced in tests to make the draw predictable.
+var randIntn = rand.Intn
+
+// pickURL returns the url to redirect to before passthrough is applied and
+// the name of the destination it was drawn from, empty when a rule or the
+// url of the link was used.
+func pickURL(w http.ResponseWriter, r *http.Request, link storage.Link, opts Options) (string, string) {
+	if target, ok := matchRules(link, r, time.Now()); ok {
+		return target, ""
+	}
+
+	if len(link.Destinations) == 0 {
+		return link.URL, ""
+	}
+
+	if link.StickySplit {
+		if cookie, err := r.Cookie(variantCookieName(link)); err == nil {
+			if d, ok := findVariant(link.Destinations, cookie.Value); ok {
+				return d.URL, d.Name
+			}
+		}
+	}
+
+	d, ok := drawVariant(link.Destinations, randIntn)
+	if !ok {
+		return link.URL, ""
+	}
+
+	if link.StickySplit {
+		http.SetCookie(w, &http.Cookie{
+			Name:     variantCookieName(link),
+			Value:    d.Name,
+			Path:     "/" + url.PathEscape(link.Alias),
+			Expires:  time.Now().Add(opts.VariantCookieTTL),
+			MaxAge:   int(opts.VariantCookieTTL.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	return d.URL, d.Name
+}
+
+// findVariant looks up a destination that is still in rotation by name.
+func findVariant(destinations []storage.Destination, name string) (storage.Destination, bool) {
+	for _, d := range destinations {
+		if d.Name == name && d.Weight > 0 {
+			return d, true
+		}
+	}
+
+	return storage.Destination{}, false
+}
+
+// drawVariant picks a destination with a probability proportional to its
+// weight using intn as the random source. It fails when all weights are 0.
+func drawVariant(destinations []storage.Destination, intn func(n int) int) (storage.Destination, bool) {
+	total := 0
+	for _, d := range destinations {
+		if d.Weight > 0 {
+			total += d.Weight
+		}
+	}
+	if total == 0 {
+		return storage.Destination{}, false
+	}
+
+	n := intn(total)
+	for _, d := range destinations {
+		if d.Weight <= 0 {
+			continue
+		}
+		if n < d.Weight {
+			return d, true
+		}
+		n -= d.Weight
+	}
+
+	return storage.Destination{}, false
+}
+
+func variantCookieName(link storage.Link) string {
+	return variantCookiePrefix + strconv.FormatInt(link.ID, 10)
+}
@@ -0,0 +1,56 @@
+package redirect
+
+import (
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
+)
+
+// checkWindow guards the activation window of a link. It reports whether the
+// link is active, otherwise the response has already been written: a redirect
+// to the fallback url when there is one and it passes the screener, the not
+// active status before the window and 410 after it.
+func checkWindow(log *slog.Logger, w http.ResponseWriter, r *http.Request, link storage.Link,
+	opts Options, now time.Time) bool {
+	early := link.ActiveFrom != nil && now.Before(*link.ActiveFrom)
+	expired := link.ActiveUntil != nil && !now.Before(*link.ActiveUntil)
+	if !early && !expired {
+		return true
+	}
+
+	log.Info("link is not active", slog.String("alias", link.Alias), slog.Bool("expired", expired))
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	fallback := link.FallbackURL
+	if fallback == "" {
+		fallback = opts.NotActiveURL
+	}
+	if fallback != "" {
+		// the fallback is screened like any other destination, the
+		// blocklist may have changed since the link was saved
+		if err := opts.Screener.Check(fallback); err != nil {
+			log.Warn("fallback rejected", sl.Err(err))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, "url is blocked")
+			return false
+		}
+
+		http.Redirect(w, r, fallback, http.StatusFound)
+		return false
+	}
+
+	if early {
+		w.Header().Set("Retry-After", link.ActiveFrom.UTC().Format(http.TimeFormat))
+		render.Status(r, opts.NotActiveStatus)
+		render.JSON(w, r, "link is not active yet")
+		return false
+	}
+
+	render.Status(r, http.StatusGone)
+	render.JSON(w, r, "link has expired")
+	return false
+}
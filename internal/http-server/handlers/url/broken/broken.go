@@ -0,0 +1,72 @@
+package broken
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
+)
+
+//go:generate mockgen -source=broken.go -destination=mocks/brokenmock.go -package=mocks
+type BrokenLister interface {
+	BrokenLinks(ctx context.Context) ([]storage.BrokenLink, error)
+}
+
+type Link struct {
+	Alias  string `json:"alias"`
+	Domain string `json:"domain,omitempty"`
+	// URL is the destination that failed, not necessarily the url of the link.
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	LatencyMS  int64     `json:"latency_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type Response struct {
+	response.Response
+	Links []Link `json:"links"`
+}
+
+// New lists the links whose destinations failed their last health check.
+func New(log *slog.Logger, brokenLister BrokenLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.broken.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		brokenLinks, err := brokenLister.BrokenLinks(r.Context())
+		if err != nil {
+			log.Error("failed to list broken links", sl.Err(err))
+			render.JSON(w, r, response.Error("internal error"))
+			return
+		}
+
+		links := make([]Link, 0, len(brokenLinks))
+		for _, link := range brokenLinks {
+			links = append(links, Link{
+				Alias:      link.Alias,
+				Domain:     link.Domain,
+				URL:        link.URL,
+				StatusCode: link.StatusCode,
+				Error:      link.Error,
+				LatencyMS:  link.Latency.Milliseconds(),
+				CheckedAt:  link.CheckedAt,
+			})
+		}
+
+		log.Info("broken links listed", slog.Int("count", len(links)))
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Links:    links,
+		})
+	}
+}
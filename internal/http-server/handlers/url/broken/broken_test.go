@@ -0,0 +1,81 @@
+package broken
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/url/broken/mocks"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBroken(t *testing.T) {
+	checkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		links     []storage.BrokenLink
+		mockError error
+		respError string
+		expected  []Link
+	}{
+		{
+			name:     "none",
+			expected: []Link{},
+		},
+		{
+			name: "broken",
+			links: []storage.BrokenLink{
+				{Alias: "sale", Check: storage.Check{
+					URL: "https://shop.example.com/sale", StatusCode: http.StatusNotFound,
+					Latency: 120 * time.Millisecond, CheckedAt: checkedAt,
+				}},
+				{Alias: "docs", Domain: "go.brand-a.com", Check: storage.Check{
+					URL: "https://docs.example.com", Error: "connection refused",
+					Latency: 3 * time.Millisecond, CheckedAt: checkedAt,
+				}},
+			},
+			expected: []Link{
+				{Alias: "sale", URL: "https://shop.example.com/sale", StatusCode: http.StatusNotFound, LatencyMS: 120, CheckedAt: checkedAt},
+				{Alias: "docs", Domain: "go.brand-a.com", URL: "https://docs.example.com", Error: "connection refused", LatencyMS: 3, CheckedAt: checkedAt},
+			},
+		},
+		{
+			name:      "error with db",
+			mockError: errors.New("another error"),
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockBrokenLister := mocks.NewMockBrokenLister(ctrl)
+			mockBrokenLister.EXPECT().BrokenLinks(gomock.Any()).Return(tc.links, tc.mockError).Times(1)
+
+			rr := httptest.NewRecorder()
+			New(slogdiscard.NewDiscardLogger(), mockBrokenLister).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/broken", nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				require.Equal(t, response.StatusError, resp.Status)
+				return
+			}
+
+			require.Equal(t, tc.expected, resp.Links)
+		})
+	}
+}
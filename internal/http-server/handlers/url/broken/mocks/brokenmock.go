@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: broken.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockBrokenLister is a mock of BrokenLister interface.
+type MockBrokenLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockBrokenListerMockRecorder
+}
+
+// MockBrokenListerMockRecorder is the mock recorder for MockBrokenLister.
+type MockBrokenListerMockRecorder struct {
+	mock *MockBrokenLister
+}
+
+// NewMockBrokenLister creates a new mock instance.
+func NewMockBrokenLister(ctrl *gomock.Controller) *MockBrokenLister {
+	mock := &MockBrokenLister{ctrl: ctrl}
+	mock.recorder = &MockBrokenListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBrokenLister) EXPECT() *MockBrokenListerMockRecorder {
+	return m.recorder
+}
+
+// BrokenLinks mocks base method.
+func (m *MockBrokenLister) BrokenLinks(ctx context.Context) ([]storage.BrokenLink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BrokenLinks", ctx)
+	ret0, _ := ret[0].([]storage.BrokenLink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BrokenLinks indicates an expected call of BrokenLinks.
+func (mr *MockBrokenListerMockRecorder) BrokenLinks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BrokenLinks", reflect.TypeOf((*MockBrokenLister)(nil).BrokenLinks), ctx)
+}
@@ -1,11 +1,15 @@
 package delete
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/chi"
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/http-server/middleware/logger"
 	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/auditlog"
+	"golang-url-shortener/internal/lib/domains"
 	"golang-url-shortener/internal/lib/logger/sl"
 	"golang-url-shortener/internal/storage"
 	"golang.org/x/exp/slog"
@@ -14,16 +18,19 @@ import (
 
 //go:generate mockgen -source=delete.go -destination=mocks/deletemock.go -package=mocks
 type URLDeleter interface {
-	DeleteURL(alias string) error
+	DeleteURL(ctx context.Context, domain, alias string, events ...storage.Event) error
 }
 
-func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
+// New deletes the link with the alias, the domain query parameter selects
+// one of the configured short domains. The deleted link is recorded in
+// auditLog, its webhook events are queued along with the delete.
+func New(log *slog.Logger, urlDeleter URLDeleter, registry *domains.Registry, auditLog *auditlog.Log) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.delete.New"
 
-		log = log.With(
+		log := logger.ForRequest(log, r).With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("subject", auth.Subject(r.Context())),
 		)
 
 		alias := chi.URLParam(r, "alias")
@@ -35,7 +42,23 @@ func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
 			return
 		}
 
-		err := urlDeleter.DeleteURL(alias)
+		domain, err := registry.Lookup(r.URL.Query().Get("domain"))
+		if err != nil {
+			log.Info("unknown domain", slog.String("domain", r.URL.Query().Get("domain")))
+			render.JSON(w, r, response.Error("unknown domain"))
+			return
+		}
+
+		before := auditLog.Snapshot(r.Context(), domain, alias)
+
+		events, err := auditLog.Events(r, storage.AuditDelete, domain, alias, before, nil)
+		if err != nil {
+			log.Error("failed to prepare webhook events", sl.Err(err))
+			render.JSON(w, r, response.Error("internal error"))
+			return
+		}
+
+		err = urlDeleter.DeleteURL(r.Context(), domain, alias, events...)
 
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Info("url not found", sl.Err(err))
@@ -51,6 +74,8 @@ func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
 
 		log.Info("url deleted", slog.String("alias", alias))
 
+		auditLog.Record(r, storage.AuditDelete, domain, alias, before, nil)
+
 		render.JSON(w, r, response.OK())
 	}
 }
@@ -46,10 +46,10 @@ func TestDelete(t *testing.T) {
 			mockUrlDeleter := mocks.NewMockURLDeleter(ctrl)
 
 			if tc.mockError != nil || tc.respError == "" {
-				mockUrlDeleter.EXPECT().DeleteURL(tc.alias).Return(tc.mockError)
+				mockUrlDeleter.EXPECT().DeleteURL(gomock.Any(), "", tc.alias).Return(tc.mockError)
 			}
 
-			handler := New(slogdiscard.NewDiscardLogger(), mockUrlDeleter)
+			handler := New(slogdiscard.NewDiscardLogger(), mockUrlDeleter, nil, nil)
 			router := chi.NewRouter()
 			router.Delete("/url/{alias}", handler)
 
@@ -5,6 +5,8 @@
 package mocks
 
 import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,15 +36,20 @@ func (m *MockURLDeleter) EXPECT() *MockURLDeleterMockRecorder {
 }
 
 // DeleteURL mocks base method.
-func (m *MockURLDeleter) DeleteURL(alias string) error {
+func (m *MockURLDeleter) DeleteURL(ctx context.Context, domain, alias string, events ...storage.Event) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteURL", alias)
+	varargs := []interface{}{ctx, domain, alias}
+	for _, a := range events {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteURL", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteURL indicates an expected call of DeleteURL.
-func (mr *MockURLDeleterMockRecorder) DeleteURL(alias interface{}) *gomock.Call {
+func (mr *MockURLDeleterMockRecorder) DeleteURL(ctx, domain, alias interface{}, events ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURL", reflect.TypeOf((*MockURLDeleter)(nil).DeleteURL), alias)
+	varargs := append([]interface{}{ctx, domain, alias}, events...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURL", reflect.TypeOf((*MockURLDeleter)(nil).DeleteURL), varargs...)
 }
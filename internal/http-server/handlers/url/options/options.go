@@ -0,0 +1,173 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"golang-url-shortener/internal/lib/password"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"time"
+)
+
+// Options are the per-link settings accepted by both save and update requests.
+// An update keeps the stored value of the pointer options left out of it.
+type Options struct {
+	RedirectType  *int   `json:"redirect_type,omitempty" validate:"omitempty,oneof=0 301 302 303 307 308"`
+	Password      string `json:"password,omitempty" validate:"omitempty,min=4,max=72"`
+	ForwardQuery  bool   `json:"forward_query,omitempty"`
+	QueryConflict string `json:"query_conflict,omitempty" validate:"omitempty,oneof=link request both"`
+	ForwardPath   bool   `json:"forward_path,omitempty"`
+	Rules         []Rule `json:"rules,omitempty" validate:"omitempty,max=20,dive"`
+
+	Destinations []Destination `json:"destinations,omitempty" validate:"omitempty,min=2,max=10,dive"`
+	StickySplit  bool          `json:"sticky_split,omitempty"`
+
+	MaxClicks *int `json:"max_clicks,omitempty" validate:"omitempty,min=0,max=1000000"`
+
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+	FallbackURL string     `json:"fallback_url,omitempty" validate:"omitempty,url"`
+}
+
+// ErrInvalid is returned by LinkOptions for options the validator can't check.
+var ErrInvalid = errors.New("invalid options")
+
+// Destination is a variant of an A/B split, it receives Weight out of the sum
+// of all weights of the traffic. A weight of 0 takes it out of rotation.
+type Destination struct {
+	Name   string `json:"name" validate:"required,max=32"`
+	URL    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"min=0,max=10000"`
+}
+
+// Rule sends the visitor to URL instead of the url of the link when all of
+// its conditions match, rules are checked in order.
+type Rule struct {
+	URL       string            `json:"url" validate:"required,url"`
+	Platforms []string          `json:"platforms,omitempty" validate:"omitempty,dive,oneof=ios android windows macos linux"`
+	Languages []string          `json:"languages,omitempty" validate:"omitempty,dive,min=1,max=35"`
+	Query     map[string]string `json:"query,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	From      *time.Time        `json:"from,omitempty"`
+	Until     *time.Time        `json:"until,omitempty"`
+}
+
+// LinkOptions converts the request options to the stored ones, hashing the password.
+func (o Options) LinkOptions() (storage.LinkOptions, error) {
+	opts := storage.LinkOptions{
+		ForwardQuery:  o.ForwardQuery,
+		QueryConflict: o.QueryConflict,
+		ForwardPath:   o.ForwardPath,
+		ActiveFrom:    o.ActiveFrom,
+		ActiveUntil:   o.ActiveUntil,
+		FallbackURL:   o.FallbackURL,
+	}
+
+	if o.RedirectType != nil {
+		opts.RedirectType = *o.RedirectType
+	} else {
+		opts.Keep.RedirectType = true
+	}
+
+	if o.MaxClicks != nil {
+		opts.MaxClicks = *o.MaxClicks
+	} else {
+		opts.Keep.MaxClicks = true
+	}
+
+	opts.Keep.ActiveFrom = o.ActiveFrom == nil
+	opts.Keep.ActiveUntil = o.ActiveUntil == nil
+
+	if o.ActiveFrom != nil && o.ActiveUntil != nil && !o.ActiveUntil.After(*o.ActiveFrom) {
+		return storage.LinkOptions{}, fmt.Errorf("%w: active_until must be after active_from", ErrInvalid)
+	}
+
+	for _, rule := range o.Rules {
+		opts.Rules = append(opts.Rules, storage.Rule{
+			URL:       rule.URL,
+			Platforms: rule.Platforms,
+			Languages: rule.Languages,
+			Query:     rule.Query,
+			Headers:   rule.Headers,
+			From:      rule.From,
+			Until:     rule.Until,
+		})
+	}
+
+	if len(o.Destinations) > 0 {
+		names := make(map[string]bool, len(o.Destinations))
+		total := 0
+		for _, d := range o.Destinations {
+			if names[d.Name] {
+				return storage.LinkOptions{}, fmt.Errorf("%w: destination %s is listed twice", ErrInvalid, d.Name)
+			}
+			names[d.Name] = true
+			total += d.Weight
+
+			opts.Destinations = append(opts.Destinations, storage.Destination{
+				Name:   d.Name,
+				URL:    d.URL,
+				Weight: d.Weight,
+			})
+		}
+
+		if total == 0 {
+			return storage.LinkOptions{}, fmt.Errorf("%w: all destinations have weight 0", ErrInvalid)
+		}
+
+		opts.StickySplit = o.StickySplit
+	}
+
+	if o.Password != "" {
+		hash, err := password.Hash(o.Password)
+		if err != nil {
+			return storage.LinkOptions{}, err
+		}
+		opts.PasswordHash = hash
+	}
+
+	return opts, nil
+}
+
+// URLs lists the urls the options can redirect to besides the url of the link.
+func (o Options) URLs() []string {
+	var urls []string
+	for _, rule := range o.Rules {
+		urls = append(urls, rule.URL)
+	}
+	for _, dest := range o.Destinations {
+		urls = append(urls, dest.URL)
+	}
+	if o.FallbackURL != "" {
+		urls = append(urls, o.FallbackURL)
+	}
+
+	return urls
+}
+
+// LogAttrs describes the options for logging without the plain password.
+func (o Options) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		optionalInt("redirect_type", o.RedirectType),
+		slog.Bool("password", o.Password != ""),
+		slog.Bool("forward_query", o.ForwardQuery),
+		slog.String("query_conflict", o.QueryConflict),
+		slog.Bool("forward_path", o.ForwardPath),
+		slog.Int("rules", len(o.Rules)),
+		slog.Int("destinations", len(o.Destinations)),
+		slog.Bool("sticky_split", o.StickySplit),
+		optionalInt("max_clicks", o.MaxClicks),
+		slog.Any("active_from", o.ActiveFrom),
+		slog.Any("active_until", o.ActiveUntil),
+		slog.String("fallback_url", o.FallbackURL),
+	}
+}
+
+// optionalInt logs an option left out of the request as null.
+func optionalInt(key string, v *int) slog.Attr {
+	if v == nil {
+		return slog.Any(key, nil)
+	}
+
+	return slog.Int(key, *v)
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: qrcode.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockURLGetter is a mock of URLGetter interface.
+type MockURLGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockURLGetterMockRecorder
+}
+
+// MockURLGetterMockRecorder is the mock recorder for MockURLGetter.
+type MockURLGetterMockRecorder struct {
+	mock *MockURLGetter
+}
+
+// NewMockURLGetter creates a new mock instance.
+func NewMockURLGetter(ctrl *gomock.Controller) *MockURLGetter {
+	mock := &MockURLGetter{ctrl: ctrl}
+	mock.recorder = &MockURLGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockURLGetter) EXPECT() *MockURLGetterMockRecorder {
+	return m.recorder
+}
+
+// GetLink mocks base method.
+func (m *MockURLGetter) GetLink(ctx context.Context, domain, alias string) (storage.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLink", ctx, domain, alias)
+	ret0, _ := ret[0].(storage.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLink indicates an expected call of GetLink.
+func (mr *MockURLGetterMockRecorder) GetLink(ctx, domain, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLink", reflect.TypeOf((*MockURLGetter)(nil).GetLink), ctx, domain, alias)
+}
@@ -0,0 +1,213 @@
+package qrcode
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/domains"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/lib/qr"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSize   = 256
+	minSize       = 64
+	maxSize       = 2048
+	defaultMargin = 4
+	maxMargin     = 16
+	defaultLevel  = "M"
+)
+
+//go:generate mockgen -source=qrcode.go -destination=mocks/qrcodemock.go -package=mocks
+type URLGetter interface {
+	GetLink(ctx context.Context, domain, alias string) (storage.Link, error)
+}
+
+type Options struct {
+	// BaseURL is the public address short links are served at.
+	// The scheme and host of the request are used when empty.
+	BaseURL string
+	// Domains is used for links on other short domains, chosen with the
+	// domain query parameter.
+	Domains *domains.Registry
+}
+
+func New(log *slog.Logger, urlGetter URLGetter, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.qrcode.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		if alias == "" {
+			log.Info("alias is empty")
+			renderError(w, r, http.StatusBadRequest, "invalid request")
+			return
+		}
+
+		qrOpts, err := parseOptions(r)
+		if err != nil {
+			log.Info("invalid qr options", sl.Err(err))
+			renderError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		domain, err := opts.Domains.Lookup(r.URL.Query().Get("domain"))
+		if err != nil {
+			log.Info("unknown domain", slog.String("domain", r.URL.Query().Get("domain")))
+			renderError(w, r, http.StatusBadRequest, "unknown domain")
+			return
+		}
+
+		_, err = urlGetter.GetLink(r.Context(), domain, alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			renderError(w, r, http.StatusNotFound, "url not found")
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			renderError(w, r, http.StatusInternalServerError, "internal error")
+			return
+		}
+
+		base := opts.Domains.BaseURL(domain, baseURL(r, opts.BaseURL))
+		shortURL := strings.TrimRight(base, "/") + "/" + url.PathEscape(alias)
+
+		image, err := qr.Encode(shortURL, qrOpts)
+		if err != nil {
+			log.Error("failed to encode qr code", sl.Err(err))
+			renderError(w, r, http.StatusInternalServerError, "failed to encode qr code")
+			return
+		}
+
+		sum := sha256.Sum256(image)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		w.Header().Set("Vary", "Accept")
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if qrOpts.Format == qr.FormatSVG {
+			w.Header().Set("Content-Type", "image/svg+xml")
+		} else {
+			w.Header().Set("Content-Type", "image/png")
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+
+		log.Info("qr code generated", slog.String("url", shortURL), slog.String("format", qrOpts.Format))
+
+		_, _ = w.Write(image)
+	}
+}
+
+func parseOptions(r *http.Request) (qr.Options, error) {
+	query := r.URL.Query()
+
+	opts := qr.Options{
+		Size:   defaultSize,
+		Level:  defaultLevel,
+		Margin: defaultMargin,
+		Format: format(r),
+	}
+
+	if v := query.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < minSize || size > maxSize {
+			return qr.Options{}, fmt.Errorf("size must be between %d and %d", minSize, maxSize)
+		}
+		opts.Size = size
+	}
+
+	if v := query.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > maxMargin {
+			return qr.Options{}, fmt.Errorf("margin must be between 0 and %d", maxMargin)
+		}
+		opts.Margin = margin
+	}
+
+	if v := query.Get("level"); v != "" {
+		switch level := strings.ToUpper(v); level {
+		case "L", "M", "Q", "H":
+			opts.Level = level
+		default:
+			return qr.Options{}, errors.New("level must be one of L, M, Q, H")
+		}
+	}
+
+	if opts.Format != qr.FormatPNG && opts.Format != qr.FormatSVG {
+		return qr.Options{}, errors.New("format must be png or svg")
+	}
+
+	return opts, nil
+}
+
+// format picks the image format from the query, the url extension
+// (/url/{alias}/qr.svg) or the Accept header, in that order.
+func format(r *http.Request) string {
+	if v := r.URL.Query().Get("format"); v != "" {
+		return strings.ToLower(v)
+	}
+
+	if v, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); v != "" {
+		return strings.ToLower(v)
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "image/svg+xml") {
+		return qr.FormatSVG
+	}
+
+	return qr.FormatPNG
+}
+
+func baseURL(r *http.Request, configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, response.Error(msg))
+}
@@ -0,0 +1,168 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/url/qrcode/mocks"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQRCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		alias       string
+		mockError   error
+		respCode    int
+		contentType string
+		respError   string
+		pngSize     int
+	}{
+		{
+			name:        "default png",
+			path:        "/url/youtube/qr",
+			alias:       "youtube",
+			respCode:    http.StatusOK,
+			contentType: "image/png",
+			pngSize:     defaultSize,
+		},
+		{
+			name:        "custom size",
+			path:        "/url/youtube/qr?size=512&level=H&margin=0",
+			alias:       "youtube",
+			respCode:    http.StatusOK,
+			contentType: "image/png",
+			pngSize:     512,
+		},
+		{
+			name:        "svg by query",
+			path:        "/url/youtube/qr?format=svg",
+			alias:       "youtube",
+			respCode:    http.StatusOK,
+			contentType: "image/svg+xml",
+		},
+		{
+			name:        "svg by extension",
+			path:        "/url/youtube/qr.svg",
+			alias:       "youtube",
+			respCode:    http.StatusOK,
+			contentType: "image/svg+xml",
+		},
+		{
+			name:        "svg by accept",
+			path:        "/url/youtube/qr",
+			accept:      "image/svg+xml",
+			alias:       "youtube",
+			respCode:    http.StatusOK,
+			contentType: "image/svg+xml",
+		},
+		{
+			name:      "size too big",
+			path:      "/url/youtube/qr?size=100000",
+			respCode:  http.StatusBadRequest,
+			respError: "size must be between 64 and 2048",
+		},
+		{
+			name:      "invalid level",
+			path:      "/url/youtube/qr?level=X",
+			respCode:  http.StatusBadRequest,
+			respError: "level must be one of L, M, Q, H",
+		},
+		{
+			name:      "invalid format",
+			path:      "/url/youtube/qr.gif",
+			respCode:  http.StatusBadRequest,
+			respError: "format must be png or svg",
+		},
+		{
+			name:      "url not found",
+			path:      "/url/missing/qr",
+			alias:     "missing",
+			mockError: storage.ErrUrlNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "url not found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+
+			if tc.alias != "" {
+				mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", tc.alias).Return(storage.Link{Alias: tc.alias}, tc.mockError).Times(1)
+			}
+
+			r := chi.NewRouter()
+			r.Use(middleware.URLFormat)
+			r.Get("/url/{alias}/qr", New(slogdiscard.NewDiscardLogger(), mockUrlGetter, Options{
+				BaseURL: "https://sho.rt",
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Accept", tc.accept)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.respError != "" {
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, tc.respError, resp.Error)
+				return
+			}
+
+			require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			require.NotEmpty(t, rr.Header().Get("ETag"))
+
+			if tc.pngSize != 0 {
+				img, err := png.Decode(bytes.NewReader(rr.Body.Bytes()))
+				require.NoError(t, err)
+				require.Equal(t, tc.pngSize, img.Bounds().Dx())
+			}
+		})
+	}
+}
+
+func TestQRCodeNotModified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUrlGetter := mocks.NewMockURLGetter(ctrl)
+	mockUrlGetter.EXPECT().GetLink(gomock.Any(), "", "youtube").Return(storage.Link{Alias: "youtube"}, nil).Times(3)
+
+	r := chi.NewRouter()
+	r.Get("/url/{alias}/qr", New(slogdiscard.NewDiscardLogger(), mockUrlGetter, Options{}))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/youtube/qr", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	etag := rr.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/url/youtube/qr", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Empty(t, rr.Body.Bytes())
+
+	// other parameters produce another image
+	req = httptest.NewRequest(http.MethodGet, "/url/youtube/qr?size=128", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotEqual(t, etag, rr.Header().Get("ETag"))
+}
@@ -5,6 +5,8 @@
 package mocks
 
 import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,16 +36,21 @@ func (m *MockURLSaver) EXPECT() *MockURLSaverMockRecorder {
 }
 
 // SaveURL mocks base method.
-func (m *MockURLSaver) SaveURL(urlToSave, alias string) (int64, error) {
+func (m *MockURLSaver) SaveURL(ctx context.Context, urlToSave, domain, alias string, opts storage.LinkOptions, events ...storage.Event) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveURL", urlToSave, alias)
+	varargs := []interface{}{ctx, urlToSave, domain, alias, opts}
+	for _, a := range events {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SaveURL", varargs...)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveURL indicates an expected call of SaveURL.
-func (mr *MockURLSaverMockRecorder) SaveURL(urlToSave, alias interface{}) *gomock.Call {
+func (mr *MockURLSaverMockRecorder) SaveURL(ctx, urlToSave, domain, alias, opts interface{}, events ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockURLSaver)(nil).SaveURL), urlToSave, alias)
+	varargs := append([]interface{}{ctx, urlToSave, domain, alias, opts}, events...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockURLSaver)(nil).SaveURL), varargs...)
 }
@@ -1,13 +1,22 @@
 package save
 
 import (
+	"context"
 	"errors"
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator"
+	"golang-url-shortener/internal/http-server/handlers/url/options"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/http-server/middleware/logger"
 	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/auditlog"
+	"golang-url-shortener/internal/lib/domains"
 	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/lib/loops"
+	"golang-url-shortener/internal/lib/metrics"
 	"golang-url-shortener/internal/lib/random"
+	"golang-url-shortener/internal/lib/reserved"
+	"golang-url-shortener/internal/screening"
 	"golang-url-shortener/internal/storage"
 	"golang.org/x/exp/slog"
 	"net/http"
@@ -15,27 +24,47 @@ import (
 
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	Alias string `json:"alias,omitempty" validate:"omitempty,notreserved"`
+	// Domain is one of the configured short domains, the primary one when empty.
+	Domain string `json:"domain,omitempty"`
+	options.Options
+}
+
+// LogValue keeps the plain password out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(append([]slog.Attr{
+		slog.String("url", r.URL),
+		slog.String("alias", r.Alias),
+		slog.String("domain", r.Domain),
+	}, r.Options.LogAttrs()...)...)
 }
 
 type Response struct {
 	response.Response
-	Alias string `json:"alias,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	Domain string `json:"domain,omitempty"`
 }
 
 const aliasLength = 6
 
 //go:generate mockgen -source=save.go -destination=mocks/savemock.go -package=savemock
 type URLSaver interface {
-	SaveURL(urlToSave, alias string) (int64, error)
+	SaveURL(ctx context.Context, urlToSave, domain, alias string, opts storage.LinkOptions, events ...storage.Event) (int64, error)
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+// New saves a link, its url and the urls of its options are checked by
+// screener first and by detector for urls leading back to the link. Aliases
+// taken by routes of the service are rejected. The new link is recorded in
+// auditLog, its webhook events are queued along with the link.
+func New(log *slog.Logger, urlSaver URLSaver, registry *domains.Registry, aliases *reserved.Aliases,
+	screener *screening.Screener, detector *loops.Detector, auditLog *auditlog.Log) http.HandlerFunc {
+	validate := aliases.Validator()
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
-		log = log.With(
+		log := logger.ForRequest(log, r).With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("subject", auth.Subject(r.Context())),
 		)
 
 		var req Request
@@ -50,7 +79,7 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 
 		log.Info("request body decoded", slog.Any("request", req))
 
-		if err := validator.New().Struct(req); err != nil {
+		if err := validate.Struct(req); err != nil {
 			validateErr := err.(validator.ValidationErrors)
 
 			log.Error("invalid request", sl.Err(err))
@@ -59,14 +88,66 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 			return
 		}
 
+		urls := append([]string{req.URL}, req.URLs()...)
+
+		if err := screener.Check(urls...); err != nil {
+			log.Info("url rejected", sl.Err(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		domain, err := registry.Lookup(req.Domain)
+		if err != nil {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.JSON(w, r, response.Error("unknown domain"))
+			return
+		}
+
 		alias := req.Alias
 		if alias == "" {
 			alias = random.NewRandomString(aliasLength)
 		}
 
-		id, err := urlSaver.SaveURL(req.URL, alias)
+		var loopErr *loops.Error
+		err = detector.Check(r.Context(), r.Host, domain, []string{alias}, urls)
+		if errors.As(err, &loopErr) {
+			log.Info("redirect loop", sl.Err(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to check for redirect loops", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to add url"))
+			return
+		}
+
+		opts, err := req.LinkOptions()
+		if errors.Is(err, options.ErrInvalid) {
+			log.Info("invalid link options", sl.Err(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to prepare link options", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to add url"))
+			return
+		}
+
+		after := auditlog.Planned(req.URL, domain, alias, opts, nil)
+
+		events, err := auditLog.Events(r, storage.AuditCreate, domain, alias, nil, after)
+		if err != nil {
+			log.Error("failed to prepare webhook events", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to add url"))
+			return
+		}
+
+		id, err := urlSaver.SaveURL(r.Context(), req.URL, domain, alias, opts, events...)
 		if errors.Is(err, storage.ErrUrlExists) {
 			log.Info("url already exists", slog.String("url", req.URL))
+			metrics.AliasCollision(req.Alias == "")
 
 			render.JSON(w, r, response.Error("url already exists"))
 
@@ -81,13 +162,16 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 
 		log.Info("url added", slog.Int64("id", id))
 
-		responseOK(w, r, alias)
+		auditLog.Record(r, storage.AuditCreate, domain, alias, nil, after)
+
+		responseOK(w, r, domain, alias)
 	}
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, alias string) {
+func responseOK(w http.ResponseWriter, r *http.Request, domain, alias string) {
 	render.JSON(w, r, Response{
 		Response: response.OK(),
 		Alias:    alias,
+		Domain:   domain,
 	})
 }
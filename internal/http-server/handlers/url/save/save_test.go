@@ -2,13 +2,19 @@ package save
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"golang-url-shortener/internal/http-server/handlers/url/save/mocks"
+	"golang-url-shortener/internal/lib/domains"
 	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/lib/loops"
+	"golang-url-shortener/internal/lib/password"
+	"golang-url-shortener/internal/lib/reserved"
+	"golang-url-shortener/internal/screening"
 	"golang-url-shortener/internal/storage"
 	"net/http"
 	"net/http/httptest"
@@ -17,11 +23,13 @@ import (
 
 func TestSaveURL(t *testing.T) {
 	tests := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name         string
+		alias        string
+		url          string
+		redirectType int
+		password     string
+		respError    string
+		mockError    error
 	}{
 		{
 			name:  "empty alias",
@@ -49,6 +57,57 @@ func TestSaveURL(t *testing.T) {
 			respError: "field URL is not valid",
 		},
 
+		{
+			name:         "permanent redirect",
+			alias:        "google",
+			url:          "https://www.youtube.com/",
+			redirectType: http.StatusMovedPermanently,
+		},
+
+		{
+			name:         "invalid redirect type",
+			alias:        "google",
+			url:          "https://www.youtube.com/",
+			redirectType: http.StatusOK,
+			respError:    "field RedirectType is not valid",
+		},
+
+		{
+			name:     "password protected",
+			alias:    "docs",
+			url:      "https://intranet.example.com/docs",
+			password: "secret",
+		},
+
+		{
+			name:      "short password",
+			alias:     "docs",
+			url:       "https://intranet.example.com/docs",
+			password:  "abc",
+			respError: "field Password is not valid",
+		},
+
+		{
+			name:      "private network url",
+			alias:     "router",
+			url:       "http://192.168.0.1/admin",
+			respError: "url http://192.168.0.1/admin is not allowed: private network addresses are not allowed",
+		},
+
+		{
+			name:      "self reference",
+			alias:     "google",
+			url:       "https://sho.rt/google",
+			respError: "url https://sho.rt/google is not allowed: it points to the link itself",
+		},
+
+		{
+			name:      "reserved alias",
+			alias:     "openapi",
+			url:       "https://google.com",
+			respError: "field Alias is reserved",
+		},
+
 		{
 			name:      "SaveURL Error",
 			alias:     "test_alias",
@@ -66,6 +125,12 @@ func TestSaveURL(t *testing.T) {
 		},
 	}
 
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
+	// self references are caught before any link is looked up
+	detector := loops.New(domains.New("https://sho.rt", nil), nil, 0)
+
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
@@ -74,13 +139,19 @@ func TestSaveURL(t *testing.T) {
 			mockUrlSaver := mocks.NewMockURLSaver(ctrl)
 
 			if tc.mockError != nil || tc.respError == "" {
-				mockUrlSaver.EXPECT().SaveURL(tc.url, gomock.Any()).Return(int64(1),
-					tc.mockError).Times(1)
+				mockUrlSaver.EXPECT().SaveURL(gomock.Any(), tc.url, "", gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, _, _ string, opts storage.LinkOptions, _ ...storage.Event) (int64, error) {
+						require.Equal(t, tc.redirectType, opts.RedirectType)
+						requirePasswordHash(t, tc.password, opts.PasswordHash)
+
+						return int64(1), tc.mockError
+					}).Times(1)
 			}
 
-			handler := New(slogdiscard.NewDiscardLogger(), mockUrlSaver)
+			handler := New(slogdiscard.NewDiscardLogger(), mockUrlSaver, nil, reserved.New("/openapi"), screener, detector, nil)
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s", "redirect_type": %d, "password": "%s"}`,
+				tc.url, tc.alias, tc.redirectType, tc.password)
 
 			req, err := http.NewRequest(http.MethodPost, "/url/", bytes.NewBuffer([]byte(input)))
 			require.NoError(t, err)
@@ -99,3 +170,109 @@ func TestSaveURL(t *testing.T) {
 		})
 	}
 }
+
+func requirePasswordHash(t *testing.T, plain, hash string) {
+	t.Helper()
+
+	if plain == "" {
+		require.Empty(t, hash)
+		return
+	}
+
+	require.NotEqual(t, plain, hash)
+	require.True(t, password.Verify(hash, plain))
+}
+
+func TestSaveURLDestinations(t *testing.T) {
+	tests := []struct {
+		name         string
+		destinations string
+		respError    string
+	}{
+		{
+			name:         "split",
+			destinations: `[{"name": "a", "url": "https://example.com/a", "weight": 70}, {"name": "b", "url": "https://example.com/b", "weight": 30}]`,
+		},
+		{
+			name:         "single destination",
+			destinations: `[{"name": "a", "url": "https://example.com/a", "weight": 70}]`,
+			respError:    "field Destinations is not valid",
+		},
+		{
+			name:         "duplicate name",
+			destinations: `[{"name": "a", "url": "https://example.com/a", "weight": 1}, {"name": "a", "url": "https://example.com/b", "weight": 1}]`,
+			respError:    "invalid options: destination a is listed twice",
+		},
+		{
+			name:         "no weight",
+			destinations: `[{"name": "a", "url": "https://example.com/a", "weight": 0}, {"name": "b", "url": "https://example.com/b", "weight": 0}]`,
+			respError:    "invalid options: all destinations have weight 0",
+		},
+		{
+			name:         "rejected scheme",
+			destinations: `[{"name": "a", "url": "https://example.com/a", "weight": 1}, {"name": "b", "url": "ftp://example.com/b", "weight": 1}]`,
+			respError:    `url ftp://example.com/b is not allowed: scheme "ftp" is not allowed`,
+		},
+	}
+
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUrlSaver := mocks.NewMockURLSaver(ctrl)
+
+			if tc.respError == "" {
+				mockUrlSaver.EXPECT().SaveURL(gomock.Any(), "https://example.com", "", "landing", gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, _, _ string, opts storage.LinkOptions, _ ...storage.Event) (int64, error) {
+						require.Len(t, opts.Destinations, 2)
+						require.True(t, opts.StickySplit)
+
+						return int64(1), nil
+					}).Times(1)
+			}
+
+			handler := New(slogdiscard.NewDiscardLogger(), mockUrlSaver, nil, nil, screener, nil, nil)
+
+			input := fmt.Sprintf(`{"url": "https://example.com", "alias": "landing", "sticky_split": true, "destinations": %s}`,
+				tc.destinations)
+
+			req, err := http.NewRequest(http.MethodPost, "/url/", bytes.NewBuffer([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveURLFallbackScreened(t *testing.T) {
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := New(slogdiscard.NewDiscardLogger(), mocks.NewMockURLSaver(ctrl), nil, nil, screener, nil, nil)
+
+	input := `{"url": "https://example.com", "alias": "launch", "active_from": "2030-01-01T00:00:00Z", "fallback_url": "http://10.0.0.1/admin"}`
+
+	req, err := http.NewRequest(http.MethodPost, "/url/", bytes.NewBuffer([]byte(input)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	require.Equal(t, "url http://10.0.0.1/admin is not allowed: private network addresses are not allowed", resp.Error)
+}
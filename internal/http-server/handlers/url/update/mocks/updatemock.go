@@ -5,6 +5,8 @@
 package updatemock
 
 import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,15 +36,20 @@ func (m *MockURLUpdater) EXPECT() *MockURLUpdaterMockRecorder {
 }
 
 // UpdateURL mocks base method.
-func (m *MockURLUpdater) UpdateURL(urlToUpdate, oldAlias, newAlias string) error {
+func (m *MockURLUpdater) UpdateURL(ctx context.Context, urlToUpdate, domain, oldAlias, newAlias string, opts storage.LinkOptions, events ...storage.Event) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateURL", urlToUpdate, oldAlias, newAlias)
+	varargs := []interface{}{ctx, urlToUpdate, domain, oldAlias, newAlias, opts}
+	for _, a := range events {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateURL", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateURL indicates an expected call of UpdateURL.
-func (mr *MockURLUpdaterMockRecorder) UpdateURL(urlToUpdate, oldAlias, newAlias interface{}) *gomock.Call {
+func (mr *MockURLUpdaterMockRecorder) UpdateURL(ctx, urlToUpdate, domain, oldAlias, newAlias, opts interface{}, events ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockURLUpdater)(nil).UpdateURL), urlToUpdate, oldAlias, newAlias)
+	varargs := append([]interface{}{ctx, urlToUpdate, domain, oldAlias, newAlias, opts}, events...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockURLUpdater)(nil).UpdateURL), varargs...)
 }
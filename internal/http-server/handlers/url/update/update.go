@@ -1,12 +1,20 @@
 package update
 
 import (
+	"context"
 	"errors"
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator"
+	"golang-url-shortener/internal/http-server/handlers/url/options"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/http-server/middleware/logger"
 	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/auditlog"
+	"golang-url-shortener/internal/lib/domains"
 	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/lib/loops"
+	"golang-url-shortener/internal/lib/reserved"
+	"golang-url-shortener/internal/screening"
 	"golang-url-shortener/internal/storage"
 	"golang.org/x/exp/slog"
 	"net/http"
@@ -15,25 +23,55 @@ import (
 type Request struct {
 	URL      string `json:"url" validate:"required,url"`
 	OldAlias string `json:"old_alias" validate:"required"`
-	NewAlias string `json:"new_alias" validate:"required"`
+	NewAlias string `json:"new_alias" validate:"required,notreserved"`
+	// Domain the link belongs to, links can't be moved between domains.
+	Domain string `json:"domain,omitempty"`
+	// RemovePassword drops the password of the link, an omitted password keeps it.
+	RemovePassword bool `json:"remove_password,omitempty"`
+	// RemoveActiveFrom and RemoveActiveUntil drop a bound of the activation
+	// window, an omitted bound keeps it.
+	RemoveActiveFrom  bool `json:"remove_active_from,omitempty"`
+	RemoveActiveUntil bool `json:"remove_active_until,omitempty"`
+	options.Options
+}
+
+// LogValue keeps the plain password out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(append([]slog.Attr{
+		slog.String("url", r.URL),
+		slog.String("old_alias", r.OldAlias),
+		slog.String("new_alias", r.NewAlias),
+		slog.String("domain", r.Domain),
+		slog.Bool("remove_password", r.RemovePassword),
+		slog.Bool("remove_active_from", r.RemoveActiveFrom),
+		slog.Bool("remove_active_until", r.RemoveActiveUntil),
+	}, r.Options.LogAttrs()...)...)
 }
 
 type Response struct {
 	response.Response
-	Alias string `json:"alias,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	Domain string `json:"domain,omitempty"`
 }
 
 //go:generate mockgen -source=update.go -destination=mocks/updatemock.go -package=updatemock
 type URLUpdater interface {
-	UpdateURL(urlToUpdate, oldAlias, newAlias string) error
+	UpdateURL(ctx context.Context, urlToUpdate, domain, oldAlias, newAlias string, opts storage.LinkOptions, events ...storage.Event) error
 }
 
-func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
+// New updates a link, its url and the urls of its options are checked by
+// screener first and by detector for urls leading back to the link. Aliases
+// taken by routes of the service are rejected. The change is recorded in
+// auditLog, its webhook events are queued along with the change.
+func New(log *slog.Logger, urlUpdater URLUpdater, registry *domains.Registry, aliases *reserved.Aliases,
+	screener *screening.Screener, detector *loops.Detector, auditLog *auditlog.Log) http.HandlerFunc {
+	validate := aliases.Validator()
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.update.New"
-		log = log.With(
+		log := logger.ForRequest(log, r).With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("subject", auth.Subject(r.Context())),
 		)
 
 		var req Request
@@ -48,7 +86,7 @@ func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
 
 		log.Info("request body decoded", slog.Any("request", req))
 
-		if err := validator.New().Struct(req); err != nil {
+		if err := validate.Struct(req); err != nil {
 			validateErr := err.(validator.ValidationErrors)
 
 			log.Error("invalid request", sl.Err(err))
@@ -57,7 +95,80 @@ func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
 			return
 		}
 
-		err = urlUpdater.UpdateURL(req.URL, req.OldAlias, req.NewAlias)
+		if req.RemovePassword && req.Password != "" {
+			log.Info("password and remove_password are both set")
+			render.JSON(w, r, response.Error("password and remove_password can't be combined"))
+			return
+		}
+
+		if req.RemoveActiveFrom && req.ActiveFrom != nil {
+			log.Info("active_from and remove_active_from are both set")
+			render.JSON(w, r, response.Error("active_from and remove_active_from can't be combined"))
+			return
+		}
+
+		if req.RemoveActiveUntil && req.ActiveUntil != nil {
+			log.Info("active_until and remove_active_until are both set")
+			render.JSON(w, r, response.Error("active_until and remove_active_until can't be combined"))
+			return
+		}
+
+		urls := append([]string{req.URL}, req.URLs()...)
+
+		if err := screener.Check(urls...); err != nil {
+			log.Info("url rejected", sl.Err(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		domain, err := registry.Lookup(req.Domain)
+		if err != nil {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.JSON(w, r, response.Error("unknown domain"))
+			return
+		}
+
+		var loopErr *loops.Error
+		err = detector.Check(r.Context(), r.Host, domain, []string{req.OldAlias, req.NewAlias}, urls)
+		if errors.As(err, &loopErr) {
+			log.Info("redirect loop", sl.Err(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to check for redirect loops", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to update url"))
+			return
+		}
+
+		opts, err := req.LinkOptions()
+		if errors.Is(err, options.ErrInvalid) {
+			log.Info("invalid link options", sl.Err(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to prepare link options", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to update url"))
+			return
+		}
+		opts.RemovePassword = req.RemovePassword
+		opts.Keep.ActiveFrom = opts.Keep.ActiveFrom && !req.RemoveActiveFrom
+		opts.Keep.ActiveUntil = opts.Keep.ActiveUntil && !req.RemoveActiveUntil
+
+		before := auditLog.Snapshot(r.Context(), domain, req.OldAlias)
+		after := auditlog.Planned(req.URL, domain, req.NewAlias, opts, before)
+
+		events, err := auditLog.Events(r, storage.AuditUpdate, domain, req.NewAlias, before, after)
+		if err != nil {
+			log.Error("failed to prepare webhook events", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to update url"))
+			return
+		}
+
+		err = urlUpdater.UpdateURL(r.Context(), req.URL, domain, req.OldAlias, req.NewAlias, opts, events...)
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Info(
 				"url with this alias not found",
@@ -69,6 +180,14 @@ func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
 			return
 		}
 
+		if errors.Is(err, storage.ErrUrlExists) {
+			log.Info("alias already exists", slog.String("alias", req.NewAlias))
+
+			render.JSON(w, r, response.Error("alias already exists"))
+
+			return
+		}
+
 		if err != nil {
 			log.Error("failed to update url", sl.Err(err))
 			render.JSON(w, r, response.Error("failed to update url"))
@@ -77,13 +196,16 @@ func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
 
 		log.Info("url updated", slog.String("url", req.URL), slog.String("alias", req.NewAlias))
 
-		responseOK(w, r, req.NewAlias)
+		auditLog.Record(r, storage.AuditUpdate, domain, req.NewAlias, before, after)
+
+		responseOK(w, r, domain, req.NewAlias)
 	}
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, alias string) {
+func responseOK(w http.ResponseWriter, r *http.Request, domain, alias string) {
 	render.JSON(w, r, Response{
 		Response: response.OK(),
 		Alias:    alias,
+		Domain:   domain,
 	})
 }
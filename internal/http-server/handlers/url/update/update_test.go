@@ -2,6 +2,7 @@ package update
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang-url-shortener/internal/http-server/handlers/url/update/mocks"
 	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/lib/password"
+	"golang-url-shortener/internal/lib/reserved"
+	"golang-url-shortener/internal/screening"
 	"golang-url-shortener/internal/storage"
 	"net/http"
 	"net/http/httptest"
@@ -16,13 +20,22 @@ import (
 )
 
 func TestUpdateURL(t *testing.T) {
+	limit, unlimited := 5, 0
+	temporary, invalid, byDefault := http.StatusTemporaryRedirect, 399, 0
+
 	tests := []struct {
-		name      string
-		url       string
-		oldAlias  string
-		newAlias  string
-		respError string
-		mockError error
+		name           string
+		url            string
+		oldAlias       string
+		newAlias       string
+		redirectType   *int
+		password       string
+		removePassword bool
+		maxClicks      *int
+		activeFrom     string
+		removeFrom     bool
+		respError      string
+		mockError      error
 	}{
 		{
 			name:      "empty old_alias",
@@ -61,6 +74,122 @@ func TestUpdateURL(t *testing.T) {
 			respError: "field URL is not valid",
 		},
 
+		{
+			name:         "change redirect type",
+			oldAlias:     "old_google",
+			newAlias:     "old_google",
+			url:          "https://www.youtube.com/",
+			redirectType: &temporary,
+		},
+
+		{
+			name:         "reset redirect type",
+			oldAlias:     "old_google",
+			newAlias:     "old_google",
+			url:          "https://www.youtube.com/",
+			redirectType: &byDefault,
+		},
+
+		{
+			name:         "invalid redirect type",
+			oldAlias:     "old_google",
+			newAlias:     "new_google",
+			url:          "https://www.youtube.com/",
+			redirectType: &invalid,
+			respError:    "field RedirectType is not valid",
+		},
+
+		{
+			name:     "change password",
+			oldAlias: "old_google",
+			newAlias: "old_google",
+			url:      "https://www.youtube.com/",
+			password: "new-secret",
+		},
+
+		{
+			name:           "remove password",
+			oldAlias:       "old_google",
+			newAlias:       "old_google",
+			url:            "https://www.youtube.com/",
+			removePassword: true,
+		},
+
+		{
+			name:     "rename keeps click limit",
+			oldAlias: "old_google",
+			newAlias: "new_google",
+			url:      "https://www.youtube.com/",
+		},
+
+		{
+			name:      "change click limit",
+			oldAlias:  "old_google",
+			newAlias:  "old_google",
+			url:       "https://www.youtube.com/",
+			maxClicks: &limit,
+		},
+
+		{
+			name:      "remove click limit",
+			oldAlias:  "old_google",
+			newAlias:  "old_google",
+			url:       "https://www.youtube.com/",
+			maxClicks: &unlimited,
+		},
+
+		{
+			name:       "change active_from",
+			oldAlias:   "old_google",
+			newAlias:   "old_google",
+			url:        "https://www.youtube.com/",
+			activeFrom: "2030-01-01T00:00:00Z",
+		},
+
+		{
+			name:       "remove active_from",
+			oldAlias:   "old_google",
+			newAlias:   "old_google",
+			url:        "https://www.youtube.com/",
+			removeFrom: true,
+		},
+
+		{
+			name:       "remove and change active_from",
+			oldAlias:   "old_google",
+			newAlias:   "old_google",
+			url:        "https://www.youtube.com/",
+			activeFrom: "2030-01-01T00:00:00Z",
+			removeFrom: true,
+			respError:  "active_from and remove_active_from can't be combined",
+		},
+
+		{
+			name:           "remove and change password",
+			oldAlias:       "old_google",
+			newAlias:       "old_google",
+			url:            "https://www.youtube.com/",
+			password:       "new-secret",
+			removePassword: true,
+			respError:      "password and remove_password can't be combined",
+		},
+
+		{
+			name:      "reserved new_alias",
+			oldAlias:  "old_google",
+			newAlias:  "openapi.json",
+			url:       "https://www.youtube.com/",
+			respError: "field NewAlias is reserved",
+		},
+
+		{
+			name:      "localhost url",
+			oldAlias:  "old_google",
+			newAlias:  "new_google",
+			url:       "http://localhost:8080/admin",
+			respError: "url http://localhost:8080/admin is not allowed: private network addresses are not allowed",
+		},
+
 		{
 			name:      "UpdateURL Error",
 			oldAlias:  "old_alias",
@@ -78,8 +207,20 @@ func TestUpdateURL(t *testing.T) {
 			respError: "url with this alias not found",
 			mockError: storage.ErrUrlNotFound,
 		},
+
+		{
+			name:      "alias taken",
+			oldAlias:  "old_google",
+			newAlias:  "taken",
+			url:       "https://google.com",
+			respError: "alias already exists",
+			mockError: storage.ErrUrlExists,
+		},
 	}
 
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
@@ -88,12 +229,43 @@ func TestUpdateURL(t *testing.T) {
 			mockUrlUpdater := updatemock.NewMockURLUpdater(ctrl)
 
 			if tc.mockError != nil || tc.respError == "" {
-				mockUrlUpdater.EXPECT().UpdateURL(tc.url, tc.oldAlias, tc.newAlias).Return(tc.mockError).Times(1)
+				mockUrlUpdater.EXPECT().UpdateURL(gomock.Any(), tc.url, "", tc.oldAlias, tc.newAlias, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, _, _, _ string, opts storage.LinkOptions, _ ...storage.Event) error {
+						require.Equal(t, tc.redirectType == nil, opts.Keep.RedirectType)
+						if tc.redirectType != nil {
+							require.Equal(t, *tc.redirectType, opts.RedirectType)
+						}
+						requirePasswordHash(t, tc.password, opts.PasswordHash)
+						require.Equal(t, tc.removePassword, opts.RemovePassword)
+						require.Equal(t, tc.maxClicks == nil, opts.Keep.MaxClicks)
+						if tc.maxClicks != nil {
+							require.Equal(t, *tc.maxClicks, opts.MaxClicks)
+						}
+						require.Equal(t, tc.activeFrom == "" && !tc.removeFrom, opts.Keep.ActiveFrom)
+						require.Equal(t, tc.activeFrom == "", opts.ActiveFrom == nil)
+						require.True(t, opts.Keep.ActiveUntil)
+
+						return tc.mockError
+					}).Times(1)
 			}
 
-			handler := New(slogdiscard.NewDiscardLogger(), mockUrlUpdater)
+			handler := New(slogdiscard.NewDiscardLogger(), mockUrlUpdater, nil, reserved.New("/openapi"), screener, nil, nil)
 
-			input := fmt.Sprintf(`{"url": "%s", "old_alias": "%s", "new_alias": "%s"}`, tc.url, tc.oldAlias, tc.newAlias)
+			redirectType := ""
+			if tc.redirectType != nil {
+				redirectType = fmt.Sprintf(`, "redirect_type": %d`, *tc.redirectType)
+			}
+			maxClicks := ""
+			if tc.maxClicks != nil {
+				maxClicks = fmt.Sprintf(`, "max_clicks": %d`, *tc.maxClicks)
+			}
+			activeFrom := ""
+			if tc.activeFrom != "" {
+				activeFrom = fmt.Sprintf(`, "active_from": "%s"`, tc.activeFrom)
+			}
+
+			input := fmt.Sprintf(`{"url": "%s", "old_alias": "%s", "new_alias": "%s", "password": "%s", "remove_password": %t, "remove_active_from": %t%s%s%s}`,
+				tc.url, tc.oldAlias, tc.newAlias, tc.password, tc.removePassword, tc.removeFrom, redirectType, maxClicks, activeFrom)
 
 			req, err := http.NewRequest(http.MethodPut, "/url/", bytes.NewBuffer([]byte(input)))
 			require.NoError(t, err)
@@ -112,3 +284,15 @@ func TestUpdateURL(t *testing.T) {
 		})
 	}
 }
+
+func requirePasswordHash(t *testing.T, plain, hash string) {
+	t.Helper()
+
+	if plain == "" {
+		require.Empty(t, hash)
+		return
+	}
+
+	require.NotEqual(t, plain, hash)
+	require.True(t, password.Verify(hash, plain))
+}
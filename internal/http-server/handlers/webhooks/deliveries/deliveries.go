@@ -0,0 +1,124 @@
+package deliveries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+type Delivery struct {
+	ID             int64           `json:"id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+type Response struct {
+	response.Response
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+//go:generate mockgen -source=deliveries.go -destination=mocks/deliveriesmock.go -package=mocks
+type DeliveryLister interface {
+	Deliveries(ctx context.Context, webhookID int64, status string, limit int) ([]storage.Delivery, error)
+}
+
+// New lists the deliveries of the webhook with the id newest first, the
+// status query parameter selects pending, delivered or dead ones.
+func New(log *slog.Logger, deliveryLister DeliveryLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.deliveries.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Info("invalid webhook id", slog.String("id", chi.URLParam(r, "id")))
+			render.JSON(w, r, response.Error("invalid request"))
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "", storage.DeliveryPending, storage.DeliveryDelivered, storage.DeliveryDead:
+		default:
+			log.Info("invalid status", slog.String("status", status))
+			render.JSON(w, r, response.Error("invalid status"))
+			return
+		}
+
+		limit := defaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit <= 0 || limit > maxLimit {
+				log.Info("invalid limit", slog.String("limit", v))
+				render.JSON(w, r, response.Error("invalid limit, expected 1 to 500"))
+				return
+			}
+		}
+
+		list, err := deliveryLister.Deliveries(r.Context(), id, status, limit)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			log.Info("webhook not found", slog.Int64("id", id))
+			render.JSON(w, r, response.Error("webhook not found"))
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to list deliveries", sl.Err(err))
+			render.JSON(w, r, response.Error("internal error"))
+			return
+		}
+
+		deliveries := make([]Delivery, 0, len(list))
+		for _, d := range list {
+			delivery := Delivery{
+				ID:             d.ID,
+				EventID:        d.EventID,
+				Event:          d.Event,
+				Status:         d.Status,
+				Attempts:       d.Attempts,
+				LastStatusCode: d.LastStatusCode,
+				LastError:      d.LastError,
+				CreatedAt:      d.CreatedAt,
+				DeliveredAt:    d.DeliveredAt,
+				Payload:        json.RawMessage(d.Payload),
+			}
+			if d.Status == storage.DeliveryPending {
+				nextAttemptAt := d.NextAttemptAt
+				delivery.NextAttemptAt = &nextAttemptAt
+			}
+
+			deliveries = append(deliveries, delivery)
+		}
+
+		render.JSON(w, r, Response{
+			Response:   response.OK(),
+			Deliveries: deliveries,
+		})
+	}
+}
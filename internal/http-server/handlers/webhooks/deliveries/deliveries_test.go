@@ -0,0 +1,120 @@
+package deliveries
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/deliveries/mocks"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeliveries(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	next := created.Add(time.Minute)
+
+	list := []storage.Delivery{
+		{ID: 2, WebhookID: 3, EventID: "evt_b", Event: "link.clicked", Payload: `{"event":"link.clicked"}`,
+			Status: storage.DeliveryPending, Attempts: 1, NextAttemptAt: next, LastStatusCode: 503, CreatedAt: created},
+		{ID: 1, WebhookID: 3, EventID: "evt_a", Event: "link.created", Payload: `{"event":"link.created"}`,
+			Status: storage.DeliveryDelivered, Attempts: 1, NextAttemptAt: created, LastStatusCode: 200, CreatedAt: created, DeliveredAt: &created},
+	}
+
+	tests := []struct {
+		name      string
+		url       string
+		status    string
+		limit     int
+		mockError error
+		mockCall  bool
+		respError string
+	}{
+		{
+			name:     "success",
+			url:      "/admin/webhooks/3/deliveries",
+			limit:    defaultLimit,
+			mockCall: true,
+		},
+		{
+			name:     "status and limit",
+			url:      "/admin/webhooks/3/deliveries?status=pending&limit=10",
+			status:   storage.DeliveryPending,
+			limit:    10,
+			mockCall: true,
+		},
+		{
+			name:      "invalid status",
+			url:       "/admin/webhooks/3/deliveries?status=failed",
+			respError: "invalid status",
+		},
+		{
+			name:      "invalid limit",
+			url:       "/admin/webhooks/3/deliveries?limit=501",
+			respError: "invalid limit, expected 1 to 500",
+		},
+		{
+			name:      "invalid id",
+			url:       "/admin/webhooks/abc/deliveries",
+			respError: "invalid request",
+		},
+		{
+			name:      "not found",
+			url:       "/admin/webhooks/3/deliveries",
+			limit:     defaultLimit,
+			mockError: storage.ErrWebhookNotFound,
+			mockCall:  true,
+			respError: "webhook not found",
+		},
+		{
+			name:      "error with db",
+			url:       "/admin/webhooks/3/deliveries",
+			limit:     defaultLimit,
+			mockError: errors.New("another error"),
+			mockCall:  true,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockDeliveryLister := mocks.NewMockDeliveryLister(ctrl)
+			if tc.mockCall {
+				var result []storage.Delivery
+				if tc.mockError == nil {
+					result = list
+				}
+				mockDeliveryLister.EXPECT().Deliveries(gomock.Any(), int64(3), tc.status, tc.limit).Return(result, tc.mockError).Times(1)
+			}
+
+			router := chi.NewRouter()
+			router.Get("/admin/webhooks/{id}/deliveries", New(slogdiscard.NewDiscardLogger(), mockDeliveryLister))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				return
+			}
+
+			require.Len(t, resp.Deliveries, 2)
+			require.Equal(t, next, *resp.Deliveries[0].NextAttemptAt)
+			require.JSONEq(t, `{"event":"link.clicked"}`, string(resp.Deliveries[0].Payload))
+			require.Nil(t, resp.Deliveries[1].NextAttemptAt)
+			require.Equal(t, created, *resp.Deliveries[1].DeliveredAt)
+		})
+	}
+}
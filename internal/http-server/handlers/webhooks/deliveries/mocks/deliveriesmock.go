@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: deliveries.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockDeliveryLister is a mock of DeliveryLister interface.
+type MockDeliveryLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeliveryListerMockRecorder
+}
+
+// MockDeliveryListerMockRecorder is the mock recorder for MockDeliveryLister.
+type MockDeliveryListerMockRecorder struct {
+	mock *MockDeliveryLister
+}
+
+// NewMockDeliveryLister creates a new mock instance.
+func NewMockDeliveryLister(ctrl *gomock.Controller) *MockDeliveryLister {
+	mock := &MockDeliveryLister{ctrl: ctrl}
+	mock.recorder = &MockDeliveryListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeliveryLister) EXPECT() *MockDeliveryListerMockRecorder {
+	return m.recorder
+}
+
+// Deliveries mocks base method.
+func (m *MockDeliveryLister) Deliveries(ctx context.Context, webhookID int64, status string, limit int) ([]storage.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deliveries", ctx, webhookID, status, limit)
+	ret0, _ := ret[0].([]storage.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Deliveries indicates an expected call of Deliveries.
+func (mr *MockDeliveryListerMockRecorder) Deliveries(ctx, webhookID, status, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deliveries", reflect.TypeOf((*MockDeliveryLister)(nil).Deliveries), ctx, webhookID, status, limit)
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: subscribe.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockWebhookCreator is a mock of WebhookCreator interface.
+type MockWebhookCreator struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookCreatorMockRecorder
+}
+
+// MockWebhookCreatorMockRecorder is the mock recorder for MockWebhookCreator.
+type MockWebhookCreatorMockRecorder struct {
+	mock *MockWebhookCreator
+}
+
+// NewMockWebhookCreator creates a new mock instance.
+func NewMockWebhookCreator(ctrl *gomock.Controller) *MockWebhookCreator {
+	mock := &MockWebhookCreator{ctrl: ctrl}
+	mock.recorder = &MockWebhookCreatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookCreator) EXPECT() *MockWebhookCreatorMockRecorder {
+	return m.recorder
+}
+
+// CreateWebhook mocks base method.
+func (m *MockWebhookCreator) CreateWebhook(ctx context.Context, hook storage.Webhook) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", ctx, hook)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockWebhookCreatorMockRecorder) CreateWebhook(ctx, hook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockWebhookCreator)(nil).CreateWebhook), ctx, hook)
+}
@@ -0,0 +1,100 @@
+package subscribe
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/screening"
+	"golang-url-shortener/internal/storage"
+	"golang-url-shortener/internal/webhooks"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
+)
+
+type Request struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=link.created link.updated link.deleted link.expired link.clicked"`
+	// Secret signs the payloads, a random one is generated when empty.
+	Secret string `json:"secret,omitempty" validate:"omitempty,min=16,max=128"`
+}
+
+type Response struct {
+	response.Response
+	ID     int64  `json:"id,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+//go:generate mockgen -source=subscribe.go -destination=mocks/subscribemock.go -package=mocks
+type WebhookCreator interface {
+	CreateWebhook(ctx context.Context, hook storage.Webhook) (int64, error)
+}
+
+// New subscribes an url to events, the url is checked by screener like the
+// urls of links.
+func New(log *slog.Logger, webhookCreator WebhookCreator, screener *screening.Screener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.subscribe.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to decode request body"))
+			return
+		}
+
+		log.Info("request body decoded", slog.String("url", req.URL), slog.Any("events", req.Events))
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+			render.JSON(w, r, response.ValidationError(validateErr))
+			return
+		}
+
+		if err := screener.Check(req.URL); err != nil {
+			log.Info("url rejected", sl.Err(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		secret := req.Secret
+		if secret == "" {
+			var err error
+			if secret, err = webhooks.NewSecret(); err != nil {
+				log.Error("failed to generate secret", sl.Err(err))
+				render.JSON(w, r, response.Error("failed to create webhook"))
+				return
+			}
+		}
+
+		id, err := webhookCreator.CreateWebhook(r.Context(), storage.Webhook{
+			URL:       req.URL,
+			Secret:    secret,
+			Events:    req.Events,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Error("failed to create webhook", sl.Err(err))
+			render.JSON(w, r, response.Error("failed to create webhook"))
+			return
+		}
+
+		log.Info("webhook created", slog.Int64("id", id))
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			ID:       id,
+			Secret:   secret,
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package subscribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/subscribe/mocks"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/screening"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		input     string
+		secret    string
+		respError string
+		mockError error
+	}{
+		{
+			name:  "success",
+			input: `{"url": "https://hooks.example.com/links", "events": ["link.created", "link.clicked"]}`,
+		},
+		{
+			name:   "with secret",
+			input:  `{"url": "https://hooks.example.com/links", "events": ["link.deleted"], "secret": "0123456789abcdef"}`,
+			secret: "0123456789abcdef",
+		},
+		{
+			name:      "short secret",
+			input:     `{"url": "https://hooks.example.com/links", "events": ["link.deleted"], "secret": "short"}`,
+			respError: "field Secret is not valid",
+		},
+		{
+			name:      "unknown event",
+			input:     `{"url": "https://hooks.example.com/links", "events": ["link.viewed"]}`,
+			respError: "field Events[0] is not valid",
+		},
+		{
+			name:      "no events",
+			input:     `{"url": "https://hooks.example.com/links", "events": []}`,
+			respError: "field Events is not valid",
+		},
+		{
+			name:      "private url",
+			input:     `{"url": "http://127.0.0.1/links", "events": ["link.created"]}`,
+			respError: "url http://127.0.0.1/links is not allowed: private network addresses are not allowed",
+		},
+		{
+			name:      "CreateWebhook error",
+			input:     `{"url": "https://hooks.example.com/links", "events": ["link.created"]}`,
+			respError: "failed to create webhook",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			var stored storage.Webhook
+			mockWebhookCreator := mocks.NewMockWebhookCreator(ctrl)
+			if tc.mockError != nil || tc.respError == "" {
+				mockWebhookCreator.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, hook storage.Webhook) (int64, error) {
+						stored = hook
+						return int64(4), tc.mockError
+					}).Times(1)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewBufferString(tc.input))
+
+			rr := httptest.NewRecorder()
+			New(slogdiscard.NewDiscardLogger(), mockWebhookCreator, screener).ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			if tc.respError != "" {
+				require.Equal(t, tc.respError, resp.Error)
+				require.Empty(t, resp.Secret)
+				return
+			}
+
+			require.Empty(t, resp.Error)
+			require.Equal(t, int64(4), resp.ID)
+			require.Equal(t, stored.Secret, resp.Secret)
+			require.Equal(t, "https://hooks.example.com/links", stored.URL)
+			if tc.secret != "" {
+				require.Equal(t, tc.secret, resp.Secret)
+			} else {
+				require.True(t, strings.HasPrefix(resp.Secret, "whsec_"))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: subscriptions.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "golang-url-shortener/internal/storage"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockWebhookLister is a mock of WebhookLister interface.
+type MockWebhookLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookListerMockRecorder
+}
+
+// MockWebhookListerMockRecorder is the mock recorder for MockWebhookLister.
+type MockWebhookListerMockRecorder struct {
+	mock *MockWebhookLister
+}
+
+// NewMockWebhookLister creates a new mock instance.
+func NewMockWebhookLister(ctrl *gomock.Controller) *MockWebhookLister {
+	mock := &MockWebhookLister{ctrl: ctrl}
+	mock.recorder = &MockWebhookListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookLister) EXPECT() *MockWebhookListerMockRecorder {
+	return m.recorder
+}
+
+// ListWebhooks mocks base method.
+func (m *MockWebhookLister) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx)
+	ret0, _ := ret[0].([]storage.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockWebhookListerMockRecorder) ListWebhooks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockWebhookLister)(nil).ListWebhooks), ctx)
+}
@@ -0,0 +1,63 @@
+package subscriptions
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
+)
+
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Response struct {
+	response.Response
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+//go:generate mockgen -source=subscriptions.go -destination=mocks/subscriptionsmock.go -package=mocks
+type WebhookLister interface {
+	ListWebhooks(ctx context.Context) ([]storage.Webhook, error)
+}
+
+// New lists the webhooks, without their secrets.
+func New(log *slog.Logger, webhookLister WebhookLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.subscriptions.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		hooks, err := webhookLister.ListWebhooks(r.Context())
+		if err != nil {
+			log.Error("failed to list webhooks", sl.Err(err))
+			render.JSON(w, r, response.Error("internal error"))
+			return
+		}
+
+		webhooks := make([]Webhook, 0, len(hooks))
+		for _, hook := range hooks {
+			webhooks = append(webhooks, Webhook{
+				ID:        hook.ID,
+				URL:       hook.URL,
+				Events:    hook.Events,
+				CreatedAt: hook.CreatedAt,
+			})
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Webhooks: webhooks,
+		})
+	}
+}
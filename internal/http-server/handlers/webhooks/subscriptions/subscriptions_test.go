@@ -0,0 +1,69 @@
+package subscriptions
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/subscriptions/mocks"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubscriptions(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		webhooks  []storage.Webhook
+		mockError error
+		respError string
+		expected  []Webhook
+	}{
+		{
+			name:     "no webhooks",
+			expected: []Webhook{},
+		},
+		{
+			name: "webhooks",
+			webhooks: []storage.Webhook{
+				{ID: 1, URL: "https://hooks.example.com/a", Secret: "whsec_a", Events: []string{"link.created"}, CreatedAt: created},
+				{ID: 2, URL: "https://hooks.example.com/b", Secret: "whsec_b", Events: []string{"link.clicked", "link.expired"}, CreatedAt: created},
+			},
+			expected: []Webhook{
+				{ID: 1, URL: "https://hooks.example.com/a", Events: []string{"link.created"}, CreatedAt: created},
+				{ID: 2, URL: "https://hooks.example.com/b", Events: []string{"link.clicked", "link.expired"}, CreatedAt: created},
+			},
+		},
+		{
+			name:      "error with db",
+			mockError: errors.New("another error"),
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockWebhookLister := mocks.NewMockWebhookLister(ctrl)
+			mockWebhookLister.EXPECT().ListWebhooks(gomock.Any()).Return(tc.webhooks, tc.mockError).Times(1)
+
+			rr := httptest.NewRecorder()
+			New(slogdiscard.NewDiscardLogger(), mockWebhookLister).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.NotContains(t, rr.Body.String(), "whsec_")
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.expected, resp.Webhooks)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: unsubscribe.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockWebhookDeleter is a mock of WebhookDeleter interface.
+type MockWebhookDeleter struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookDeleterMockRecorder
+}
+
+// MockWebhookDeleterMockRecorder is the mock recorder for MockWebhookDeleter.
+type MockWebhookDeleterMockRecorder struct {
+	mock *MockWebhookDeleter
+}
+
+// NewMockWebhookDeleter creates a new mock instance.
+func NewMockWebhookDeleter(ctrl *gomock.Controller) *MockWebhookDeleter {
+	mock := &MockWebhookDeleter{ctrl: ctrl}
+	mock.recorder = &MockWebhookDeleterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookDeleter) EXPECT() *MockWebhookDeleterMockRecorder {
+	return m.recorder
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockWebhookDeleter) DeleteWebhook(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockWebhookDeleterMockRecorder) DeleteWebhook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockWebhookDeleter)(nil).DeleteWebhook), ctx, id)
+}
@@ -0,0 +1,55 @@
+package unsubscribe
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strconv"
+)
+
+//go:generate mockgen -source=unsubscribe.go -destination=mocks/unsubscribemock.go -package=mocks
+type WebhookDeleter interface {
+	DeleteWebhook(ctx context.Context, id int64) error
+}
+
+// New deletes the webhook with the id, its pending deliveries are dropped.
+func New(log *slog.Logger, webhookDeleter WebhookDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.unsubscribe.New"
+
+		log := logger.ForRequest(log, r).With(
+			slog.String("op", op),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Info("invalid webhook id", slog.String("id", chi.URLParam(r, "id")))
+			render.JSON(w, r, response.Error("invalid request"))
+			return
+		}
+
+		err = webhookDeleter.DeleteWebhook(r.Context(), id)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			log.Info("webhook not found", slog.Int64("id", id))
+			render.JSON(w, r, response.Error("webhook not found"))
+			return
+		}
+
+		if err != nil {
+			log.Error("failed to delete webhook", sl.Err(err))
+			render.JSON(w, r, response.Error("internal error"))
+			return
+		}
+
+		log.Info("webhook deleted", slog.Int64("id", id))
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,73 @@
+package unsubscribe
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/unsubscribe/mocks"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnsubscribe(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		respError string
+		mockError error
+		mockCall  bool
+	}{
+		{
+			name:     "success",
+			id:       "3",
+			mockCall: true,
+		},
+		{
+			name:      "not found",
+			id:        "3",
+			respError: "webhook not found",
+			mockError: storage.ErrWebhookNotFound,
+			mockCall:  true,
+		},
+		{
+			name:      "error with db",
+			id:        "3",
+			respError: "internal error",
+			mockError: errors.New("another error"),
+			mockCall:  true,
+		},
+		{
+			name:      "invalid id",
+			id:        "abc",
+			respError: "invalid request",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockWebhookDeleter := mocks.NewMockWebhookDeleter(ctrl)
+			if tc.mockCall {
+				mockWebhookDeleter.EXPECT().DeleteWebhook(gomock.Any(), int64(3)).Return(tc.mockError).Times(1)
+			}
+
+			router := chi.NewRouter()
+			router.Delete("/admin/webhooks/{id}", New(slogdiscard.NewDiscardLogger(), mockWebhookDeleter))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin/webhooks/"+tc.id, nil))
+
+			var resp response.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
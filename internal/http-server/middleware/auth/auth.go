@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/apikey"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// touchInterval limits how often the last use of a key is written.
+const touchInterval = time.Minute
+
+// Identity is who made an authenticated request.
+type Identity struct {
+	// Subject is the basic auth login, key:<id> for API keys or
+	// jwt:<subject claim> for JWTs.
+	Subject string
+	// KeyID is the id of the API key used, 0 for other credentials.
+	KeyID int64
+	// Roles are taken from JWTs, Scopes are granted by them.
+	Roles  []string
+	Scopes []string
+}
+
+// HasScope reports whether the identity was granted scope.
+func (i Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type ctxKey struct{}
+
+// FromContext returns the identity stored by the middleware.
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(ctxKey{}).(Identity)
+
+	return identity, ok
+}
+
+// Subject returns the subject of the identity in ctx, empty for
+// unauthenticated requests.
+func Subject(ctx context.Context) string {
+	identity, _ := FromContext(ctx)
+
+	return identity.Subject
+}
+
+// WithIdentity returns a copy of ctx carrying identity.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, identity)
+}
+
+type KeyStore interface {
+	GetAPIKey(ctx context.Context, hash string) (storage.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+type Options struct {
+	// Realm is sent in WWW-Authenticate.
+	Realm string
+	// Login and Password enable basic auth with every scope, for clients
+	// predating API keys. Basic auth is off when Login is empty.
+	Login    string
+	Password string
+	// Keys enables Authorization: Bearer with API keys.
+	Keys KeyStore
+	// JWT enables Authorization: Bearer with JWTs, tried for bearer tokens
+	// that aren't API keys.
+	JWT *JWTVerifier
+}
+
+// New authenticates requests with an API key, a JWT or the basic auth
+// credentials and stores the Identity in the request context, other
+// requests get 401.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	if opts.Realm == "" {
+		opts.Realm = "url-shortener"
+	}
+
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			entry := logger.ForRequest(log, r)
+
+			identity, err := authenticate(r, opts)
+			if err != nil {
+				entry.Info("unauthorized", sl.Err(err))
+				unauthorized(w, r, opts)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+var (
+	errNoCredentials = errors.New("no credentials")
+	errInvalidKey    = errors.New("invalid api key")
+	errNoVerifier    = errors.New("bearer token is not an api key")
+	errInvalidLogin  = errors.New("invalid login or password")
+)
+
+func authenticate(r *http.Request, opts Options) (Identity, error) {
+	if token, ok := bearerToken(r); ok {
+		switch {
+		case opts.Keys != nil && apikey.LooksLikeKey(token):
+			return authenticateKey(r.Context(), token, opts.Keys)
+		case opts.JWT != nil:
+			return opts.JWT.Verify(token)
+		default:
+			return Identity{}, errNoVerifier
+		}
+	}
+
+	if login, password, ok := r.BasicAuth(); ok && opts.Login != "" {
+		loginOK := subtle.ConstantTimeCompare([]byte(login), []byte(opts.Login)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(opts.Password)) == 1
+		if !loginOK || !passwordOK {
+			return Identity{}, errInvalidLogin
+		}
+
+		return Identity{Subject: login, Scopes: apikey.Scopes}, nil
+	}
+
+	return Identity{}, errNoCredentials
+}
+
+func authenticateKey(ctx context.Context, token string, keys KeyStore) (Identity, error) {
+	key, err := keys.GetAPIKey(ctx, apikey.Hash(token))
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return Identity{}, errInvalidKey
+	}
+	if err != nil {
+		return Identity{}, err
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return Identity{}, errInvalidKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+		// a failed write shouldn't lock the key out
+		_ = keys.TouchAPIKey(ctx, key.ID, now)
+	}
+
+	return Identity{
+		Subject: "key:" + strconv.FormatInt(key.ID, 10),
+		KeyID:   key.ID,
+		Scopes:  key.Scopes,
+	}, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, opts Options) {
+	if opts.Keys != nil || opts.JWT != nil {
+		w.Header().Add("WWW-Authenticate", `Bearer realm="`+opts.Realm+`"`)
+	}
+	if opts.Login != "" {
+		w.Header().Add("WWW-Authenticate", `Basic realm="`+opts.Realm+`"`)
+	}
+
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, response.Error("unauthorized"))
+}
+
+// RequireScope answers 403 to identities without scope, it must run after New.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			identity, _ := FromContext(r.Context())
+			if !identity.HasScope(scope) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error("missing scope "+scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
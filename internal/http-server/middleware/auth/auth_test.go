@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/apikey"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type keyStore struct {
+	keys    map[string]storage.APIKey
+	touched []int64
+}
+
+func (s *keyStore) GetAPIKey(_ context.Context, hash string) (storage.APIKey, error) {
+	key, ok := s.keys[hash]
+	if !ok {
+		return storage.APIKey{}, storage.ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+func (s *keyStore) TouchAPIKey(_ context.Context, id int64, _ time.Time) error {
+	s.touched = append(s.touched, id)
+	return nil
+}
+
+func TestAuth(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	recently := time.Now().Add(-time.Second)
+
+	keys := &keyStore{keys: map[string]storage.APIKey{
+		apikey.Hash("usk_reader"):  {ID: 1, Scopes: []string{apikey.ScopeLinksRead}},
+		apikey.Hash("usk_writer"):  {ID: 2, Scopes: []string{apikey.ScopeLinksRead, apikey.ScopeLinksWrite}, LastUsedAt: &recently},
+		apikey.Hash("usk_revoked"): {ID: 3, Scopes: []string{apikey.ScopeLinksWrite}, RevokedAt: &past},
+		apikey.Hash("usk_expired"): {ID: 4, Scopes: []string{apikey.ScopeLinksWrite}, ExpiresAt: &past},
+	}}
+
+	tests := []struct {
+		name     string
+		header   string
+		basic    []string
+		respCode int
+		subject  string
+	}{
+		{name: "no credentials", respCode: http.StatusUnauthorized},
+		{name: "key with scope", header: "Bearer usk_writer", respCode: http.StatusOK, subject: "key:2"},
+		{name: "lowercase scheme", header: "bearer usk_writer", respCode: http.StatusOK, subject: "key:2"},
+		{name: "key without scope", header: "Bearer usk_reader", respCode: http.StatusForbidden},
+		{name: "unknown key", header: "Bearer usk_unknown", respCode: http.StatusUnauthorized},
+		{name: "revoked key", header: "Bearer usk_revoked", respCode: http.StatusUnauthorized},
+		{name: "expired key", header: "Bearer usk_expired", respCode: http.StatusUnauthorized},
+		{name: "basic auth", basic: []string{"admin", "secret"}, respCode: http.StatusOK, subject: "admin"},
+		{name: "wrong password", basic: []string{"admin", "wrong"}, respCode: http.StatusUnauthorized},
+	}
+
+	handler := New(slogdiscard.NewDiscardLogger(), Options{Login: "admin", Password: "secret", Keys: keys})(
+		RequireScope(apikey.ScopeLinksWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := FromContext(r.Context())
+			require.True(t, ok)
+			_, _ = w.Write([]byte(identity.Subject))
+		})))
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/url", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			if tc.basic != nil {
+				req.SetBasicAuth(tc.basic[0], tc.basic[1])
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			switch tc.respCode {
+			case http.StatusOK:
+				require.Equal(t, tc.subject, rr.Body.String())
+			case http.StatusUnauthorized:
+				require.Equal(t, []string{`Bearer realm="url-shortener"`, `Basic realm="url-shortener"`},
+					rr.Header().Values("WWW-Authenticate"))
+				fallthrough
+			default:
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, response.StatusError, resp.Status)
+			}
+		})
+	}
+
+	// recently used keys aren't written on every request
+	require.NotContains(t, keys.touched, int64(2))
+	require.Contains(t, keys.touched, int64(1))
+}
+
+func TestAuthWithoutBasic(t *testing.T) {
+	handler := New(slogdiscard.NewDiscardLogger(), Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler called")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/url", nil)
+	req.SetBasicAuth("", "")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+}
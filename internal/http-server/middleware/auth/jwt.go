@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type JWTOptions struct {
+	// HMACSecret verifies HS256 tokens.
+	HMACSecret string
+	// PublicKeyPath is a PEM file with the RSA key verifying RS256 tokens.
+	PublicKeyPath string
+	// JWKSPath is a local JWKS file, RSA keys are picked by the kid header
+	// of RS256 tokens and oct keys by the kid of HS256 ones.
+	JWKSPath string
+
+	// Issuer and Audience are checked when set.
+	Issuer   string
+	Audience string
+
+	// SubjectClaim names the user, sub by default. RolesClaim holds the
+	// roles as a list or a space separated string, roles by default.
+	SubjectClaim string
+	RolesClaim   string
+	// RoleScopes grants API scopes to roles, a token gets the scopes of all of its roles.
+	RoleScopes map[string][]string
+}
+
+// JWTVerifier turns bearer JWTs into identities.
+type JWTVerifier struct {
+	opts      JWTOptions
+	hmacKeys  map[string][]byte
+	rsaKeys   map[string]*rsa.PublicKey
+	methods   []string
+	parseOpts []jwt.ParserOption
+}
+
+// NewJWTVerifier loads the configured keys, at least one is required.
+func NewJWTVerifier(opts JWTOptions) (*JWTVerifier, error) {
+	const op = "auth.NewJWTVerifier"
+
+	if opts.SubjectClaim == "" {
+		opts.SubjectClaim = "sub"
+	}
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = "roles"
+	}
+
+	v := &JWTVerifier{
+		opts:     opts,
+		hmacKeys: make(map[string][]byte),
+		rsaKeys:  make(map[string]*rsa.PublicKey),
+	}
+
+	if opts.HMACSecret != "" {
+		v.hmacKeys[""] = []byte(opts.HMACSecret)
+	}
+
+	if opts.PublicKeyPath != "" {
+		data, err := os.ReadFile(opts.PublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %s : %w", op, opts.PublicKeyPath, err)
+		}
+		v.rsaKeys[""] = key
+	}
+
+	if opts.JWKSPath != "" {
+		if err := v.loadJWKS(opts.JWKSPath); err != nil {
+			return nil, fmt.Errorf("%s : %s : %w", op, opts.JWKSPath, err)
+		}
+	}
+
+	if len(v.hmacKeys) > 0 {
+		v.methods = append(v.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(v.rsaKeys) > 0 {
+		v.methods = append(v.methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(v.methods) == 0 {
+		return nil, fmt.Errorf("%s : no verification keys configured", op)
+	}
+
+	v.parseOpts = []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if opts.Issuer != "" {
+		v.parseOpts = append(v.parseOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		v.parseOpts = append(v.parseOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	return v, nil
+}
+
+// Verify checks the signature and the claims of token and maps it to an identity.
+func (v *JWTVerifier) Verify(token string) (Identity, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(token, claims, v.key, v.parseOpts...)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	subject, _ := claims[v.opts.SubjectClaim].(string)
+	if subject == "" {
+		return Identity{}, fmt.Errorf("claim %s is missing", v.opts.SubjectClaim)
+	}
+
+	roles := stringList(claims[v.opts.RolesClaim])
+
+	granted := make(map[string]bool)
+	for _, role := range roles {
+		for _, scope := range v.opts.RoleScopes[role] {
+			granted[scope] = true
+		}
+	}
+
+	scopes := make([]string, 0, len(granted))
+	for scope := range granted {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	// prefixed so that a sub like key:1 can't pass for an API key
+	return Identity{Subject: "jwt:" + subject, Roles: roles, Scopes: scopes}, nil
+}
+
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if key, ok := v.hmacKeys[kid]; ok {
+			return key, nil
+		}
+		if key, ok := v.hmacKeys[""]; ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodRSA:
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		if key, ok := v.rsaKeys[""]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no key for kid %q", kid)
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		K   string `json:"k"`
+	} `json:"keys"`
+}
+
+func (v *JWTVerifier) loadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		switch key.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(key.N)
+			if err != nil {
+				return fmt.Errorf("key %q: invalid n: %w", key.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(key.E)
+			if err != nil {
+				return fmt.Errorf("key %q: invalid e: %w", key.Kid, err)
+			}
+
+			exponent := new(big.Int).SetBytes(e)
+			if !exponent.IsInt64() || exponent.Int64() < 3 {
+				return fmt.Errorf("key %q: invalid exponent", key.Kid)
+			}
+
+			v.rsaKeys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(key.K)
+			if err != nil {
+				return fmt.Errorf("key %q: invalid k: %w", key.Kid, err)
+			}
+
+			v.hmacKeys[key.Kid] = secret
+		}
+	}
+
+	if len(set.Keys) == 0 {
+		return errors.New("no keys")
+	}
+
+	return nil
+}
+
+// stringList reads a claim holding a list of strings or a space separated string.
+func stringList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/lib/apikey"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const secret = "portal-secret"
+
+var roleScopes = map[string][]string{
+	"viewer": {apikey.ScopeLinksRead},
+	"editor": {apikey.ScopeLinksRead, apikey.ScopeLinksWrite},
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func claims(overrides jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub":   "alice",
+		"iss":   "portal",
+		"roles": []string{"editor"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+
+	return c
+}
+
+func TestVerifyHS256(t *testing.T) {
+	verifier, err := NewJWTVerifier(JWTOptions{HMACSecret: secret, Issuer: "portal", RoleScopes: roleScopes})
+	require.NoError(t, err)
+
+	hs := jwt.SigningMethodHS256
+
+	tests := []struct {
+		name   string
+		token  string
+		scopes []string
+		ok     bool
+	}{
+		{
+			name:   "editor",
+			token:  sign(t, hs, []byte(secret), "", claims(nil)),
+			scopes: []string{apikey.ScopeLinksRead, apikey.ScopeLinksWrite},
+			ok:     true,
+		},
+		{
+			name:   "roles as a string",
+			token:  sign(t, hs, []byte(secret), "", claims(jwt.MapClaims{"roles": "viewer unknown"})),
+			scopes: []string{apikey.ScopeLinksRead},
+			ok:     true,
+		},
+		{
+			name:   "no roles",
+			token:  sign(t, hs, []byte(secret), "", claims(jwt.MapClaims{"roles": nil})),
+			scopes: []string{},
+			ok:     true,
+		},
+		{name: "wrong secret", token: sign(t, hs, []byte("other"), "", claims(nil))},
+		{name: "expired", token: sign(t, hs, []byte(secret), "", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}))},
+		{name: "no expiry", token: sign(t, hs, []byte(secret), "", claims(jwt.MapClaims{"exp": nil}))},
+		{name: "wrong issuer", token: sign(t, hs, []byte(secret), "", claims(jwt.MapClaims{"iss": "other"}))},
+		{name: "no subject", token: sign(t, hs, []byte(secret), "", claims(jwt.MapClaims{"sub": nil}))},
+		{name: "alg none", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(nil))},
+		{name: "garbage", token: "not.a.jwt"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, err := verifier.Verify(tc.token)
+			if !tc.ok {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "jwt:alice", identity.Subject)
+			require.Equal(t, tc.scopes, identity.Scopes)
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	dir := t.TempDir()
+
+	pemKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwksKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&pemKey.PublicKey)
+	require.NoError(t, err)
+	pemPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(pemPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "2024-05",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(jwksKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(jwksKey.E)).Bytes()),
+		}},
+	})
+	require.NoError(t, err)
+	jwksPath := filepath.Join(dir, "jwks.json")
+	require.NoError(t, os.WriteFile(jwksPath, jwks, 0o644))
+
+	verifier, err := NewJWTVerifier(JWTOptions{PublicKeyPath: pemPath, JWKSPath: jwksPath, RoleScopes: roleScopes})
+	require.NoError(t, err)
+
+	rs := jwt.SigningMethodRS256
+
+	_, err = verifier.Verify(sign(t, rs, pemKey, "", claims(nil)))
+	require.NoError(t, err)
+
+	_, err = verifier.Verify(sign(t, rs, jwksKey, "2024-05", claims(nil)))
+	require.NoError(t, err)
+
+	// a JWKS key isn't the default one
+	_, err = verifier.Verify(sign(t, rs, jwksKey, "", claims(nil)))
+	require.Error(t, err)
+
+	// HS256 is not accepted without an HMAC key
+	_, err = verifier.Verify(sign(t, jwt.SigningMethodHS256, []byte(secret), "", claims(nil)))
+	require.Error(t, err)
+}
+
+func TestNewJWTVerifierWithoutKeys(t *testing.T) {
+	_, err := NewJWTVerifier(JWTOptions{})
+	require.Error(t, err)
+
+	_, err = NewJWTVerifier(JWTOptions{JWKSPath: filepath.Join(t.TempDir(), "missing.json")})
+	require.Error(t, err)
+}
+
+func TestAuthJWT(t *testing.T) {
+	verifier, err := NewJWTVerifier(JWTOptions{HMACSecret: secret, RoleScopes: roleScopes})
+	require.NoError(t, err)
+
+	handler := New(slogdiscard.NewDiscardLogger(), Options{Login: "admin", Password: "secret", JWT: verifier})(
+		RequireScope(apikey.ScopeLinksWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, _ := FromContext(r.Context())
+			_, _ = w.Write([]byte(identity.Subject))
+		})))
+
+	for token, respCode := range map[string]int{
+		sign(t, jwt.SigningMethodHS256, []byte(secret), "", claims(nil)):                              http.StatusOK,
+		sign(t, jwt.SigningMethodHS256, []byte(secret), "", claims(jwt.MapClaims{"roles": "viewer"})): http.StatusForbidden,
+		sign(t, jwt.SigningMethodHS256, []byte("other"), "", claims(nil)):                             http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, respCode, rr.Code)
+		if respCode == http.StatusOK {
+			require.Equal(t, "jwt:alice", rr.Body.String())
+		}
+	}
+
+	// basic auth keeps working next to JWTs
+	req := httptest.NewRequest(http.MethodPost, "/url", nil)
+	req.SetBasicAuth("admin", "secret")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+}
@@ -1,12 +1,19 @@
 package logger
 
 import (
+	"context"
 	"github.com/go-chi/chi/middleware"
+	"golang-url-shortener/internal/lib/tracing"
 	"golang.org/x/exp/slog"
 	"net/http"
 	"time"
 )
 
+type ctxKey struct{}
+
+// New logs every request once it's completed. The request_id and trace_id of
+// the request are put in its context for ForRequest, so the handlers log them
+// too.
 func New(log *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		log = log.With(
@@ -15,12 +22,17 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 		log.Info("logger middleware enabled")
 
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			entry := log.With(
+			ids := []any{
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("trace_id", tracing.TraceID(r.Context())),
+			}
+			r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, ids))
+
+			entry := ForRequest(log, r).With(
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
-				slog.String("request_id", middleware.GetReqID(r.Context())),
 			)
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
@@ -39,3 +51,14 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(fn)
 	}
 }
+
+// ForRequest returns log with the request_id and trace_id New found for r,
+// log itself for requests that didn't go through New.
+func ForRequest(log *slog.Logger, r *http.Request) *slog.Logger {
+	ids, ok := r.Context().Value(ctxKey{}).([]any)
+	if !ok {
+		return log
+	}
+
+	return log.With(ids...)
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"golang-url-shortener/internal/lib/tracing"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func TestForRequest(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(New(log))
+	router.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		ForRequest(log, r).Info("handled")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/sale", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry["msg"] != "logger middleware enabled" {
+			lines = append(lines, entry)
+		}
+	}
+
+	// the line of the handler and the one of the middleware
+	require.Len(t, lines, 2)
+	for _, entry := range lines {
+		require.Equal(t, "req-1", entry["request_id"])
+		require.Equal(t, traceID, entry["trace_id"])
+	}
+}
+
+func TestForRequestWithoutMiddleware(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	req := httptest.NewRequest(http.MethodGet, "/sale", nil)
+
+	require.Same(t, log, ForRequest(log, req))
+}
@@ -0,0 +1,184 @@
+package ratelimit
+
+import (
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang.org/x/exp/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Options struct {
+	// Name separates the buckets of limiters sharing a store.
+	Name  string
+	Limit Limit
+	// TrustProxy takes the client IP from X-Forwarded-For and X-Real-IP,
+	// only enable it behind a proxy setting them.
+	TrustProxy bool
+	// TrustedProxies are the addresses or CIDR ranges of the proxies in
+	// front of the service. The headers are only read from them and their
+	// X-Forwarded-For entries are skipped. Without any, the peer is the
+	// proxy and the rightmost entry is the client.
+	TrustedProxies []string
+}
+
+// New limits requests per API key, authenticated user or client IP with a
+// token bucket. Requests over the limit get 429 with Retry-After, every
+// response carries the X-RateLimit-* headers. The limiter is off when
+// Limit.Requests is not positive, Limit.Period is a minute by default.
+func New(log *slog.Logger, store Store, opts Options) func(next http.Handler) http.Handler {
+	if opts.Limit.Period <= 0 {
+		opts.Limit.Period = time.Minute
+	}
+
+	return func(next http.Handler) http.Handler {
+		if opts.Limit.Requests <= 0 {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+			slog.String("limiter", opts.Name),
+		)
+
+		proxies := newProxies(log, opts.TrustProxy, opts.TrustedProxies)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := clientKey(r, proxies)
+
+			res, err := store.Take(opts.Name+"\x00"+key, opts.Limit, time.Now())
+			if err != nil {
+				// a failing store shouldn't take the service down
+				logger.ForRequest(log, r).Error("failed to take a token", sl.Err(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+			if !res.Allowed {
+				logger.ForRequest(log, r).Info("rate limited", slog.String("key", key))
+
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, response.Error("too many requests"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// clientKey is the API key or the user of an authenticated request, the
+// client IP otherwise.
+func clientKey(r *http.Request, proxies *proxies) string {
+	if identity, ok := auth.FromContext(r.Context()); ok {
+		if identity.KeyID != 0 {
+			return "key:" + strconv.FormatInt(identity.KeyID, 10)
+		}
+		return "user:" + identity.Subject
+	}
+
+	return "ip:" + clientIP(r, proxies)
+}
+
+// clientIP is the peer address, or when the peer is a trusted proxy the
+// rightmost X-Forwarded-For entry that isn't one. Entries to its left are
+// set by the client and can't be trusted.
+func clientIP(r *http.Request, proxies *proxies) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !proxies.isPeer(host) {
+		return host
+	}
+
+	var entries []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		entries = append(entries, strings.Split(header, ",")...)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := strings.TrimSpace(entries[i])
+		if entry == "" {
+			continue
+		}
+		if i == 0 || !proxies.contains(entry) {
+			return entry
+		}
+	}
+
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return strings.TrimSpace(realIP)
+	}
+
+	return host
+}
+
+type proxies struct {
+	enabled bool
+	nets    []*net.IPNet
+}
+
+func newProxies(log *slog.Logger, enabled bool, trusted []string) *proxies {
+	p := &proxies{enabled: enabled}
+
+	for _, entry := range trusted {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Error("skipping invalid trusted proxy", slog.String("proxy", entry), sl.Err(err))
+			continue
+		}
+		p.nets = append(p.nets, ipNet)
+	}
+
+	return p
+}
+
+// isPeer reports whether the headers of a request from host are trusted.
+func (p *proxies) isPeer(host string) bool {
+	if !p.enabled {
+		return false
+	}
+
+	return len(p.nets) == 0 || p.contains(host)
+}
+
+func (p *proxies) contains(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range p.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,240 @@
+package ratelimit
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 3}
+	now := time.Now()
+
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take("a", limit, now)
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, 3, res.Limit)
+		require.Equal(t, i, res.Remaining)
+	}
+
+	res, err := store.Take("a", limit, now)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	require.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	// other keys have their own bucket
+	res, _ = store.Take("b", limit, now)
+	require.True(t, res.Allowed)
+
+	// two tokens a second
+	res, _ = store.Take("a", limit, now.Add(500*time.Millisecond))
+	require.True(t, res.Allowed)
+	res, _ = store.Take("a", limit, now.Add(500*time.Millisecond))
+	require.False(t, res.Allowed)
+
+	// never more than the burst
+	res, _ = store.Take("a", limit, now.Add(time.Hour))
+	require.True(t, res.Allowed)
+	require.Equal(t, 2, res.Remaining)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name       string
+		opts       Options
+		store      Store
+		prepare    func(r *http.Request) *http.Request
+		remoteAddr string
+		codes      []int
+		headers    bool
+	}{
+		{
+			name:    "by client ip",
+			opts:    Options{Limit: Limit{Requests: 2, Period: time.Minute}},
+			codes:   []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			headers: true,
+		},
+		{
+			name: "by api key",
+			opts: Options{Limit: Limit{Requests: 1, Period: time.Minute}},
+			prepare: func(r *http.Request) *http.Request {
+				return r.WithContext(auth.WithIdentity(r.Context(), auth.Identity{Subject: "key:1", KeyID: 1}))
+			},
+			codes:   []int{http.StatusOK, http.StatusTooManyRequests},
+			headers: true,
+		},
+		{
+			name: "forwarded ip",
+			opts: Options{Limit: Limit{Requests: 1, Period: time.Minute}, TrustProxy: true},
+			prepare: func(r *http.Request) *http.Request {
+				// each request comes from another client behind the proxy
+				r.Header.Set("X-Forwarded-For", r.Header.Get("X-Test-Client"))
+				return r
+			},
+			codes:   []int{http.StatusOK, http.StatusOK, http.StatusOK},
+			headers: true,
+		},
+		{
+			name: "spoofed forwarded ip",
+			opts: Options{Limit: Limit{Requests: 1, Period: time.Minute}, TrustProxy: true},
+			prepare: func(r *http.Request) *http.Request {
+				// the client rotates the entries it sends, the proxy appends its peer
+				r.Header.Set("X-Forwarded-For", r.Header.Get("X-Test-Client")+", 203.0.113.7")
+				return r
+			},
+			codes:   []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests},
+			headers: true,
+		},
+		{
+			name:  "disabled",
+			opts:  Options{},
+			codes: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name:  "failing store",
+			opts:  Options{Limit: Limit{Requests: 1, Period: time.Minute}},
+			store: failingStore{},
+			codes: []int{http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.store
+			if store == nil {
+				store = NewMemoryStore()
+			}
+
+			handler := New(slogdiscard.NewDiscardLogger(), store, tc.opts)(ok)
+
+			for i, code := range tc.codes {
+				req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+				req.RemoteAddr = "192.0.2.1:1234"
+				req.Header.Set("X-Test-Client", "198.51.100."+string(rune('1'+i)))
+				if tc.prepare != nil {
+					req = tc.prepare(req)
+				}
+
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+
+				require.Equal(t, code, rr.Code, "request %d", i)
+				if tc.headers {
+					require.NotEmpty(t, rr.Header().Get("X-RateLimit-Limit"))
+					require.NotEmpty(t, rr.Header().Get("X-RateLimit-Remaining"))
+					require.NotEmpty(t, rr.Header().Get("X-RateLimit-Reset"))
+				}
+				if code == http.StatusTooManyRequests {
+					// a token every Period / Requests
+					require.Equal(t, strconv.Itoa(60/tc.opts.Limit.Requests), rr.Header().Get("Retry-After"))
+					require.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+				}
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		trusted    []string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "headers ignored",
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "rightmost entry",
+			trustProxy: true,
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"198.51.100.1, 198.51.100.2", "203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted hops skipped",
+			trustProxy: true,
+			trusted:    []string{"10.0.0.0/8", "192.0.2.1"},
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"198.51.100.1, 203.0.113.7, 10.1.2.3"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "only trusted hops",
+			trustProxy: true,
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"10.1.2.3, 10.1.2.4"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "untrusted peer",
+			trustProxy: true,
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "real ip",
+			trustProxy: true,
+			remoteAddr: "192.0.2.1:1234",
+			realIP:     "198.51.100.1",
+			want:       "198.51.100.1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, header := range tc.forwarded {
+				req.Header.Add("X-Forwarded-For", header)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			proxies := newProxies(slogdiscard.NewDiscardLogger(), tc.trustProxy, tc.trusted)
+
+			require.Equal(t, tc.want, clientIP(req, proxies))
+		})
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Period: time.Second}
+	now := time.Now()
+
+	for i := 0; i < maxTrackedBuckets; i++ {
+		_, _ = store.Take(strconv.Itoa(i), limit, now)
+	}
+
+	// every bucket is full again, a sweep drops one batch of them
+	_, _ = store.Take("new", limit, now.Add(time.Minute))
+	require.Equal(t, maxTrackedBuckets-sweepBatch+1, len(store.buckets))
+}
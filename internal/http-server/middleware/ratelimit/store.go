@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	maxTrackedBuckets = 100000
+	// sweepBatch bounds the buckets looked at by a sweep, so a request
+	// never holds the lock for a walk over all of them.
+	sweepBatch = 1000
+)
+
+// Limit is a token bucket refilled with Requests tokens every Period and
+// holding up to Burst of them.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	// Burst is Requests when not positive.
+	Burst int
+}
+
+func (l Limit) burst() int {
+	if l.Burst <= 0 {
+		return l.Requests
+	}
+
+	return l.Burst
+}
+
+// rate is the number of tokens added per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result is the state of a bucket after a request took from it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is when the next token is available, set when the request
+	// was not allowed. Reset is when the bucket is full again.
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Store keeps the buckets, an implementation shared between instances can
+// replace the in-memory one.
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+// MemoryStore keeps the buckets of a single instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take takes a token from the bucket of key, it never fails.
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	burst := float64(limit.burst())
+	rate := limit.rate()
+
+	if len(s.buckets) >= maxTrackedBuckets {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now, rate: rate, burst: burst}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last, b.rate, b.burst = now, rate, burst
+
+	res := Result{Limit: limit.burst()}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((burst - b.tokens) / rate)
+
+	return res, nil
+}
+
+// sweep drops the buckets that are full again among up to sweepBatch of
+// them, they behave like new ones. Map iteration starts at a random bucket,
+// so successive sweeps cover the whole map.
+func (s *MemoryStore) sweep(now time.Time) {
+	seen := 0
+	for key, b := range s.buckets {
+		if seen == sweepBatch {
+			return
+		}
+		seen++
+
+		if b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
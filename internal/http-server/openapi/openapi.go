@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+//go:embed openapi.json
+var specJSON []byte
+
+//go:embed docs.html
+var docsHTML []byte
+
+type Document struct {
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]Operation
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	RequestBody *RequestBody `json:"requestBody,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+var spec = mustParse(specJSON)
+
+func mustParse(data []byte) *Document {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		panic("openapi: invalid embedded spec: " + err.Error())
+	}
+
+	return &doc
+}
+
+func Spec() *Document {
+	return spec
+}
+
+// FindOperation looks up the operation documented for the request method and path.
+// Path templates such as /url/{alias} match any single segment, concrete paths
+// take precedence over templated ones.
+func (d *Document) FindOperation(method, path string) (Operation, bool) {
+	method = strings.ToLower(method)
+	segments := splitPath(path)
+
+	var found Operation
+	best := -1
+
+	for template, item := range d.Paths {
+		op, ok := item[method]
+		if !ok {
+			continue
+		}
+
+		literals, ok := matchPath(splitPath(template), segments)
+		if ok && literals > best {
+			found, best = op, literals
+		}
+	}
+
+	return found, best >= 0
+}
+
+func (d *Document) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+
+	return s
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+// matchPath reports whether segments match the template and how many
+// of the template segments are literals.
+func matchPath(template, segments []string) (int, bool) {
+	if len(template) != len(segments) {
+		return 0, false
+	}
+
+	literals := 0
+	for i, part := range template {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if segments[i] == "" {
+				return 0, false
+			}
+			continue
+		}
+
+		if part != segments[i] {
+			return 0, false
+		}
+		literals++
+	}
+
+	return literals, true
+}
+
+func SpecHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(specJSON)
+	}
+}
+
+func DocsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(docsHTML)
+	}
+}
@@ -0,0 +1,215 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/render"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang.org/x/exp/slog"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxBodySize = 1 << 20
+
+// ValidateRequest rejects JSON bodies that don't match the request schema
+// documented for the route. Routes without a documented body pass through.
+func ValidateRequest(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/openapi"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			op, ok := spec.FindOperation(r.Method, r.URL.Path)
+			if !ok || op.RequestBody == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			entry := logger.ForRequest(log, r).With(
+				slog.String("operation", op.OperationID),
+			)
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+			if err != nil {
+				entry.Error("failed to read request body", sl.Err(err))
+				badRequest(w, r, "failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			if len(bytes.TrimSpace(body)) == 0 {
+				if op.RequestBody.Required {
+					entry.Info("request body is empty")
+					badRequest(w, r, "request body is empty")
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			media, ok := op.RequestBody.Content["application/json"]
+			if !ok || media.Schema == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				entry.Info("failed to decode request body", sl.Err(err))
+				badRequest(w, r, "failed to decode request body")
+				return
+			}
+
+			if errs := spec.Validate(media.Schema, value); len(errs) > 0 {
+				entry.Info("request body does not match schema", slog.Any("errors", errs))
+				badRequest(w, r, strings.Join(errs, ", "))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func badRequest(w http.ResponseWriter, r *http.Request, msg string) {
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, response.Error(msg))
+}
+
+// Validate checks value decoded with json.Decoder.UseNumber against the schema
+// and returns a message for every violation found.
+func (d *Document) Validate(s *Schema, value interface{}) []string {
+	return d.validate(s, value, "body")
+}
+
+func (d *Document) validate(s *Schema, value interface{}, field string) []string {
+	s = d.resolve(s)
+	if s == nil {
+		return nil
+	}
+
+	if value == nil {
+		return []string{fmt.Sprintf("field %s must not be null", field)}
+	}
+
+	var errs []string
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("field %s must be an object", field)}
+		}
+
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, fmt.Sprintf("field %s is a required field", name))
+			}
+		}
+
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, fmt.Sprintf("field %s is unknown", name))
+				}
+				continue
+			}
+
+			errs = append(errs, d.validate(prop, obj[name], name)...)
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("field %s must be an array", field)}
+		}
+
+		for i, item := range arr {
+			errs = append(errs, d.validate(s.Items, item, fmt.Sprintf("%s[%d]", field, i))...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("field %s must be a string", field)}
+		}
+
+		if s.MinLength != nil && len([]rune(str)) < *s.MinLength {
+			errs = append(errs, fmt.Sprintf("field %s is too short", field))
+		}
+		if s.MaxLength != nil && len([]rune(str)) > *s.MaxLength {
+			errs = append(errs, fmt.Sprintf("field %s is too long", field))
+		}
+		if s.Format == "uri" && !isURI(str) {
+			errs = append(errs, fmt.Sprintf("field %s is not a valid URL", field))
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				errs = append(errs, fmt.Sprintf("field %s is not a valid date-time", field))
+			}
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return []string{fmt.Sprintf("field %s must be a number", field)}
+		}
+
+		f, err := num.Float64()
+		if err != nil || (s.Type == "integer" && strings.ContainsAny(num.String(), ".eE")) {
+			return []string{fmt.Sprintf("field %s must be an integer", field)}
+		}
+
+		if s.Minimum != nil && f < *s.Minimum {
+			errs = append(errs, fmt.Sprintf("field %s must be at least %v", field, *s.Minimum))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			errs = append(errs, fmt.Sprintf("field %s must be at most %v", field, *s.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("field %s must be a boolean", field)}
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		errs = append(errs, fmt.Sprintf("field %s has unsupported value", field))
+	}
+
+	return errs
+}
+
+func isURI(s string) bool {
+	u, err := url.ParseRequestURI(s)
+
+	return err == nil && u.Scheme != ""
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+
+	return false
+}
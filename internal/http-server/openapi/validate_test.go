@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		respCode  int
+		respError string
+	}{
+		{
+			name:     "valid save",
+			method:   http.MethodPost,
+			path:     "/url/",
+			body:     `{"url": "https://www.youtube.com/", "alias": "youtube"}`,
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "missing url",
+			method:    http.MethodPost,
+			path:      "/url",
+			body:      `{"alias": "youtube"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field url is a required field",
+		},
+		{
+			name:      "invalid url",
+			method:    http.MethodPost,
+			path:      "/url",
+			body:      `{"url": "wrong url"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field url is not a valid URL",
+		},
+		{
+			name:      "unknown field",
+			method:    http.MethodPost,
+			path:      "/url",
+			body:      `{"url": "https://www.youtube.com/", "aliass": "youtube"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field aliass is unknown",
+		},
+		{
+			name:      "invalid rule",
+			method:    http.MethodPost,
+			path:      "/url",
+			body:      `{"url": "https://example.com/", "rules": [{"url": "https://example.com/de", "platforms": ["symbian"], "from": "tomorrow"}]}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field from is not a valid date-time, field platforms[0] has unsupported value",
+		},
+		{
+			name:      "wrong type",
+			method:    http.MethodPut,
+			path:      "/url",
+			body:      `{"url": "https://www.youtube.com/", "old_alias": 1, "new_alias": ""}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field new_alias is too short, field old_alias must be a string",
+		},
+		{
+			name:      "empty body",
+			method:    http.MethodPut,
+			path:      "/url",
+			respCode:  http.StatusBadRequest,
+			respError: "request body is empty",
+		},
+		{
+			name:      "malformed json",
+			method:    http.MethodPost,
+			path:      "/url",
+			body:      `{"url": `,
+			respCode:  http.StatusBadRequest,
+			respError: "failed to decode request body",
+		},
+		{
+			name:     "route without body",
+			method:   http.MethodDelete,
+			path:     "/url/youtube",
+			respCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var passedBody []byte
+			called := false
+
+			r := chi.NewRouter()
+			r.Use(ValidateRequest(slogdiscard.NewDiscardLogger()))
+			r.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				passedBody, _ = io.ReadAll(r.Body)
+			})
+
+			req, err := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.respError == "" {
+				// the body must still be readable by the handler
+				require.True(t, called)
+				require.Equal(t, tc.body, string(passedBody))
+				return
+			}
+
+			var resp response.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
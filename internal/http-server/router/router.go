@@ -0,0 +1,218 @@
+package router
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"golang-url-shortener/internal/config"
+	"golang-url-shortener/internal/health"
+	"golang-url-shortener/internal/http-server/handlers/audit"
+	"golang-url-shortener/internal/http-server/handlers/keys/create"
+	"golang-url-shortener/internal/http-server/handlers/keys/list"
+	"golang-url-shortener/internal/http-server/handlers/keys/revoke"
+	"golang-url-shortener/internal/http-server/handlers/preview"
+	"golang-url-shortener/internal/http-server/handlers/redirect"
+	"golang-url-shortener/internal/http-server/handlers/url/broken"
+	"golang-url-shortener/internal/http-server/handlers/url/delete"
+	"golang-url-shortener/internal/http-server/handlers/url/qrcode"
+	"golang-url-shortener/internal/http-server/handlers/url/save"
+	"golang-url-shortener/internal/http-server/handlers/url/update"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/deliveries"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/subscribe"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/subscriptions"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/unsubscribe"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/http-server/middleware/ratelimit"
+	"golang-url-shortener/internal/http-server/openapi"
+	"golang-url-shortener/internal/lib/apikey"
+	"golang-url-shortener/internal/lib/auditlog"
+	"golang-url-shortener/internal/lib/domains"
+	"golang-url-shortener/internal/lib/loops"
+	"golang-url-shortener/internal/lib/metrics"
+	"golang-url-shortener/internal/lib/reserved"
+	"golang-url-shortener/internal/lib/tracing"
+	"golang-url-shortener/internal/screening"
+	"golang-url-shortener/internal/webhooks"
+	"golang.org/x/exp/slog"
+	"net/http"
+)
+
+type Storage interface {
+	save.URLSaver
+	update.URLUpdater
+	delete.URLDeleter
+	redirect.URLGetter
+	redirect.ClickRecorder
+	preview.LinkGetter
+	broken.BrokenLister
+	auth.KeyStore
+	create.KeyCreator
+	list.KeyLister
+	revoke.KeyRevoker
+	auditlog.Store
+	audit.AuditLister
+	webhooks.EventStore
+	subscribe.WebhookCreator
+	subscriptions.WebhookLister
+	unsubscribe.WebhookDeleter
+	deliveries.DeliveryLister
+}
+
+// New builds the router, jwtVerifier is nil when JWTs aren't accepted and
+// probes is nil when /readyz has nothing to check.
+func New(log *slog.Logger, cfg *config.Config, storage Storage, screener *screening.Screener, jwtVerifier *auth.JWTVerifier, probes *health.Health) *chi.Mux {
+	router := chi.NewRouter()
+	registry := domains.New(cfg.HTTPServer.BaseURL, cfg.HTTPServer.Domains)
+	detector := loops.New(registry, storage, cfg.Redirect.MaxChainDepth)
+	// filled from the routes below once they are all registered
+	aliases := reserved.New()
+
+	var events *webhooks.Dispatcher
+	if cfg.Webhooks.Enabled {
+		events = webhooks.NewDispatcher(log, storage)
+	}
+	auditLog := auditlog.New(log, storage, events)
+
+	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	if cfg.Metrics.Enabled {
+		router.Use(metrics.Middleware)
+	}
+	router.Use(logger.New(log))
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.URLFormat)
+	router.Use(registry.Middleware)
+
+	// URLFormat trims the extension, so /openapi.json is routed as /openapi.
+	router.Get("/openapi", openapi.SpecHandler())
+	router.Get("/docs", openapi.DocsHandler())
+	router.Get("/healthz", health.LiveHandler())
+	router.Get("/readyz", probes.ReadyHandler(log))
+
+	if cfg.Metrics.Enabled && cfg.Metrics.Address == "" {
+		path := cfg.Metrics.Path
+		if path == "" {
+			path = metrics.DefaultPath
+		}
+		router.Get(path, metrics.Handler().ServeHTTP)
+	}
+
+	authenticate := auth.New(log, auth.Options{
+		Login:    cfg.HTTPServer.Login,
+		Password: cfg.HTTPServer.Password,
+		Keys:     storage,
+		JWT:      jwtVerifier,
+	})
+
+	limits := ratelimit.NewMemoryStore()
+
+	// the auth limiter runs before authenticate and keys the client IP so
+	// that guessing credentials is throttled, the API limiter runs after it
+	// and keys the API key or user. The routes share the buckets of a client.
+	authLimit := ratelimit.New(log, limits, ratelimit.Options{
+		Name:           "auth",
+		Limit:          limit(cfg.RateLimit.Auth),
+		TrustProxy:     cfg.RateLimit.TrustProxy,
+		TrustedProxies: cfg.RateLimit.TrustedProxies,
+	})
+	apiLimit := ratelimit.New(log, limits, ratelimit.Options{
+		Name:           "api",
+		Limit:          limit(cfg.RateLimit.API),
+		TrustProxy:     cfg.RateLimit.TrustProxy,
+		TrustedProxies: cfg.RateLimit.TrustedProxies,
+	})
+
+	router.Route("/url", func(r chi.Router) {
+		r.Use(authLimit)
+		r.Use(authenticate)
+		r.Use(apiLimit)
+		r.Use(openapi.ValidateRequest(log))
+
+		r.With(auth.RequireScope(apikey.ScopeLinksWrite)).Post("/", save.New(log, storage, registry, aliases, screener, detector, auditLog))
+		r.With(auth.RequireScope(apikey.ScopeLinksRead)).Get("/broken", broken.New(log, storage))
+		r.With(auth.RequireScope(apikey.ScopeLinksDelete)).Delete("/{alias}", delete.New(log, storage, registry, auditLog))
+		r.With(auth.RequireScope(apikey.ScopeLinksWrite)).Put("/", update.New(log, storage, registry, aliases, screener, detector, auditLog))
+		r.With(auth.RequireScope(apikey.ScopeLinksRead)).Get("/{alias}/qr", qrcode.New(log, storage, qrcode.Options{
+			BaseURL: cfg.HTTPServer.BaseURL,
+			Domains: registry,
+		}))
+	})
+
+	router.Route("/admin/keys", func(r chi.Router) {
+		r.Use(authLimit)
+		r.Use(authenticate)
+		r.Use(apiLimit)
+		r.Use(auth.RequireScope(apikey.ScopeKeysManage))
+		r.Use(openapi.ValidateRequest(log))
+
+		r.Post("/", create.New(log, storage))
+		r.Get("/", list.New(log, storage))
+		r.Delete("/{id}", revoke.New(log, storage))
+	})
+
+	router.Route("/audit", func(r chi.Router) {
+		r.Use(authLimit)
+		r.Use(authenticate)
+		r.Use(apiLimit)
+		r.Use(auth.RequireScope(apikey.ScopeAuditRead))
+
+		r.Get("/", audit.New(log, storage))
+	})
+
+	router.Route("/admin/webhooks", func(r chi.Router) {
+		r.Use(authLimit)
+		r.Use(authenticate)
+		r.Use(apiLimit)
+		r.Use(auth.RequireScope(apikey.ScopeWebhooksManage))
+		r.Use(openapi.ValidateRequest(log))
+
+		r.Post("/", subscribe.New(log, storage, screener))
+		r.Get("/", subscriptions.New(log, storage))
+		r.Delete("/{id}", unsubscribe.New(log, storage))
+		r.Get("/{id}/deliveries", deliveries.New(log, storage))
+	})
+
+	redirectHandler := redirect.New(log, storage, storage, redirect.Options{
+		DefaultType:            cfg.Redirect.DefaultType,
+		CookieSecret:           []byte(cfg.Redirect.CookieSecret),
+		CookieTTL:              cfg.Redirect.PasswordCookieTTL,
+		PasswordAttempts:       cfg.Redirect.PasswordAttempts,
+		PasswordAttemptsWindow: cfg.Redirect.PasswordAttemptsWindow,
+		VariantCookieTTL:       cfg.Redirect.VariantCookieTTL,
+		NotActiveURL:           cfg.Redirect.NotActiveURL,
+		NotActiveStatus:        cfg.Redirect.NotActiveStatus,
+		Screener:               screener,
+		Events:                 events,
+	})
+
+	previewHandler := preview.New(log, storage)
+
+	router.Group(func(r chi.Router) {
+		r.Use(ratelimit.New(log, limits, ratelimit.Options{
+			Name:           "redirect",
+			Limit:          limit(cfg.RateLimit.Redirect),
+			TrustProxy:     cfg.RateLimit.TrustProxy,
+			TrustedProxies: cfg.RateLimit.TrustedProxies,
+		}))
+
+		r.Get("/{alias}", redirectHandler)
+		r.Post("/{alias}", redirectHandler)
+		r.Get("/{alias}/*", redirectHandler)
+		r.Post("/{alias}/*", redirectHandler)
+
+		r.Get("/{alias}+", previewHandler)
+		r.Get("/{alias}/preview", previewHandler)
+	})
+
+	// the routes of the service win over /{alias}, links can't take their names
+	_ = chi.Walk(router, func(_ string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		aliases.Add(route)
+		return nil
+	})
+
+	return router
+}
+
+func limit(cfg config.Limit) ratelimit.Limit {
+	return ratelimit.Limit{Requests: cfg.Requests, Period: cfg.Period, Burst: cfg.Burst}
+}
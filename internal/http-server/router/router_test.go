@@ -0,0 +1,102 @@
+package router
+
+import (
+	"context"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/config"
+	"golang-url-shortener/internal/http-server/openapi"
+	"golang-url-shortener/internal/lib/domains"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"golang-url-shortener/internal/storage/sqlite"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoutesDocumented(t *testing.T) {
+	router := New(slogdiscard.NewDiscardLogger(), &config.Config{Metrics: config.Metrics{Enabled: true}}, nil, nil, nil, nil)
+
+	documented := make(map[string]bool)
+	for p, item := range openapi.Spec().Paths {
+		// URLFormat routes /openapi.json as /openapi, so compare without extensions.
+		p = strings.TrimSuffix(p, path.Ext(p))
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+p] = true
+		}
+	}
+
+	err := chi.Walk(router, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		// chi catch-all is documented as a {path} parameter
+		if strings.HasSuffix(route, "/*") {
+			route = strings.TrimSuffix(route, "*") + "{path}"
+		}
+
+		require.True(t, documented[method+" "+route], "route %s %s is not documented in openapi.json", method, route)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestForwardPathKeepsExtension(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.SaveURL(context.Background(), "https://example.com/base", domains.Default, "promo", storage.LinkOptions{ForwardPath: true})
+	require.NoError(t, err)
+
+	router := New(slogdiscard.NewDiscardLogger(), &config.Config{}, s, nil, nil, nil)
+
+	cases := []struct {
+		path     string
+		location string
+	}{
+		{path: "/promo/docs/page.html", location: "https://example.com/base/docs/page.html"},
+		{path: "/promo/file.pdf", location: "https://example.com/base/file.pdf"},
+		{path: "/promo/a%2Fb.tar.gz", location: "https://example.com/base/a%2Fb.tar.gz"},
+	}
+
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		require.Equal(t, http.StatusFound, rr.Code, tc.path)
+		require.Equal(t, tc.location, rr.Header().Get("Location"), tc.path)
+	}
+}
+
+func TestReservedAliases(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	cfg := &config.Config{
+		HTTPServer: config.HTTPServer{Login: "admin", Password: "secret"},
+		Metrics:    config.Metrics{Enabled: true},
+	}
+	router := New(slogdiscard.NewDiscardLogger(), cfg, s, nil, nil, nil)
+
+	for _, alias := range []string{"openapi", "openapi.json", "docs", "healthz", "readyz", "metrics", "url", "admin", "audit"} {
+		body := `{"url": "https://example.com", "alias": "` + alias + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/url/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("admin", "secret")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, alias)
+		require.Contains(t, rr.Body.String(), "field Alias is reserved", alias)
+	}
+
+	_, err = s.GetLink(context.Background(), domains.Default, "docs")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
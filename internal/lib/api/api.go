@@ -11,7 +11,15 @@ var (
 )
 
 func GetRedirect(url string) (string, error) {
-	const op = "api.GetRedirect"
+	location, _, err := GetRedirectWithStatus(url)
+
+	return location, err
+}
+
+// GetRedirectWithStatus requests url without following redirects and returns
+// the Location header together with the 3xx status code of the response.
+func GetRedirectWithStatus(url string) (string, int, error) {
+	const op = "api.GetRedirectWithStatus"
 
 	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
 		return http.ErrUseLastResponse
@@ -19,13 +27,13 @@ func GetRedirect(url string) (string, error) {
 
 	resp, err := client.Get(url)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusFound {
-		return "", fmt.Errorf("%s: %w: %v", op, ErrInvalidStatusCode, resp)
+	if resp.StatusCode < http.StatusMultipleChoices || resp.StatusCode >= http.StatusBadRequest {
+		return "", resp.StatusCode, fmt.Errorf("%s: %w: %v", op, ErrInvalidStatusCode, resp)
 	}
 
-	return resp.Header.Get("Location"), nil
+	return resp.Header.Get("Location"), resp.StatusCode, nil
 }
@@ -38,6 +38,8 @@ func ValidationError(errs validator.ValidationErrors) Response {
 			errMessages = append(errMessages, fmt.Sprintf("field %s is a required field", err.Field()))
 		case "url":
 			errMessages = append(errMessages, fmt.Sprintf("field %s is not a valid URL", err.Field()))
+		case "notreserved":
+			errMessages = append(errMessages, fmt.Sprintf("field %s is reserved", err.Field()))
 		default:
 			errMessages = append(errMessages, fmt.Sprintf("field %s is not valid", err.Field()))
 		}
@@ -0,0 +1,75 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Scopes of API keys.
+const (
+	ScopeLinksRead   = "links:read"
+	ScopeLinksWrite  = "links:write"
+	ScopeLinksDelete = "links:delete"
+	ScopeStatsRead   = "stats:read"
+	ScopeKeysManage  = "keys:manage"
+	ScopeAuditRead   = "audit:read"
+	// ScopeWebhooksManage manages webhooks and reads their deliveries.
+	ScopeWebhooksManage = "webhooks:manage"
+)
+
+// Scopes lists every scope a key can be given.
+var Scopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeLinksDelete, ScopeStatsRead, ScopeKeysManage, ScopeAuditRead,
+	ScopeWebhooksManage}
+
+const (
+	prefix = "usk_"
+	// PrefixLength is how much of a key is kept in plain text to recognise it.
+	PrefixLength = len(prefix) + 8
+)
+
+// Generate returns a new random key. Keys carry 256 bits of entropy, so
+// a plain SHA-256 of them is enough to store.
+func Generate() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return prefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Hash returns the hex SHA-256 of key, the form keys are stored and looked up in.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Prefix returns the part of key shown in listings.
+func Prefix(key string) string {
+	if len(key) < PrefixLength {
+		return key
+	}
+
+	return key[:PrefixLength]
+}
+
+// ValidScope reports whether scope is one of Scopes.
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LooksLikeKey reports whether token has the shape of a key, other bearer
+// tokens are left to other authenticators.
+func LooksLikeKey(token string) bool {
+	return strings.HasPrefix(token, prefix)
+}
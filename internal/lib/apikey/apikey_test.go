@@ -0,0 +1,27 @@
+package apikey
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	key1, err := Generate()
+	require.NoError(t, err)
+	key2, err := Generate()
+	require.NoError(t, err)
+
+	require.NotEqual(t, key1, key2)
+	require.True(t, LooksLikeKey(key1))
+	require.Len(t, Prefix(key1), PrefixLength)
+
+	require.Len(t, Hash(key1), 64)
+	require.Equal(t, Hash(key1), Hash(key1))
+	require.NotEqual(t, Hash(key1), Hash(key2))
+}
+
+func TestValidScope(t *testing.T) {
+	require.True(t, ValidScope(ScopeLinksWrite))
+	require.False(t, ValidScope("links:*"))
+	require.False(t, ValidScope(""))
+}
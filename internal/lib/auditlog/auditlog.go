@@ -0,0 +1,227 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/middleware"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang-url-shortener/internal/webhooks"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
+)
+
+type Store interface {
+	GetLink(ctx context.Context, domain, alias string) (storage.Link, error)
+	RecordAudit(ctx context.Context, entry storage.AuditEntry) error
+}
+
+// Log records the changes made to links and builds their webhook events,
+// which the storage queues in the transaction of the change. Failures to
+// record are logged and don't fail the change, it already happened.
+// A nil *Log records nothing.
+type Log struct {
+	log    *slog.Logger
+	store  Store
+	events *webhooks.Dispatcher
+}
+
+// New builds a Log, events is nil when webhooks are off.
+func New(log *slog.Logger, store Store, events *webhooks.Dispatcher) *Log {
+	return &Log{
+		log:    log.With(slog.String("component", "auditlog")),
+		store:  store,
+		events: events,
+	}
+}
+
+// Change is the data of the link.created, link.updated and link.deleted
+// webhook events.
+type Change struct {
+	Actor  string `json:"actor"`
+	Domain string `json:"domain,omitempty"`
+	Alias  string `json:"alias"`
+	Before *Link  `json:"before,omitempty"`
+	After  *Link  `json:"after,omitempty"`
+}
+
+var actionEvents = map[string]string{
+	storage.AuditCreate: webhooks.EventLinkCreated,
+	storage.AuditUpdate: webhooks.EventLinkUpdated,
+	storage.AuditDelete: webhooks.EventLinkDeleted,
+}
+
+// Link is the state of a link kept in the audit log, the password hash
+// is left out.
+type Link struct {
+	URL           string                `json:"url"`
+	Domain        string                `json:"domain,omitempty"`
+	Alias         string                `json:"alias"`
+	RedirectType  int                   `json:"redirect_type,omitempty"`
+	Password      bool                  `json:"password,omitempty"`
+	ForwardQuery  bool                  `json:"forward_query,omitempty"`
+	QueryConflict string                `json:"query_conflict,omitempty"`
+	ForwardPath   bool                  `json:"forward_path,omitempty"`
+	Rules         []storage.Rule        `json:"rules,omitempty"`
+	Destinations  []storage.Destination `json:"destinations,omitempty"`
+	StickySplit   bool                  `json:"sticky_split,omitempty"`
+	MaxClicks     int                   `json:"max_clicks,omitempty"`
+	ActiveFrom    *time.Time            `json:"active_from,omitempty"`
+	ActiveUntil   *time.Time            `json:"active_until,omitempty"`
+	FallbackURL   string                `json:"fallback_url,omitempty"`
+}
+
+// Snapshot returns the current state of a link, nil when it doesn't exist.
+func (l *Log) Snapshot(ctx context.Context, domain, alias string) *Link {
+	if l == nil {
+		return nil
+	}
+
+	link, err := l.store.GetLink(ctx, domain, alias)
+	if err != nil {
+		if !errors.Is(err, storage.ErrUrlNotFound) {
+			l.log.Error("failed to get link", sl.Err(err), slog.String("alias", alias))
+		}
+		return nil
+	}
+
+	return snapshot(link)
+}
+
+// Planned returns the state of the link alias of domain once saved with
+// urlToSave and opts, before is its current state, nil for a new link. The
+// current password is kept unless opts set or remove it, and so are the
+// options named by opts.Keep.
+func Planned(urlToSave, domain, alias string, opts storage.LinkOptions, before *Link) *Link {
+	link := snapshot(storage.Link{URL: urlToSave, Domain: domain, Alias: alias, LinkOptions: opts})
+	if before != nil {
+		if opts.PasswordHash == "" && !opts.RemovePassword {
+			link.Password = before.Password
+		}
+		if opts.Keep.RedirectType {
+			link.RedirectType = before.RedirectType
+		}
+		if opts.Keep.MaxClicks {
+			link.MaxClicks = before.MaxClicks
+		}
+		if opts.Keep.ActiveFrom {
+			link.ActiveFrom = before.ActiveFrom
+		}
+		if opts.Keep.ActiveUntil {
+			link.ActiveUntil = before.ActiveUntil
+		}
+	}
+	// stored in UTC, the snapshot taken back from the storage matches
+	link.ActiveFrom = utc(link.ActiveFrom)
+	link.ActiveUntil = utc(link.ActiveUntil)
+
+	return link
+}
+
+func snapshot(link storage.Link) *Link {
+	return &Link{
+		URL:           link.URL,
+		Domain:        link.Domain,
+		Alias:         link.Alias,
+		RedirectType:  link.RedirectType,
+		Password:      link.PasswordHash != "",
+		ForwardQuery:  link.ForwardQuery,
+		QueryConflict: link.QueryConflict,
+		ForwardPath:   link.ForwardPath,
+		Rules:         link.Rules,
+		Destinations:  link.Destinations,
+		StickySplit:   link.StickySplit,
+		MaxClicks:     link.MaxClicks,
+		ActiveFrom:    link.ActiveFrom,
+		ActiveUntil:   link.ActiveUntil,
+		FallbackURL:   link.FallbackURL,
+	}
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	u := t.UTC()
+	return &u
+}
+
+// Events returns the webhook events of a change made by the request r, for
+// the storage to queue them in the transaction of the change. There are none
+// when webhooks are off.
+func (l *Log) Events(r *http.Request, action, domain, alias string, before, after *Link) ([]storage.Event, error) {
+	const op = "auditlog.Events"
+
+	event, ok := actionEvents[action]
+	if l == nil || !ok {
+		return nil, nil
+	}
+
+	data := Change{Actor: auth.Subject(r.Context()), Domain: domain, Alias: alias, Before: before, After: after}
+
+	e, ok, err := l.events.Event(event, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	return []storage.Event{e}, nil
+}
+
+// Record stores a change made by the request r, before and after are
+// the states of the link around it.
+func (l *Log) Record(r *http.Request, action, domain, alias string, before, after *Link) {
+	if l == nil {
+		return
+	}
+
+	log := logger.ForRequest(l.log, r).With(
+		slog.String("action", action),
+		slog.String("alias", alias),
+	)
+
+	entry := storage.AuditEntry{
+		Actor:      auth.Subject(r.Context()),
+		Action:     action,
+		Domain:     domain,
+		Alias:      alias,
+		RequestID:  middleware.GetReqID(r.Context()),
+		RemoteAddr: r.RemoteAddr,
+		CreatedAt:  time.Now(),
+	}
+
+	var err error
+	if entry.Before, err = marshal(before); err != nil {
+		log.Error("failed to marshal link", sl.Err(err))
+		return
+	}
+	if entry.After, err = marshal(after); err != nil {
+		log.Error("failed to marshal link", sl.Err(err))
+		return
+	}
+
+	if err := l.store.RecordAudit(r.Context(), entry); err != nil {
+		log.Error("failed to record audit entry", sl.Err(err))
+	}
+}
+
+func marshal(link *Link) (string, error) {
+	if link == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(link)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,159 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/http-server/middleware/auth"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/storage"
+	"golang-url-shortener/internal/webhooks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	links   map[string]storage.Link
+	entries []storage.AuditEntry
+	err     error
+}
+
+func (s *memStore) GetLink(_ context.Context, domain, alias string) (storage.Link, error) {
+	if s.err != nil {
+		return storage.Link{}, s.err
+	}
+
+	link, ok := s.links[domain+"/"+alias]
+	if !ok {
+		return storage.Link{}, storage.ErrUrlNotFound
+	}
+
+	return link, nil
+}
+
+func (s *memStore) RecordAudit(_ context.Context, entry storage.AuditEntry) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestRecord(t *testing.T) {
+	store := &memStore{links: map[string]storage.Link{
+		"/sale": {ID: 1, Alias: "sale", URL: "https://shop.example.com/sale", LinkOptions: storage.LinkOptions{
+			RedirectType: http.StatusMovedPermanently,
+			PasswordHash: "$2a$10$secret",
+		}},
+	}}
+	log := New(slogdiscard.NewDiscardLogger(), store, nil)
+
+	before := log.Snapshot(context.Background(), "", "sale")
+	require.NotNil(t, before)
+	require.True(t, before.Password)
+	require.Nil(t, log.Snapshot(context.Background(), "", "missing"))
+
+	req := httptest.NewRequest(http.MethodDelete, "/url/sale", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	ctx := auth.WithIdentity(req.Context(), auth.Identity{Subject: "alice"})
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "req-1")
+
+	log.Record(req.WithContext(ctx), storage.AuditDelete, "", "sale", before, nil)
+
+	require.Len(t, store.entries, 1)
+	entry := store.entries[0]
+	require.Equal(t, "alice", entry.Actor)
+	require.Equal(t, storage.AuditDelete, entry.Action)
+	require.Equal(t, "sale", entry.Alias)
+	require.Equal(t, "req-1", entry.RequestID)
+	require.Equal(t, "192.0.2.1:1234", entry.RemoteAddr)
+	require.JSONEq(t, `{"url":"https://shop.example.com/sale","alias":"sale","redirect_type":301,"password":true}`, entry.Before)
+	require.Empty(t, entry.After)
+	require.NotContains(t, entry.Before, "secret")
+	require.False(t, entry.CreatedAt.IsZero())
+}
+
+func TestRecordFailures(t *testing.T) {
+	store := &memStore{err: errors.New("database is locked")}
+	log := New(slogdiscard.NewDiscardLogger(), store, nil)
+
+	// failures are logged, the change already happened
+	require.Nil(t, log.Snapshot(context.Background(), "", "sale"))
+	log.Record(httptest.NewRequest(http.MethodPost, "/url", nil), storage.AuditCreate, "", "sale", nil, nil)
+
+	var nilLog *Log
+	require.Nil(t, nilLog.Snapshot(context.Background(), "", "sale"))
+	nilLog.Record(httptest.NewRequest(http.MethodPost, "/url", nil), storage.AuditCreate, "", "sale", nil, nil)
+}
+
+func TestPlanned(t *testing.T) {
+	activeFrom := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	opts := storage.LinkOptions{RedirectType: http.StatusMovedPermanently, ActiveFrom: &activeFrom}
+
+	activeFromUTC := activeFrom.UTC()
+
+	after := Planned("https://shop.example.com/sale", "", "sale", opts, nil)
+	require.Equal(t, &Link{
+		URL:          "https://shop.example.com/sale",
+		Alias:        "sale",
+		RedirectType: http.StatusMovedPermanently,
+		ActiveFrom:   &activeFromUTC,
+	}, after)
+
+	// the password is kept unless it's changed or removed
+	before := &Link{URL: "https://shop.example.com/sale", Alias: "sale", Password: true}
+	require.True(t, Planned("https://shop.example.com/sale", "", "sale", storage.LinkOptions{}, before).Password)
+	require.False(t, Planned("https://shop.example.com/sale", "", "sale", storage.LinkOptions{RemovePassword: true}, before).Password)
+	require.True(t, Planned("https://shop.example.com/sale", "", "sale", storage.LinkOptions{PasswordHash: "hash"}, nil).Password)
+
+	// so are the options the update leaves out
+	before = &Link{URL: "https://shop.example.com/sale", Alias: "sale", MaxClicks: 10}
+	require.Equal(t, 10, Planned("https://shop.example.com/sale", "", "sale", storage.LinkOptions{Keep: storage.Keep{MaxClicks: true}}, before).MaxClicks)
+	require.Zero(t, Planned("https://shop.example.com/sale", "", "sale", storage.LinkOptions{}, before).MaxClicks)
+
+	before = &Link{URL: "https://shop.example.com/sale", Alias: "sale", RedirectType: http.StatusMovedPermanently}
+	require.Equal(t, http.StatusMovedPermanently, Planned("https://shop.example.com/sale", "", "sale", storage.LinkOptions{Keep: storage.Keep{RedirectType: true}}, before).RedirectType)
+
+	before = &Link{URL: "https://shop.example.com/sale", Alias: "sale", ActiveFrom: &activeFromUTC}
+	require.Equal(t, &activeFromUTC, Planned("https://shop.example.com/sale", "", "sale", storage.LinkOptions{Keep: storage.Keep{ActiveFrom: true}}, before).ActiveFrom)
+	require.Nil(t, Planned("https://shop.example.com/sale", "", "sale", storage.LinkOptions{}, before).ActiveFrom)
+}
+
+func TestEvents(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/url", nil)
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Subject: "alice"}))
+
+	before := &Link{URL: "https://shop.example.com/sale", Alias: "sale"}
+	after := &Link{URL: "https://shop.example.com/sale", Alias: "sale", MaxClicks: 10}
+
+	log := New(slogdiscard.NewDiscardLogger(), &memStore{}, webhooks.NewDispatcher(slogdiscard.NewDiscardLogger(), nil))
+
+	events, err := log.Events(req, storage.AuditUpdate, "", "sale", before, after)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, webhooks.EventLinkUpdated, events[0].Name)
+	require.NotEmpty(t, events[0].ID)
+
+	var payload struct {
+		Event string `json:"event"`
+		Data  Change `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &payload))
+	require.Equal(t, webhooks.EventLinkUpdated, payload.Event)
+	require.Equal(t, Change{Actor: "alice", Alias: "sale", Before: before, After: after}, payload.Data)
+
+	// nothing to queue when webhooks are off
+	events, err = New(slogdiscard.NewDiscardLogger(), &memStore{}, nil).Events(req, storage.AuditUpdate, "", "sale", before, after)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	var nilLog *Log
+	events, err = nilLog.Events(req, storage.AuditDelete, "", "sale", before, nil)
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
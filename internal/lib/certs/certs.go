@@ -0,0 +1,219 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang.org/x/exp/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultReload is how often Watch looks at the files without an interval.
+const defaultReload = time.Minute
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Reloader serves the certificate of a cert and key file pair and swaps it
+// atomically when Watch sees the files change, so certificates can be
+// rotated without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewReloader loads the certificate from certFile and keyFile, both PEM.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	const op = "certs.NewReloader"
+
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.reload(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return r, nil
+}
+
+// GetCertificate is meant for tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// Watch reloads the certificate whenever the modification time of either
+// file changes, checking every interval until ctx is done. A broken pair,
+// like a cert already replaced while the key isn't yet, keeps the previous
+// certificate and is retried on the next check.
+func (r *Reloader) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	const op = "certs.Watch"
+
+	if interval <= 0 {
+		interval = defaultReload
+	}
+
+	log = log.With(slog.String("op", op), slog.String("cert_file", r.certFile))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reload()
+			if err != nil {
+				log.Error("failed to reload certificate", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				log.Info("certificate reloaded", slog.Time("not_after", r.notAfter()))
+			}
+		}
+	}
+}
+
+// reload reads the files if either changed since the last load.
+func (r *Reloader) reload() (bool, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return false, err
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+func (r *Reloader) notAfter() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert.Leaf.NotAfter
+}
+
+// Config builds the server TLS config serving the certificates of r.
+// minVersion is one of 1.0 to 1.3, 1.2 when empty. cipherSuites are names
+// like TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 limiting the TLS 1.0-1.2
+// suites, Go's defaults are used when empty. TLS 1.3 suites can't be
+// configured.
+func Config(r *Reloader, minVersion string, cipherSuites []string) (*tls.Config, error) {
+	const op = "certs.Config"
+
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+
+	if minVersion != "" {
+		version, err := MinVersion(minVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		cfg.MinVersion = version
+	}
+
+	for _, name := range cipherSuites {
+		id, err := CipherSuite(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		cfg.CipherSuites = append(cfg.CipherSuites, id)
+	}
+
+	return cfg, nil
+}
+
+// MinVersion returns the TLS version named one of 1.0 to 1.3.
+func MinVersion(name string) (uint16, error) {
+	version, ok := versions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown min version %q, expected 1.0, 1.1, 1.2 or 1.3", name)
+	}
+
+	return version, nil
+}
+
+// CipherSuite returns the id of a secure TLS 1.0-1.2 suite by name.
+func CipherSuite(name string) (uint16, error) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name != name {
+			continue
+		}
+		for _, version := range suite.SupportedVersions {
+			if version != tls.VersionTLS13 {
+				return suite.ID, nil
+			}
+		}
+		return 0, fmt.Errorf("cipher suite %s is TLS 1.3 only, those are always enabled", name)
+	}
+
+	return 0, fmt.Errorf("unknown or insecure cipher suite %s", name)
+}
+
+// RedirectHandler redirects plain HTTP requests to the same host and path
+// over HTTPS at the port of httpsAddr.
+func RedirectHandler(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
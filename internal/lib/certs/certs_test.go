@@ -0,0 +1,179 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair(t, certFile, keyFile, "first", time.Now().Add(-time.Hour))
+
+	r, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	require.Equal(t, "first", commonName(t, r))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, slogdiscard.NewDiscardLogger(), time.Millisecond)
+
+	// a cert without its key keeps the previous pair
+	otherDir := t.TempDir()
+	writePair(t, filepath.Join(otherDir, "cert.pem"), filepath.Join(otherDir, "key.pem"), "second", time.Now())
+	copyFile(t, filepath.Join(otherDir, "cert.pem"), certFile)
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, "first", commonName(t, r))
+
+	copyFile(t, filepath.Join(otherDir, "key.pem"), keyFile)
+	require.Eventually(t, func() bool {
+		return commonName(t, r) == "second"
+	}, time.Second, time.Millisecond)
+}
+
+func TestNewReloader(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	require.Error(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	cases := []struct {
+		name         string
+		minVersion   string
+		cipherSuites []string
+		wantVersion  uint16
+		wantSuites   []uint16
+		wantErr      string
+	}{
+		{
+			name:        "defaults",
+			wantVersion: tls.VersionTLS12,
+		},
+		{
+			name:         "suites",
+			minVersion:   "1.3",
+			cipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"},
+			wantVersion:  tls.VersionTLS13,
+			wantSuites:   []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256},
+		},
+		{
+			name:       "unknown version",
+			minVersion: "1.4",
+			wantErr:    `certs.Config : unknown min version "1.4", expected 1.0, 1.1, 1.2 or 1.3`,
+		},
+		{
+			name:         "insecure suite",
+			cipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+			wantErr:      "certs.Config : unknown or insecure cipher suite TLS_RSA_WITH_RC4_128_SHA",
+		},
+		{
+			name:         "tls 1.3 suite",
+			cipherSuites: []string{"TLS_AES_128_GCM_SHA256"},
+			wantErr:      "certs.Config : cipher suite TLS_AES_128_GCM_SHA256 is TLS 1.3 only, those are always enabled",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Config(&Reloader{}, tc.minVersion, tc.cipherSuites)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.wantVersion, cfg.MinVersion)
+			require.Equal(t, tc.wantSuites, cfg.CipherSuites)
+			require.NotNil(t, cfg.GetCertificate)
+		})
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		httpsAddr string
+		host      string
+		target    string
+	}{
+		{name: "default port", httpsAddr: ":443", host: "sho.rt", target: "https://sho.rt/abc?x=1"},
+		{name: "other port", httpsAddr: "0.0.0.0:8443", host: "sho.rt:8080", target: "https://sho.rt:8443/abc?x=1"},
+		{name: "ipv6", httpsAddr: ":443", host: "[::1]:8080", target: "https://[::1]/abc?x=1"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/abc?x=1", nil)
+			req.Host = tc.host
+			rr := httptest.NewRecorder()
+
+			RedirectHandler(tc.httpsAddr).ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusPermanentRedirect, rr.Code)
+			require.Equal(t, tc.target, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+
+	return cert.Leaf.Subject.CommonName
+}
+
+// writePair writes a self-signed certificate and its key, modified at modTime.
+func writePair(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+
+	data, err := os.ReadFile(from)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(to, data, 0o600))
+}
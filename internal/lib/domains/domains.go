@@ -0,0 +1,141 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Default is the domain of links on the primary host of the service.
+const Default = ""
+
+var ErrUnknownDomain = errors.New("unknown domain")
+
+type ctxKey struct{}
+
+// Registry holds the short domains served next to the primary host. Each
+// domain is a separate alias namespace, the primary host uses Default.
+type Registry struct {
+	scheme  string
+	primary string
+	domains map[string]bool
+}
+
+// New builds a registry from the base url of the service and the extra
+// domains. A nil *Registry only knows the Default domain.
+func New(baseURL string, domains []string) *Registry {
+	r := &Registry{scheme: "http", domains: make(map[string]bool, len(domains))}
+
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		r.scheme = u.Scheme
+		r.primary = normalize(u.Host)
+	}
+
+	for _, d := range domains {
+		if d = normalize(d); d != "" && d != r.primary {
+			r.domains[d] = true
+		}
+	}
+
+	return r
+}
+
+// Resolve maps the Host header of a request to its domain, unknown hosts
+// fall back to Default.
+func (r *Registry) Resolve(host string) string {
+	if r == nil {
+		return Default
+	}
+
+	if d := normalize(host); r.domains[d] {
+		return d
+	}
+
+	return Default
+}
+
+// Lookup validates a domain given through the API, the primary host and an
+// empty string both mean Default.
+func (r *Registry) Lookup(domain string) (string, error) {
+	d := normalize(domain)
+	if d == "" {
+		return Default, nil
+	}
+
+	if r == nil {
+		return "", ErrUnknownDomain
+	}
+
+	if d == r.primary {
+		return Default, nil
+	}
+	if !r.domains[d] {
+		return "", ErrUnknownDomain
+	}
+
+	return d, nil
+}
+
+// Own reports whether host is served by the service and returns its domain.
+func (r *Registry) Own(host string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+
+	d := normalize(host)
+	if d != "" && d == r.primary {
+		return Default, true
+	}
+	if r.domains[d] {
+		return d, true
+	}
+
+	return "", false
+}
+
+// BaseURL returns the url short links of domain start with, baseURL is used
+// for Default.
+func (r *Registry) BaseURL(domain, baseURL string) string {
+	if r == nil || domain == Default {
+		return baseURL
+	}
+
+	return r.scheme + "://" + domain
+}
+
+// Middleware stores the domain of the request host in the request context.
+func (r *Registry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), ctxKey{}, r.Resolve(req.Host))
+
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// FromContext returns the domain stored by Middleware, Default without it.
+func FromContext(ctx context.Context) string {
+	d, _ := ctx.Value(ctxKey{}).(string)
+
+	return d
+}
+
+// SameHost reports whether a and b name the same host, ignoring the port,
+// the case and the trailing dot. An empty host matches nothing.
+func SameHost(a, b string) bool {
+	a = normalize(a)
+
+	return a != "" && a == normalize(b)
+}
+
+// normalize lower-cases a host and strips the port and the trailing dot.
+func normalize(host string) string {
+	host = strings.TrimSpace(strings.ToLower(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,105 @@
+package domains
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	registry := New("https://sho.rt", []string{"Go.Brand-A.com", "go.brand-b.com."})
+
+	tests := map[string]string{
+		"sho.rt":              Default,
+		"go.brand-a.com":      "go.brand-a.com",
+		"GO.BRAND-A.COM:8080": "go.brand-a.com",
+		"go.brand-b.com":      "go.brand-b.com",
+		"go.brand-c.com":      Default,
+		"":                    Default,
+	}
+
+	for host, domain := range tests {
+		require.Equal(t, domain, registry.Resolve(host), host)
+	}
+
+	require.Equal(t, Default, (*Registry)(nil).Resolve("go.brand-a.com"))
+}
+
+func TestLookup(t *testing.T) {
+	registry := New("https://sho.rt", []string{"go.brand-a.com"})
+
+	tests := map[string]struct {
+		domain string
+		err    error
+	}{
+		"":               {domain: Default},
+		"sho.rt":         {domain: Default},
+		"go.brand-a.com": {domain: "go.brand-a.com"},
+		"Go.Brand-A.com": {domain: "go.brand-a.com"},
+		"go.brand-c.com": {err: ErrUnknownDomain},
+	}
+
+	for input, tc := range tests {
+		domain, err := registry.Lookup(input)
+		require.ErrorIs(t, err, tc.err, input)
+		require.Equal(t, tc.domain, domain, input)
+	}
+
+	_, err := (*Registry)(nil).Lookup("go.brand-a.com")
+	require.ErrorIs(t, err, ErrUnknownDomain)
+}
+
+func TestOwn(t *testing.T) {
+	registry := New("https://sho.rt", []string{"go.brand-a.com"})
+
+	tests := map[string]struct {
+		domain string
+		own    bool
+	}{
+		"sho.rt":           {domain: Default, own: true},
+		"SHO.RT:443":       {domain: Default, own: true},
+		"go.brand-a.com":   {domain: "go.brand-a.com", own: true},
+		"go.brand-c.com":   {},
+		"":                 {},
+		"shop.example.com": {},
+	}
+
+	for host, tc := range tests {
+		domain, own := registry.Own(host)
+		require.Equal(t, tc.own, own, host)
+		require.Equal(t, tc.domain, domain, host)
+	}
+
+	_, own := (*Registry)(nil).Own("sho.rt")
+	require.False(t, own)
+}
+
+func TestSameHost(t *testing.T) {
+	require.True(t, SameHost("sho.rt", "SHO.RT:8082"))
+	require.True(t, SameHost("sho.rt.", "sho.rt"))
+	require.False(t, SameHost("sho.rt", "go.brand-a.com"))
+	require.False(t, SameHost("", ""))
+}
+
+func TestBaseURL(t *testing.T) {
+	registry := New("https://sho.rt", []string{"go.brand-a.com"})
+
+	require.Equal(t, "https://sho.rt", registry.BaseURL(Default, "https://sho.rt"))
+	require.Equal(t, "https://go.brand-a.com", registry.BaseURL("go.brand-a.com", "https://sho.rt"))
+}
+
+func TestMiddleware(t *testing.T) {
+	registry := New("https://sho.rt", []string{"go.brand-a.com"})
+
+	var domain string
+	handler := registry.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		domain = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/sale", nil)
+	req.Host = "go.brand-a.com"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, "go.brand-a.com", domain)
+}
@@ -0,0 +1,161 @@
+package loops
+
+import (
+	"context"
+	"errors"
+	"golang-url-shortener/internal/lib/domains"
+	"golang-url-shortener/internal/storage"
+	"net/url"
+	"strings"
+)
+
+const defaultMaxDepth = 3
+
+var (
+	ErrSelfReference = errors.New("it points to the link itself")
+	ErrLoop          = errors.New("it creates a redirect loop")
+	ErrTooDeep       = errors.New("it chains too many short links")
+)
+
+// Error is returned by Check for a url that must not be saved.
+type Error struct {
+	URL string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return "url " + e.URL + " is not allowed: " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type LinkGetter interface {
+	GetLink(ctx context.Context, domain, alias string) (storage.Link, error)
+}
+
+// Detector follows urls pointing back to the service through the stored
+// links. A nil *Detector accepts everything.
+type Detector struct {
+	registry *domains.Registry
+	links    LinkGetter
+	maxDepth int
+}
+
+// New builds a Detector allowing chains of up to maxDepth short links,
+// 3 when it's not positive.
+func New(registry *domains.Registry, links LinkGetter, maxDepth int) *Detector {
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+
+	return &Detector{registry: registry, links: links, maxDepth: maxDepth}
+}
+
+type ref struct {
+	domain string
+	alias  string
+}
+
+// Check returns an *Error when one of urls, saved for the aliases of domain,
+// would point back to the link or go through more than maxDepth short links.
+// Every url of the links on the way is followed, not only the main one.
+// Urls on host, the Host of the request, belong to the service too, so loops
+// are caught even when the base url doesn't name the primary host.
+func (d *Detector) Check(ctx context.Context, host, domain string, aliases []string, urls []string) error {
+	if d == nil {
+		return nil
+	}
+
+	self := make(map[ref]bool, len(aliases))
+	for _, alias := range aliases {
+		self[ref{domain: domain, alias: alias}] = true
+	}
+
+	for _, rawURL := range urls {
+		if err := d.follow(ctx, host, rawURL, 0, self, make(map[ref]bool)); err != nil {
+			var loopErr *Error
+			if errors.As(err, &loopErr) {
+				// report the url of the request, not the one deep in the chain
+				loopErr.URL = rawURL
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Detector) follow(ctx context.Context, host, rawURL string, depth int, self, visited map[ref]bool) error {
+	target, ok := d.resolve(host, rawURL)
+	if !ok {
+		return nil
+	}
+
+	if self[target] {
+		if depth == 0 {
+			return &Error{URL: rawURL, Err: ErrSelfReference}
+		}
+		return &Error{URL: rawURL, Err: ErrLoop}
+	}
+	if visited[target] {
+		return &Error{URL: rawURL, Err: ErrLoop}
+	}
+
+	link, err := d.links.GetLink(ctx, target.domain, target.alias)
+	if errors.Is(err, storage.ErrUrlNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if depth >= d.maxDepth {
+		return &Error{URL: rawURL, Err: ErrTooDeep}
+	}
+
+	visited[target] = true
+	defer delete(visited, target)
+
+	for _, next := range link.URLs() {
+		if err := d.follow(ctx, host, next, depth+1, self, visited); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve maps a url on one of the short domains or on host to the link it
+// redirects through.
+func (d *Detector) resolve(host, rawURL string) (ref, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ref{}, false
+	}
+
+	domain, ok := d.registry.Own(u.Host)
+	if !ok && domains.SameHost(u.Host, host) {
+		domain, ok = d.registry.Resolve(host), true
+	}
+	if !ok {
+		return ref{}, false
+	}
+
+	// URLFormat serves /alias.json as /alias, it drops the extension of the
+	// last segment before routing
+	p := u.Path
+	if i := strings.LastIndex(p, "."); i > strings.LastIndex(p, "/") {
+		p = p[:i]
+	}
+
+	// /alias, /alias/rest/of/path and the /alias+ preview all belong to alias
+	alias, _, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	alias = strings.TrimSuffix(alias, "+")
+	if alias == "" {
+		return ref{}, false
+	}
+
+	return ref{domain: domain, alias: alias}, true
+}
@@ -0,0 +1,178 @@
+package loops
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/lib/domains"
+	"golang-url-shortener/internal/storage"
+	"testing"
+)
+
+type links map[string]storage.Link
+
+func (l links) GetLink(_ context.Context, domain, alias string) (storage.Link, error) {
+	link, ok := l[domain+"/"+alias]
+	if !ok {
+		return storage.Link{}, storage.ErrUrlNotFound
+	}
+
+	return link, nil
+}
+
+func TestCheck(t *testing.T) {
+	registry := domains.New("https://sho.rt", []string{"go.brand-a.com"})
+
+	stored := links{
+		"/a": {URL: "https://sho.rt/b"},
+		"/b": {URL: "https://sho.rt/c"},
+		"/c": {URL: "https://example.com"},
+		"/d": {URL: "https://sho.rt/a"},
+		"/split": {URL: "https://example.com", LinkOptions: storage.LinkOptions{
+			Destinations: []storage.Destination{{URL: "https://example.com/a"}, {URL: "https://go.brand-a.com/promo"}},
+		}},
+		"/bad":                 {URL: "https://sho.rt/bad2"},
+		"/bad2":                {URL: "https://sho.rt/bad"},
+		"/ext":                 {URL: "https://sho.rt/x.html"},
+		"/plain":               {URL: "https://example.com"},
+		"go.brand-a.com/promo": {URL: "https://sho.rt/x"},
+	}
+
+	tests := []struct {
+		name    string
+		host    string
+		domain  string
+		aliases []string
+		urls    []string
+		err     error
+	}{
+		{
+			name:    "external url",
+			aliases: []string{"x"},
+			urls:    []string{"https://example.com/x"},
+		},
+		{
+			name:    "itself",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/x"},
+			err:     ErrSelfReference,
+		},
+		{
+			name:    "itself with path and preview",
+			aliases: []string{"x"},
+			urls:    []string{"https://example.com", "https://SHO.RT/x+"},
+			err:     ErrSelfReference,
+		},
+		{
+			name:    "same alias on another domain",
+			domain:  "go.brand-a.com",
+			aliases: []string{"plain"},
+			urls:    []string{"https://sho.rt/plain"},
+		},
+		{
+			name:    "unknown alias",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/missing"},
+		},
+		{
+			name:    "chain within the limit",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/a"},
+		},
+		{
+			name:    "chain over the limit",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/d"},
+			err:     ErrTooDeep,
+		},
+		{
+			name:    "loop back through a chain",
+			aliases: []string{"c"},
+			urls:    []string{"https://sho.rt/a"},
+			err:     ErrLoop,
+		},
+		{
+			name:    "loop through a destination on another domain",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/split"},
+			err:     ErrLoop,
+		},
+		{
+			name:    "stored loop",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/bad"},
+			err:     ErrLoop,
+		},
+		{
+			name:    "itself on the request host",
+			host:    "links.internal:8082",
+			aliases: []string{"x"},
+			urls:    []string{"http://links.internal/x"},
+			err:     ErrSelfReference,
+		},
+		{
+			name:    "itself with an extension",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/x.json"},
+			err:     ErrSelfReference,
+		},
+		{
+			name:    "loop back through an extension",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/ext"},
+			err:     ErrLoop,
+		},
+		{
+			name:    "extension of an inner segment",
+			aliases: []string{"x"},
+			urls:    []string{"https://sho.rt/x.v2/page"},
+		},
+		{
+			name:    "old alias of an update",
+			aliases: []string{"old", "new"},
+			urls:    []string{"https://sho.rt/old"},
+			err:     ErrSelfReference,
+		},
+	}
+
+	detector := New(registry, stored, 3)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := detector.Check(context.Background(), tc.host, tc.domain, tc.aliases, tc.urls)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tc.err)
+
+			var loopErr *Error
+			require.True(t, errors.As(err, &loopErr))
+			require.Contains(t, tc.urls, loopErr.URL)
+		})
+	}
+
+	require.NoError(t, (*Detector)(nil).Check(context.Background(), "sho.rt", "", []string{"x"}, []string{"https://sho.rt/x"}))
+}
+
+func TestCheckWithoutBaseURL(t *testing.T) {
+	registry := domains.New("", []string{"go.brand-a.com"})
+
+	stored := links{
+		"/a": {URL: "https://sho.rt/b"},
+		"/b": {URL: "https://sho.rt/x"},
+	}
+
+	detector := New(registry, stored, 3)
+
+	err := detector.Check(context.Background(), "sho.rt", "", []string{"x"}, []string{"https://sho.rt/x"})
+	require.ErrorIs(t, err, ErrSelfReference)
+
+	err = detector.Check(context.Background(), "sho.rt", "", []string{"x"}, []string{"https://sho.rt/a"})
+	require.ErrorIs(t, err, ErrLoop)
+
+	// without the request host the primary host is unknown
+	err = detector.Check(context.Background(), "", "", []string{"x"}, []string{"https://sho.rt/x"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "url_shortener"
+
+// DefaultPath serves the metrics when the config has no path.
+const DefaultPath = "/metrics"
+
+// Results of redirects.
+const (
+	RedirectHit  = "hit"
+	RedirectMiss = "miss"
+)
+
+// Kinds of aliases in collisions.
+const (
+	AliasGenerated = "generated"
+	AliasCustom    = "custom"
+)
+
+// The collectors are registered in the default registry, next to its Go
+// runtime and process collectors.
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method, route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	redirects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Redirects by result, a miss is an alias without a link.",
+	}, []string{"result"})
+
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of storage operations by method.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"method"})
+
+	aliasCollisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alias_collisions_total",
+		Help:      "Saves rejected because the alias was taken, by kind of alias.",
+	}, []string{"alias"})
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware counts the requests and observes their latency. Requests are
+// labelled by the chi route pattern rather than the path, so aliases don't
+// make a series each.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+		requests.With(labels).Inc()
+		requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Redirect counts a redirect with the result, RedirectHit or RedirectMiss.
+func Redirect(result string) {
+	redirects.WithLabelValues(result).Inc()
+}
+
+// AliasCollision counts a save rejected because the alias exists.
+func AliasCollision(generated bool) {
+	kind := AliasCustom
+	if generated {
+		kind = AliasGenerated
+	}
+
+	aliasCollisions.WithLabelValues(kind).Inc()
+}
+
+// Storage starts timing the storage method, the returned func observes the
+// duration:
+//
+//	defer metrics.Storage("GetLink")()
+func Storage(method string) func() {
+	start := time.Now()
+
+	return func() {
+		storageDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	}
+}
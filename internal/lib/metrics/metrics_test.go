@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	})
+	router.Route("/url", func(r chi.Router) {
+		r.Delete("/{alias}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	for _, path := range []string{"/abc", "/xyz"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/url/abc", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a/b/c", nil))
+
+	// aliases share the series of their route
+	require.Equal(t, 2.0, testutil.ToFloat64(requests.WithLabelValues(http.MethodGet, "/{alias}", "302")))
+	require.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues(http.MethodDelete, "/url/{alias}", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues(http.MethodGet, "unmatched", "404")))
+	require.Equal(t, 3, testutil.CollectAndCount(requestDuration, "url_shortener_http_request_duration_seconds"))
+}
+
+func TestHandler(t *testing.T) {
+	Redirect(RedirectHit)
+	Redirect(RedirectMiss)
+	Redirect(RedirectHit)
+	AliasCollision(true)
+	Storage("GetLink")()
+
+	require.Equal(t, 2.0, testutil.ToFloat64(redirects.WithLabelValues(RedirectHit)))
+	require.Equal(t, 1.0, testutil.ToFloat64(aliasCollisions.WithLabelValues(AliasGenerated)))
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, DefaultPath, nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	for _, metric := range []string{
+		`url_shortener_redirects_total{result="miss"} 1`,
+		`url_shortener_alias_collisions_total{alias="generated"} 1`,
+		`url_shortener_storage_operation_duration_seconds_count{method="GetLink"} 1`,
+		"go_goroutines",
+	} {
+		require.True(t, strings.Contains(body, metric), metric)
+	}
+}
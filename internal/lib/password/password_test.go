@@ -0,0 +1,16 @@
+package password
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	hash, err := Hash("secret")
+	require.NoError(t, err)
+	require.NotEqual(t, "secret", hash)
+
+	require.True(t, Verify(hash, "secret"))
+	require.False(t, Verify(hash, "Secret"))
+	require.False(t, Verify("", "secret"))
+}
@@ -0,0 +1,124 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+var (
+	ErrInvalidLevel  = errors.New("invalid error correction level")
+	ErrInvalidFormat = errors.New("invalid format")
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+type Options struct {
+	// Size is the width and height of the image in pixels.
+	Size int
+	// Level is the error correction level: L, M, Q or H.
+	Level string
+	// Margin is the quiet zone around the code in modules.
+	Margin int
+	Format string
+}
+
+func Encode(content string, opts Options) ([]byte, error) {
+	const op = "lib.qr.Encode"
+
+	level, ok := levels[strings.ToUpper(opts.Level)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrInvalidLevel, opts.Level)
+	}
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	code.DisableBorder = true
+
+	bitmap := code.Bitmap()
+
+	switch opts.Format {
+	case FormatPNG:
+		return encodePNG(bitmap, opts.Size, opts.Margin)
+	case FormatSVG:
+		return encodeSVG(bitmap, opts.Size, opts.Margin), nil
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrInvalidFormat, opts.Format)
+	}
+}
+
+// encodePNG scales modules by a whole number of pixels to keep edges sharp and
+// centers the code when size is not a multiple of the module count.
+func encodePNG(bitmap [][]bool, size, margin int) ([]byte, error) {
+	modules := len(bitmap) + 2*margin
+
+	scale := size / modules
+	if scale < 1 {
+		scale = 1
+	}
+	if size < modules*scale {
+		size = modules * scale
+	}
+	offset := (size-modules*scale)/2 + margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeSVG(bitmap [][]bool, size, margin int) []byte {
+	modules := len(bitmap) + 2*margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modules, modules)
+
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+margin, y+margin)
+			}
+		}
+	}
+
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
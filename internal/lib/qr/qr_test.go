@@ -0,0 +1,48 @@
+package qr
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := map[string]struct {
+		opts     Options
+		wantSize int
+		err      error
+	}{
+		"png": {opts: Options{Size: 256, Level: "M", Margin: 4, Format: FormatPNG}, wantSize: 256},
+		// too small size falls back to one pixel per module, the content needs a 25x25 code
+		"png below minimum": {opts: Options{Size: 10, Level: "L", Margin: 0, Format: FormatPNG}, wantSize: 25},
+		"svg":               {opts: Options{Size: 300, Level: "h", Margin: 2, Format: FormatSVG}},
+		"invalid level":     {opts: Options{Size: 256, Level: "X", Format: FormatPNG}, err: ErrInvalidLevel},
+		"invalid format":    {opts: Options{Size: 256, Level: "M", Format: "gif"}, err: ErrInvalidFormat},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := Encode("https://sho.rt/abc", tc.opts)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.opts.Format == FormatSVG {
+				svg := string(data)
+				require.True(t, strings.HasPrefix(svg, "<svg "))
+				require.Contains(t, svg, `width="300"`)
+				require.True(t, strings.HasSuffix(svg, "</svg>"))
+				return
+			}
+
+			img, err := png.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, tc.wantSize, img.Bounds().Dx())
+			require.Equal(t, tc.wantSize, img.Bounds().Dy())
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package redirecttype
+
+import "net/http"
+
+// Valid reports whether code can be used as the redirect status of a link.
+func Valid(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,22 @@
+package redirecttype
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for code, valid := range map[int]bool{
+		http.StatusMovedPermanently:  true,
+		http.StatusFound:             true,
+		http.StatusSeeOther:          true,
+		http.StatusTemporaryRedirect: true,
+		http.StatusPermanentRedirect: true,
+		http.StatusOK:                false,
+		http.StatusNotModified:       false,
+		0:                            false,
+	} {
+		require.Equal(t, valid, Valid(code), code)
+	}
+}
@@ -0,0 +1,60 @@
+package reserved
+
+import (
+	"github.com/go-playground/validator"
+	"path"
+	"strings"
+)
+
+// Tag is the validation tag rejecting reserved aliases.
+const Tag = "notreserved"
+
+// Aliases are the first path segments taken by the routes of the service. A
+// link with one of them as alias would never be reached, the route wins over
+// /{alias}. A nil *Aliases reserves nothing.
+type Aliases struct {
+	names map[string]bool
+}
+
+// New reserves the first segment of each of routes.
+func New(routes ...string) *Aliases {
+	a := &Aliases{names: make(map[string]bool, len(routes))}
+	for _, route := range routes {
+		a.Add(route)
+	}
+
+	return a
+}
+
+// Add reserves the first segment of route, parameters and catch-alls are
+// left to the links.
+func (a *Aliases) Add(route string) {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+	if segment == "" || strings.ContainsAny(segment, "{*") {
+		return
+	}
+
+	a.names[segment] = true
+}
+
+// Contains reports whether alias is taken by a route. URLFormat routes
+// /docs.json as /docs, so the extension is ignored.
+func (a *Aliases) Contains(alias string) bool {
+	if a == nil {
+		return false
+	}
+
+	return a.names[alias] || a.names[strings.TrimSuffix(alias, path.Ext(alias))]
+}
+
+// Validator returns a validator knowing Tag.
+func (a *Aliases) Validator() *validator.Validate {
+	v := validator.New()
+	if err := v.RegisterValidation(Tag, func(fl validator.FieldLevel) bool {
+		return !a.Contains(fl.Field().String())
+	}); err != nil {
+		panic(err)
+	}
+
+	return v
+}
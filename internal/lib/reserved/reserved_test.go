@@ -0,0 +1,38 @@
+package reserved
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestContains(t *testing.T) {
+	aliases := New("/docs", "/url/*", "/admin/keys/{id}", "/{alias}", "/{alias}+", "/")
+
+	for alias, taken := range map[string]bool{
+		"docs":      true,
+		"docs.json": true,
+		"url":       true,
+		"admin":     true,
+		"keys":      false,
+		"{alias}":   false,
+		"Docs":      false,
+		"docs2":     false,
+		"":          false,
+	} {
+		require.Equal(t, taken, aliases.Contains(alias), alias)
+	}
+
+	require.False(t, (*Aliases)(nil).Contains("docs"))
+}
+
+func TestValidator(t *testing.T) {
+	type request struct {
+		Alias string `validate:"omitempty,notreserved"`
+	}
+
+	v := New("/docs").Validator()
+
+	require.NoError(t, v.Struct(request{Alias: "promo"}))
+	require.NoError(t, v.Struct(request{}))
+	require.Error(t, v.Struct(request{Alias: "docs"}))
+}
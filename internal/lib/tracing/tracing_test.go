@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	traceparent = "00-" + traceID + "-00f067aa0ba902b7-01"
+)
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := record(t)
+
+	var handlerTraceID string
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "sqlite.GetLink")
+		span.End()
+
+		handlerTraceID = TraceID(r.Context())
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/sale", nil)
+	req.Header.Set("traceparent", traceparent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the trace of the caller continues
+	require.Equal(t, traceID, handlerTraceID)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	storageSpan, serverSpan := spans[0], spans[1]
+	require.Equal(t, "GET /{alias}", serverSpan.Name())
+	require.Equal(t, traceID, serverSpan.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	require.Equal(t, "Error", serverSpan.Status().Code.String())
+	require.Equal(t, serverSpan.SpanContext().SpanID(), storageSpan.Parent().SpanID())
+
+	// a request without a traceparent starts a trace
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sale", nil))
+	require.NotEmpty(t, handlerTraceID)
+	require.NotEqual(t, traceID, handlerTraceID)
+}
+
+func TestInject(t *testing.T) {
+	record(t)
+
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(),
+		propagation.HeaderCarrier(http.Header{"Traceparent": {traceparent}}))
+	ctx, span := Start(ctx, "webhooks.deliver")
+	defer span.End()
+
+	header := http.Header{}
+	Inject(ctx, header)
+
+	require.Contains(t, header.Get("traceparent"), traceID)
+	require.Empty(t, TraceID(context.Background()))
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	_, err := Setup(context.Background(), Options{Exporter: "zipkin"})
+	require.Error(t, err)
+
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterStdout, Writer: &out})
+	require.NoError(t, err)
+
+	_, span := Start(context.Background(), "sqlite.SaveURL")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	require.Contains(t, out.String(), `"Name":"sqlite.SaveURL"`)
+	require.Contains(t, out.String(), `"Value":"url-shortener"`)
+}
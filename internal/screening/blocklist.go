@@ -0,0 +1,71 @@
+package screening
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+type blocklist struct {
+	exact     map[string]bool
+	wildcards []string
+}
+
+// parseBlocklist reads one domain pattern per line, blank lines and lines
+// starting with # are skipped. A pattern without wildcards blocks the domain
+// and its subdomains, patterns with * or ? are matched against the whole host,
+// so *.example.com blocks the subdomains but not example.com itself.
+func parseBlocklist(r io.Reader) (*blocklist, error) {
+	list := &blocklist{exact: make(map[string]bool)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		pattern := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		pattern = strings.TrimSuffix(pattern, ".")
+
+		if !strings.ContainsAny(pattern, "*?[") {
+			list.exact[pattern] = true
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %w", line, pattern, err)
+		}
+		list.wildcards = append(list.wildcards, pattern)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// match returns the pattern blocking host.
+func (l *blocklist) match(host string) (string, bool) {
+	for domain := host; domain != ""; {
+		if l.exact[domain] {
+			return domain, true
+		}
+
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+
+	for _, pattern := range l.wildcards {
+		// hosts have no slashes, so * matches across labels
+		if ok, _ := path.Match(pattern, host); ok {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
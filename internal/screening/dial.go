@@ -0,0 +1,47 @@
+package screening
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when a Transport refuses to connect.
+var ErrPrivateAddress = errors.New("private network addresses are not allowed")
+
+// Transport returns an http transport refusing to connect to private network
+// addresses. The address is checked once resolved, so names pointing at
+// private hosts are refused too, not only the ones Check can tell from the
+// url. Proxies from the environment are not used, the address behind them
+// couldn't be checked.
+func Transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refusePrivate,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
+
+// refusePrivate is the Control of the dialer of Transport, address is the
+// resolved ip and port about to be connected to.
+func refusePrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+
+	return nil
+}
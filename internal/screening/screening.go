@@ -0,0 +1,236 @@
+package screening
+
+import (
+	"context"
+	"fmt"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang.org/x/exp/slog"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultReload is how often Watch looks at the blocklist without an interval.
+const defaultReload = 30 * time.Second
+
+// RejectedError is returned by Check for destinations that must not be stored
+// or redirected to, Reason is safe to show to the client.
+type RejectedError struct {
+	URL    string
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "url " + e.URL + " is not allowed: " + e.Reason
+}
+
+// Config configures a Screener.
+type Config struct {
+	// AllowedSchemes of destinations, http and https when empty.
+	AllowedSchemes []string
+	// BlocklistPath is a file with one domain pattern per line, see parseBlocklist.
+	BlocklistPath string
+	// AllowIPHosts allows public IP literals as hosts, private ones are always rejected.
+	AllowIPHosts bool
+}
+
+// Screener checks destinations against the configured rules. The blocklist is
+// swapped atomically by Watch, so Check can be called concurrently. A nil
+// *Screener accepts everything.
+type Screener struct {
+	schemes      map[string]bool
+	allowIPHosts bool
+	path         string
+
+	mu        sync.RWMutex
+	blocklist *blocklist
+	modTime   time.Time
+}
+
+// New builds a Screener and loads the blocklist when a path is configured.
+func New(cfg Config) (*Screener, error) {
+	const op = "screening.New"
+
+	schemes := cfg.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+
+	s := &Screener{
+		schemes:      make(map[string]bool, len(schemes)),
+		allowIPHosts: cfg.AllowIPHosts,
+		path:         cfg.BlocklistPath,
+		blocklist:    &blocklist{},
+	}
+	for _, scheme := range schemes {
+		s.schemes[strings.ToLower(scheme)] = true
+	}
+
+	if s.path != "" {
+		if _, err := s.reload(); err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Check returns a *RejectedError for the first of rawURLs that is not an
+// acceptable destination.
+func (s *Screener) Check(rawURLs ...string) error {
+	if s == nil {
+		return nil
+	}
+
+	for _, rawURL := range rawURLs {
+		if reason := s.reject(rawURL); reason != "" {
+			return &RejectedError{URL: rawURL, Reason: reason}
+		}
+	}
+
+	return nil
+}
+
+// reject returns why rawURL is rejected, an empty string when it's accepted.
+func (s *Screener) reject(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "url can't be parsed"
+	}
+
+	if !s.schemes[strings.ToLower(u.Scheme)] {
+		return fmt.Sprintf("scheme %q is not allowed", u.Scheme)
+	}
+
+	if u.User != nil {
+		return "credentials in the url are not allowed"
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return "host is missing"
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if isPrivate(ip) {
+			return "private network addresses are not allowed"
+		}
+		if !s.allowIPHosts {
+			return "ip address hosts are not allowed"
+		}
+		return ""
+	}
+
+	if isLocalName(host) {
+		return "private network addresses are not allowed"
+	}
+
+	s.mu.RLock()
+	pattern, blocked := s.blocklist.match(host)
+	s.mu.RUnlock()
+
+	if blocked {
+		return fmt.Sprintf("domain %s is blocklisted by %s", host, pattern)
+	}
+
+	return ""
+}
+
+// Watch reloads the blocklist whenever its modification time changes,
+// checking every interval until ctx is done. A broken file keeps the
+// previous list in place.
+func (s *Screener) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	const op = "screening.Watch"
+
+	if s == nil || s.path == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultReload
+	}
+
+	log = log.With(slog.String("op", op), slog.String("path", s.path))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := s.reload()
+			if err != nil {
+				log.Error("failed to reload blocklist", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				log.Info("blocklist reloaded", slog.Int("patterns", s.size()))
+			}
+		}
+	}
+}
+
+// reload reads the blocklist file if it changed since the last load.
+func (s *Screener) reload() (bool, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	list, err := parseBlocklist(f)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.blocklist = list
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+
+	return true, nil
+}
+
+func (s *Screener) size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.blocklist.exact) + len(s.blocklist.wildcards)
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast()
+}
+
+// isLocalName reports whether host is a name that only resolves inside a network.
+func isLocalName(host string) bool {
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".lan", ".home.arpa"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
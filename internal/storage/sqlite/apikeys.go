@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"golang-url-shortener/internal/storage"
+	"strings"
+	"time"
+)
+
+const apiKeyColumns = "id, name, prefix, scopes, created_at, expires_at, last_used_at, revoked_at"
+
+// CreateAPIKey stores a key by its hash and returns its id.
+func (s *Storage) CreateAPIKey(ctx context.Context, key storage.APIKey, hash string) (int64, error) {
+	const op = "storage.sqlite.CreateAPIKey"
+	ctx, done := track(ctx, "CreateAPIKey")
+	defer done()
+
+	stmt, err := s.db.PrepareContext(ctx, `
+	INSERT INTO api_key (name, prefix, key_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, key.Name, key.Prefix, hash, strings.Join(key.Scopes, " "),
+		key.CreatedAt.UTC(), utcOrNil(key.ExpiresAt))
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetAPIKey finds a key by its hash, revoked and expired keys included.
+func (s *Storage) GetAPIKey(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.sqlite.GetAPIKey"
+	ctx, done := track(ctx, "GetAPIKey")
+	defer done()
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT "+apiKeyColumns+" FROM api_key WHERE key_hash = ?")
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s : %w", op, err)
+	}
+
+	key, err := scanAPIKey(stmt.QueryRowContext(ctx, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, storage.ErrKeyNotFound
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	const op = "storage.sqlite.ListAPIKeys"
+	ctx, done := track(ctx, "ListAPIKeys")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_key ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []storage.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// TouchAPIKey records when the key was last used.
+func (s *Storage) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	const op = "storage.sqlite.TouchAPIKey"
+	ctx, done := track(ctx, "TouchAPIKey")
+	defer done()
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE api_key SET last_used_at = ? WHERE id = ?", usedAt.UTC(), id); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeAPIKey disables a key for good, revoking it again is a no-op.
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+	ctx, done := track(ctx, "RevokeAPIKey")
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE api_key SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+func scanAPIKey(row scanner) (storage.APIKey, error) {
+	var (
+		key        storage.APIKey
+		scopes     string
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+		revokedAt  sql.NullTime
+	)
+
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return storage.APIKey{}, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return key, nil
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"golang-url-shortener/internal/storage"
+	"strings"
+)
+
+const (
+	auditColumns = "id, actor, action, domain, alias, before_value, after_value, request_id, remote_addr, created_at"
+
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+func (s *Storage) RecordAudit(ctx context.Context, entry storage.AuditEntry) error {
+	const op = "storage.sqlite.RecordAudit"
+	ctx, done := track(ctx, "RecordAudit")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx, `
+	INSERT INTO audit_log (actor, action, domain, alias, before_value, after_value, request_id, remote_addr, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Actor, entry.Action, entry.Domain, entry.Alias, entry.Before, entry.After,
+		entry.RequestID, entry.RemoteAddr, entry.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+// AuditLog returns the entries matching filter, newest first. The limit is
+// 50 by default and at most 500.
+func (s *Storage) AuditLog(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	const op = "storage.sqlite.AuditLog"
+	ctx, done := track(ctx, "AuditLog")
+	defer done()
+
+	var (
+		conds []string
+		args  []interface{}
+	)
+	for column, value := range map[string]string{
+		"actor":  filter.Actor,
+		"action": filter.Action,
+		"domain": filter.Domain,
+		"alias":  filter.Alias,
+	} {
+		if value != "" {
+			conds = append(conds, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if filter.From != nil {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.Until != nil {
+		conds = append(conds, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+	if filter.BeforeID > 0 {
+		conds = append(conds, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_log"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []storage.AuditEntry
+	for rows.Next() {
+		var entry storage.AuditEntry
+
+		err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Domain, &entry.Alias,
+			&entry.Before, &entry.After, &entry.RequestID, &entry.RemoteAddr, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"golang-url-shortener/internal/storage"
+	"sort"
+	"time"
+)
+
+// DestinationURLs returns every distinct url links can redirect to, including
+// the urls of rules, destinations and fallbacks.
+func (s *Storage) DestinationURLs(ctx context.Context) ([]string, error) {
+	const op = "storage.sqlite.DestinationURLs"
+	ctx, done := track(ctx, "DestinationURLs")
+	defer done()
+
+	links, err := s.allLinks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, link := range links {
+		for _, u := range link.URLs() {
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+	sort.Strings(urls)
+
+	return urls, nil
+}
+
+// SaveCheck stores the result of a health check, replacing the previous one.
+func (s *Storage) SaveCheck(ctx context.Context, check storage.Check) error {
+	const op = "storage.sqlite.SaveCheck"
+	ctx, done := track(ctx, "SaveCheck")
+	defer done()
+
+	stmt, err := s.db.PrepareContext(ctx, `
+	INSERT INTO url_check (url, status_code, error, latency_ms, checked_at) VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(url) DO UPDATE SET
+	    status_code = excluded.status_code,
+	    error = excluded.error,
+	    latency_ms = excluded.latency_ms,
+	    checked_at = excluded.checked_at`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, check.URL, check.StatusCode, check.Error, check.Latency.Milliseconds(), check.CheckedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+// PruneChecks deletes the checks of the urls not in keep, the destinations
+// links no longer redirect to, and returns how many were deleted.
+func (s *Storage) PruneChecks(ctx context.Context, keep []string) (int64, error) {
+	const op = "storage.sqlite.PruneChecks"
+	ctx, done := track(ctx, "PruneChecks")
+	defer done()
+
+	kept := make(map[string]bool, len(keep))
+	for _, u := range keep {
+		kept[u] = true
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT url FROM url_check")
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+	defer rows.Close()
+
+	var stale []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return 0, fmt.Errorf("%s : %w", op, err)
+		}
+		if !kept[u] {
+			stale = append(stale, u)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+	rows.Close()
+
+	var pruned int64
+	for _, u := range stale {
+		res, err := s.db.ExecContext(ctx, "DELETE FROM url_check WHERE url = ?", u)
+		if err != nil {
+			return pruned, fmt.Errorf("%s : %w", op, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return pruned, fmt.Errorf("%s : %w", op, err)
+		}
+		pruned += n
+	}
+
+	return pruned, nil
+}
+
+// BrokenLinks returns the links with at least one destination that failed its
+// last check, a link is listed once per broken destination.
+func (s *Storage) BrokenLinks(ctx context.Context) ([]storage.BrokenLink, error) {
+	const op = "storage.sqlite.BrokenLinks"
+	ctx, done := track(ctx, "BrokenLinks")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT url, status_code, error, latency_ms, checked_at FROM url_check
+	WHERE error != '' OR status_code >= 400`)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+	defer rows.Close()
+
+	broken := make(map[string]storage.Check)
+	for rows.Next() {
+		var (
+			check   storage.Check
+			latency int64
+		)
+		if err := rows.Scan(&check.URL, &check.StatusCode, &check.Error, &latency, &check.CheckedAt); err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		check.Latency = time.Duration(latency) * time.Millisecond
+
+		broken[check.URL] = check
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	if len(broken) == 0 {
+		return nil, nil
+	}
+
+	links, err := s.allLinks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	var result []storage.BrokenLink
+	for _, link := range links {
+		seen := make(map[string]bool)
+		for _, u := range link.URLs() {
+			check, ok := broken[u]
+			if !ok || seen[u] {
+				continue
+			}
+			seen[u] = true
+
+			result = append(result, storage.BrokenLink{Domain: link.Domain, Alias: link.Alias, Check: check})
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Storage) allLinks(ctx context.Context) ([]storage.Link, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+linkColumns+" FROM url ORDER BY domain, alias")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"golang-url-shortener/internal/storage"
+)
+
+func (s *Storage) RecordClick(ctx context.Context, click storage.Click) error {
+	const op = "storage.sqlite.RecordClick"
+	ctx, done := track(ctx, "RecordClick")
+	defer done()
+
+	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO click (url_id, clicked_at, variant) VALUES (?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, click.URLID, click.ClickedAt.UTC(), click.Variant)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CountClicks(ctx context.Context, urlID int64) (int64, error) {
+	const op = "storage.sqlite.CountClicks"
+	ctx, done := track(ctx, "CountClicks")
+	defer done()
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT COUNT(*) FROM click WHERE url_id = ?")
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	var count int64
+	if err := stmt.QueryRowContext(ctx, urlID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return count, nil
+}
+
+// ConsumeClick takes one click from a click-limited link. The check and the
+// decrement are a single statement, so concurrent redirects can't overshoot.
+func (s *Storage) ConsumeClick(ctx context.Context, urlID int64) error {
+	const op = "storage.sqlite.ConsumeClick"
+	ctx, done := track(ctx, "ConsumeClick")
+	defer done()
+
+	stmt, err := s.db.PrepareContext(ctx, "UPDATE url SET clicks_left = clicks_left - 1 WHERE id = ? AND clicks_left > 0")
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, urlID)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrClicksExhausted
+	}
+
+	return nil
+}
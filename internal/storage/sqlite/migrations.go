@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order on top of the base schema created in New.
+// PRAGMA user_version holds the number of migrations already applied,
+// so new entries must only ever be appended.
+var migrations = []string{
+	`ALTER TABLE url ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE url ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE url ADD COLUMN created_at DATETIME`,
+	`CREATE TABLE IF NOT EXISTS click(
+	    id INTEGER PRIMARY KEY,
+	    url_id INTEGER NOT NULL,
+	    clicked_at DATETIME NOT NULL);
+	CREATE INDEX IF NOT EXISTS idx_click_url_id ON click(url_id)`,
+	`ALTER TABLE url ADD COLUMN forward_query INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE url ADD COLUMN query_conflict TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE url ADD COLUMN forward_path INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE url ADD COLUMN rules TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE url ADD COLUMN destinations TEXT NOT NULL DEFAULT '';
+	ALTER TABLE url ADD COLUMN sticky_split INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE click ADD COLUMN variant TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE url ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE url ADD COLUMN clicks_left INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE url ADD COLUMN active_from DATETIME;
+	ALTER TABLE url ADD COLUMN active_until DATETIME;
+	ALTER TABLE url ADD COLUMN fallback_url TEXT NOT NULL DEFAULT ''`,
+	// aliases become unique per domain, SQLite can only drop the old
+	// constraint by rebuilding the table
+	`CREATE TABLE url_domain(
+	    id INTEGER PRIMARY KEY,
+	    domain TEXT NOT NULL DEFAULT '',
+	    alias TEXT NOT NULL,
+	    url TEXT NOT NULL,
+	    redirect_type INTEGER NOT NULL DEFAULT 0,
+	    password_hash TEXT NOT NULL DEFAULT '',
+	    created_at DATETIME,
+	    forward_query INTEGER NOT NULL DEFAULT 0,
+	    query_conflict TEXT NOT NULL DEFAULT '',
+	    forward_path INTEGER NOT NULL DEFAULT 0,
+	    rules TEXT NOT NULL DEFAULT '',
+	    destinations TEXT NOT NULL DEFAULT '',
+	    sticky_split INTEGER NOT NULL DEFAULT 0,
+	    max_clicks INTEGER NOT NULL DEFAULT 0,
+	    clicks_left INTEGER NOT NULL DEFAULT 0,
+	    active_from DATETIME,
+	    active_until DATETIME,
+	    fallback_url TEXT NOT NULL DEFAULT '',
+	    UNIQUE(domain, alias));
+	INSERT INTO url_domain (id, alias, url, redirect_type, password_hash, created_at,
+	    forward_query, query_conflict, forward_path, rules, destinations, sticky_split,
+	    max_clicks, clicks_left, active_from, active_until, fallback_url)
+	SELECT id, alias, url, redirect_type, password_hash, created_at,
+	    forward_query, query_conflict, forward_path, rules, destinations, sticky_split,
+	    max_clicks, clicks_left, active_from, active_until, fallback_url
+	FROM url;
+	DROP TABLE url;
+	ALTER TABLE url_domain RENAME TO url;
+	CREATE INDEX IF NOT EXISTS idx_alias ON url(alias)`,
+	`CREATE TABLE IF NOT EXISTS url_check(
+	    url TEXT PRIMARY KEY,
+	    status_code INTEGER NOT NULL DEFAULT 0,
+	    error TEXT NOT NULL DEFAULT '',
+	    latency_ms INTEGER NOT NULL DEFAULT 0,
+	    checked_at DATETIME NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS api_key(
+	    id INTEGER PRIMARY KEY,
+	    name TEXT NOT NULL,
+	    prefix TEXT NOT NULL,
+	    key_hash TEXT NOT NULL UNIQUE,
+	    scopes TEXT NOT NULL,
+	    created_at DATETIME NOT NULL,
+	    expires_at DATETIME,
+	    last_used_at DATETIME,
+	    revoked_at DATETIME)`,
+	`CREATE TABLE IF NOT EXISTS audit_log(
+	    id INTEGER PRIMARY KEY,
+	    actor TEXT NOT NULL,
+	    action TEXT NOT NULL,
+	    domain TEXT NOT NULL DEFAULT '',
+	    alias TEXT NOT NULL,
+	    before_value TEXT NOT NULL DEFAULT '',
+	    after_value TEXT NOT NULL DEFAULT '',
+	    request_id TEXT NOT NULL DEFAULT '',
+	    remote_addr TEXT NOT NULL DEFAULT '',
+	    created_at DATETIME NOT NULL);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_alias ON audit_log(domain, alias);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor)`,
+	`CREATE TABLE IF NOT EXISTS webhook(
+	    id INTEGER PRIMARY KEY,
+	    url TEXT NOT NULL,
+	    secret TEXT NOT NULL,
+	    events TEXT NOT NULL,
+	    created_at DATETIME NOT NULL);
+	CREATE TABLE IF NOT EXISTS webhook_delivery(
+	    id INTEGER PRIMARY KEY,
+	    webhook_id INTEGER NOT NULL,
+	    event_id TEXT NOT NULL,
+	    event TEXT NOT NULL,
+	    payload TEXT NOT NULL,
+	    status TEXT NOT NULL,
+	    attempts INTEGER NOT NULL DEFAULT 0,
+	    next_attempt_at DATETIME NOT NULL,
+	    last_status_code INTEGER NOT NULL DEFAULT 0,
+	    last_error TEXT NOT NULL DEFAULT '',
+	    created_at DATETIME NOT NULL,
+	    delivered_at DATETIME);
+	CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due ON webhook_delivery(status, next_attempt_at);
+	CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook ON webhook_delivery(webhook_id)`,
+	`CREATE TABLE IF NOT EXISTS webhook_watermark(
+	    event TEXT PRIMARY KEY,
+	    until_at DATETIME NOT NULL)`,
+}
+
+func migrate(db *sql.DB) error {
+	const op = "storage.sqlite.migrate"
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("%s : %w", op, err)
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("%s : migration %d : %w", op, i+1, err)
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("%s : %w", op, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("%s : %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+func schemaVersion(db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
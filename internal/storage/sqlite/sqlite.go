@@ -1,11 +1,17 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/mattn/go-sqlite3"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"golang-url-shortener/internal/lib/metrics"
+	"golang-url-shortener/internal/lib/tracing"
 	"golang-url-shortener/internal/storage"
+	"time"
 )
 
 type Storage struct {
@@ -20,35 +26,59 @@ func New(storagePath string) (*Storage, error) {
 		return nil, fmt.Errorf("%s : %w", op, err)
 	}
 
-	stmt, err := db.Prepare(`
+	_, err = db.Exec(`
 	CREATE TABLE IF NOT EXISTS url(
 	    id INTEGER PRIMARY KEY,
 	    alias TEXT NOT NULL UNIQUE,
 	    url TEXT NOT NULL);
 	CREATE INDEX IF NOT EXISTS idx_alias ON url(alias)`)
-
 	if err != nil {
 		return nil, fmt.Errorf("%s : %w", op, err)
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec()
-	if err != nil {
+	if err := migrate(db); err != nil {
 		return nil, fmt.Errorf("%s : %w", op, err)
 	}
 
 	return &Storage{db: db}, nil
 }
 
-func (s *Storage) SaveURL(urlToSave, alias string) (int64, error) {
+// SaveURL adds a link, the deliveries of events are queued in the same
+// transaction.
+func (s *Storage) SaveURL(ctx context.Context, urlToSave, domain, alias string, opts storage.LinkOptions, events ...storage.Event) (int64, error) {
 	const op = "storage.sqlite.SaveURL"
+	ctx, done := track(ctx, "SaveURL")
+	defer done()
+
+	rules, err := marshalList(opts.Rules)
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	destinations, err := marshalList(opts.Destinations)
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := s.db.Prepare("INSERT INTO url (url, alias) VALUES (?, ?)")
+	stmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO url (url, domain, alias, redirect_type, password_hash, forward_query, query_conflict, forward_path,
+	                 rules, destinations, sticky_split, max_clicks, clicks_left,
+	                 active_from, active_until, fallback_url, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return 0, fmt.Errorf("%s : %w", op, err)
 	}
 
-	res, err := stmt.Exec(urlToSave, alias)
+	res, err := stmt.ExecContext(ctx, urlToSave, domain, alias, opts.RedirectType, opts.PasswordHash,
+		opts.ForwardQuery, opts.QueryConflict, opts.ForwardPath,
+		rules, destinations, opts.StickySplit, opts.MaxClicks, opts.MaxClicks,
+		utcOrNil(opts.ActiveFrom), utcOrNil(opts.ActiveUntil), opts.FallbackURL, time.Now().UTC())
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
 			return 0, fmt.Errorf("%s : %w", op, storage.ErrUrlExists)
@@ -60,18 +90,29 @@ func (s *Storage) SaveURL(urlToSave, alias string) (int64, error) {
 	if err != nil {
 		return 0, fmt.Errorf("%s : %w", op, err)
 	}
+
+	if err := enqueueEvents(ctx, tx, events); err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
+func (s *Storage) GetURL(ctx context.Context, domain, alias string) (string, error) {
 	const op = "storage.sqlite.GetURL"
+	ctx, done := track(ctx, "GetURL")
+	defer done()
 
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	stmt, err := s.db.PrepareContext(ctx, "SELECT url FROM url WHERE domain = ? AND alias = ?")
 	if err != nil {
 		return "", fmt.Errorf("%s : %w", op, err)
 	}
 
-	row := stmt.QueryRow(alias)
+	row := stmt.QueryRowContext(ctx, domain, alias)
 
 	var url string
 	err = row.Scan(&url)
@@ -85,15 +126,133 @@ func (s *Storage) GetURL(alias string) (string, error) {
 	return url, nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
+const linkColumns = `id, domain, alias, url, redirect_type, password_hash,
+	forward_query, query_conflict, forward_path, rules, destinations, sticky_split,
+	max_clicks, clicks_left, active_from, active_until, fallback_url, created_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLink(row scanner) (storage.Link, error) {
+	var (
+		link         storage.Link
+		rules        string
+		destinations string
+		activeFrom   sql.NullTime
+		activeUntil  sql.NullTime
+		createdAt    sql.NullTime
+	)
+
+	err := row.Scan(&link.ID, &link.Domain, &link.Alias, &link.URL, &link.RedirectType, &link.PasswordHash,
+		&link.ForwardQuery, &link.QueryConflict, &link.ForwardPath,
+		&rules, &destinations, &link.StickySplit, &link.MaxClicks, &link.ClicksLeft,
+		&activeFrom, &activeUntil, &link.FallbackURL, &createdAt)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	link.CreatedAt = createdAt.Time
+
+	if activeFrom.Valid {
+		link.ActiveFrom = &activeFrom.Time
+	}
+	if activeUntil.Valid {
+		link.ActiveUntil = &activeUntil.Time
+	}
+
+	if rules != "" {
+		if err := json.Unmarshal([]byte(rules), &link.Rules); err != nil {
+			return storage.Link{}, fmt.Errorf("invalid rules of %q: %w", link.Alias, err)
+		}
+	}
+
+	if destinations != "" {
+		if err := json.Unmarshal([]byte(destinations), &link.Destinations); err != nil {
+			return storage.Link{}, fmt.Errorf("invalid destinations of %q: %w", link.Alias, err)
+		}
+	}
+
+	return link, nil
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return t.UTC()
+}
+
+// keptOr passes NULL for an option the update keeps, COALESCE then leaves
+// the stored value.
+func keptOr(keep bool, v interface{}) interface{} {
+	if keep {
+		return nil
+	}
+
+	return v
+}
+
+// marshalList stores lists as JSON text, empty lists as an empty string.
+func marshalList[T any](list []T) (string, error) {
+	if len(list) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func (s *Storage) GetLink(ctx context.Context, domain, alias string) (storage.Link, error) {
+	const op = "storage.sqlite.GetLink"
+	ctx, done := track(ctx, "GetLink")
+	defer done()
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT "+linkColumns+" FROM url WHERE domain = ? AND alias = ?")
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s : %w", op, err)
+	}
+
+	link, err := scanLink(stmt.QueryRowContext(ctx, domain, alias))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Link{}, storage.ErrUrlNotFound
+		}
+		return storage.Link{}, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return link, nil
+}
+
+// DeleteURL removes a link with its clicks, the deliveries of events are
+// queued in the same transaction.
+func (s *Storage) DeleteURL(ctx context.Context, domain, alias string, events ...storage.Event) error {
 	const op = "storage.sqlite.DeleteURL"
+	ctx, done := track(ctx, "DeleteURL")
+	defer done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := s.db.Prepare("DELETE FROM url WHERE alias = ?")
+	_, err = tx.ExecContext(ctx, "DELETE FROM click WHERE url_id IN (SELECT id FROM url WHERE domain = ? AND alias = ?)",
+		domain, alias)
 	if err != nil {
 		return fmt.Errorf("%s : %w", op, err)
 	}
 
-	rows, err := stmt.Exec(alias)
+	stmt, err := tx.PrepareContext(ctx, "DELETE FROM url WHERE domain = ? AND alias = ?")
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	rows, err := stmt.ExecContext(ctx, domain, alias)
 	if err != nil {
 		return fmt.Errorf("%s : %w", op, err)
 	}
@@ -107,21 +266,72 @@ func (s *Storage) DeleteURL(alias string) error {
 		return storage.ErrUrlNotFound
 	}
 
+	if err := enqueueEvents(ctx, tx, events); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
 	return nil
 }
 
-func (s *Storage) UpdateURL(urlToUpdate, oldAlias, newAlias string) error {
+// UpdateURL replaces the options of a link, except the ones named by
+// opts.Keep. An empty password hash keeps the current password and clicks
+// already used count against a changed max_clicks.
+// Renaming to an alias taken on the domain returns storage.ErrUrlExists. The
+// deliveries of events are queued in the same transaction.
+func (s *Storage) UpdateURL(ctx context.Context, urlToUpdate, domain, oldAlias, newAlias string, opts storage.LinkOptions,
+	events ...storage.Event) error {
 	const op = "storage.sqlite.UpdateURL"
+	ctx, done := track(ctx, "UpdateURL")
+	defer done()
+
+	rules, err := marshalList(opts.Rules)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
 
-	stmt, err := s.db.Prepare("UPDATE url SET alias = (?) WHERE url = (?) AND alias = (?)")
+	destinations, err := marshalList(opts.Destinations)
 	if err != nil {
 		return fmt.Errorf("%s : %w", op, err)
 	}
 
-	res, err := stmt.Exec(newAlias, urlToUpdate, oldAlias)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+	UPDATE url SET
+	    alias = (?),
+	    redirect_type = COALESCE(?, redirect_type),
+	    password_hash = CASE WHEN ? THEN '' ELSE COALESCE(NULLIF(?, ''), password_hash) END,
+	    forward_query = (?),
+	    query_conflict = (?),
+	    forward_path = (?),
+	    rules = (?),
+	    destinations = (?),
+	    sticky_split = (?),
+	    clicks_left = COALESCE(MAX(? - (max_clicks - clicks_left), 0), clicks_left),
+	    max_clicks = COALESCE(?, max_clicks),
+	    active_from = CASE WHEN ? THEN active_from ELSE ? END,
+	    active_until = CASE WHEN ? THEN active_until ELSE ? END,
+	    fallback_url = (?)
+	WHERE url = (?) AND domain = (?) AND alias = (?)`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, newAlias, keptOr(opts.Keep.RedirectType, opts.RedirectType), opts.RemovePassword, opts.PasswordHash,
+		opts.ForwardQuery, opts.QueryConflict, opts.ForwardPath,
+		rules, destinations, opts.StickySplit, keptOr(opts.Keep.MaxClicks, opts.MaxClicks), keptOr(opts.Keep.MaxClicks, opts.MaxClicks),
+		opts.Keep.ActiveFrom, utcOrNil(opts.ActiveFrom), opts.Keep.ActiveUntil, utcOrNil(opts.ActiveUntil), opts.FallbackURL, urlToUpdate, domain, oldAlias)
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return nil
+			return fmt.Errorf("%s : %w", op, storage.ErrUrlExists)
 		}
 		return fmt.Errorf("%s : %w", op, err)
 	}
@@ -135,19 +345,80 @@ func (s *Storage) UpdateURL(urlToUpdate, oldAlias, newAlias string) error {
 		return storage.ErrUrlNotFound
 	}
 
+	if err := enqueueEvents(ctx, tx, events); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
 	return nil
 }
 
-func (s *Storage) ClearDB() error {
+func (s *Storage) ClearDB(ctx context.Context) error {
 	const op = "storage.sqlite.ClearDB"
+	ctx, done := track(ctx, "ClearDB")
+	defer done()
 
-	stmt, err := s.db.Prepare("DELETE FROM url")
-	if err != nil {
+	for _, table := range []string{"click", "url", "url_check", "api_key", "audit_log", "webhook_delivery", "webhook", "webhook_watermark"} {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return fmt.Errorf("%s : %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// track starts a child span of ctx for the storage method and times it,
+// the returned func ends both.
+func track(ctx context.Context, method string) (context.Context, func()) {
+	ctx, span := tracing.Start(ctx, "sqlite."+method, semconv.DBSystemSqlite, semconv.DBOperation(method))
+	observe := metrics.Storage(method)
+
+	return ctx, func() {
+		observe()
+		span.End()
+	}
+}
+
+// Ping checks that the database answers.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+	ctx, done := track(ctx, "Ping")
+	defer done()
+
+	if err := s.db.PingContext(ctx); err != nil {
 		return fmt.Errorf("%s : %w", op, err)
 	}
 
-	_, err = stmt.Exec()
-	if err != nil {
+	return nil
+}
+
+// CheckMigrations returns an error unless every migration is applied, a
+// newer schema written by a later version of the service fails as well.
+func (s *Storage) CheckMigrations(ctx context.Context) error {
+	const op = "storage.sqlite.CheckMigrations"
+	ctx, done := track(ctx, "CheckMigrations")
+	defer done()
+
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if version != len(migrations) {
+		return fmt.Errorf("%s : schema version %d, expected %d", op, version, len(migrations))
+	}
+
+	return nil
+}
+
+// Close closes the database, the storage can't be used afterwards.
+func (s *Storage) Close() error {
+	const op = "storage.sqlite.Close"
+
+	if err := s.db.Close(); err != nil {
 		return fmt.Errorf("%s : %w", op, err)
 	}
 
@@ -0,0 +1,335 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"golang-url-shortener/internal/storage"
+	"strings"
+	"time"
+)
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+
+func (s *Storage) CreateWebhook(ctx context.Context, hook storage.Webhook) (int64, error) {
+	const op = "storage.sqlite.CreateWebhook"
+	ctx, done := track(ctx, "CreateWebhook")
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "INSERT INTO webhook (url, secret, events, created_at) VALUES (?, ?, ?, ?)",
+		hook.URL, hook.Secret, strings.Join(hook.Events, " "), hook.CreatedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	const op = "storage.sqlite.ListWebhooks"
+	ctx, done := track(ctx, "ListWebhooks")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, url, secret, events, created_at FROM webhook ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+	defer rows.Close()
+
+	var hooks []storage.Webhook
+	for rows.Next() {
+		var (
+			hook   storage.Webhook
+			events string
+		)
+		if err := rows.Scan(&hook.ID, &hook.URL, &hook.Secret, &events, &hook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		hook.Events = strings.Fields(events)
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return hooks, nil
+}
+
+// DeleteWebhook removes a webhook with its deliveries, pending ones included.
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteWebhook"
+	ctx, done := track(ctx, "DeleteWebhook")
+	defer done()
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM webhook_delivery WHERE webhook_id = ?", id); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM webhook WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// EnqueueEvent adds a pending delivery of the event for every webhook
+// subscribed to it, nothing when there are none.
+func (s *Storage) EnqueueEvent(ctx context.Context, eventID, event, payload string, at time.Time) error {
+	const op = "storage.sqlite.EnqueueEvent"
+	ctx, done := track(ctx, "EnqueueEvent")
+	defer done()
+
+	err := enqueueEvents(ctx, s.db, []storage.Event{{ID: eventID, Name: event, Payload: payload, At: at}})
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// enqueueEvents adds the deliveries of events through db, the transaction of
+// the change for the events of a link change.
+func enqueueEvents(ctx context.Context, db execer, events []storage.Event) error {
+	for _, e := range events {
+		_, err := db.ExecContext(ctx, `
+		INSERT INTO webhook_delivery (webhook_id, event_id, event, payload, status, next_attempt_at, created_at)
+		SELECT id, ?, ?, ?, ?, ?, ? FROM webhook WHERE ' ' || events || ' ' LIKE '% ' || ? || ' %'`,
+			e.ID, e.Name, e.Payload, storage.DeliveryPending, e.At.UTC(), e.At.UTC(), e.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DueDeliveries returns up to limit pending deliveries due at now, oldest first.
+func (s *Storage) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]storage.PendingDelivery, error) {
+	const op = "storage.sqlite.DueDeliveries"
+	ctx, done := track(ctx, "DueDeliveries")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+deliveryColumns+`, w.id, w.url, w.secret, w.events, w.created_at
+	FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id
+	WHERE d.status = ? AND d.next_attempt_at <= ?
+	ORDER BY d.next_attempt_at, d.id LIMIT ?`, storage.DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []storage.PendingDelivery
+	for rows.Next() {
+		var (
+			pending     storage.PendingDelivery
+			deliveredAt sql.NullTime
+			events      string
+		)
+
+		d, w := &pending.Delivery, &pending.Webhook
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &deliveredAt,
+			&w.ID, &w.URL, &w.Secret, &events, &w.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		w.Events = strings.Fields(events)
+
+		deliveries = append(deliveries, pending)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// MarkDelivered records the successful attempt of a delivery.
+func (s *Storage) MarkDelivered(ctx context.Context, id int64, statusCode int, at time.Time) error {
+	const op = "storage.sqlite.MarkDelivered"
+	ctx, done := track(ctx, "MarkDelivered")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx, `
+	UPDATE webhook_delivery SET status = ?, attempts = attempts + 1, last_status_code = ?, last_error = '', delivered_at = ?
+	WHERE id = ?`, storage.DeliveryDelivered, statusCode, at.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed attempt of a delivery. It's retried at
+// nextAttempt, a nil nextAttempt moves it to the dead letters.
+func (s *Storage) MarkFailed(ctx context.Context, id int64, statusCode int, errMsg string, nextAttempt *time.Time) error {
+	const op = "storage.sqlite.MarkFailed"
+	ctx, done := track(ctx, "MarkFailed")
+	defer done()
+
+	status := storage.DeliveryPending
+	if nextAttempt == nil {
+		status = storage.DeliveryDead
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+	UPDATE webhook_delivery SET status = ?, attempts = attempts + 1, last_status_code = ?, last_error = ?,
+	    next_attempt_at = COALESCE(?, next_attempt_at)
+	WHERE id = ?`, status, statusCode, errMsg, utcOrNil(nextAttempt), id)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+// Deliveries returns the deliveries of a webhook newest first, only the
+// ones in status when it's not empty.
+func (s *Storage) Deliveries(ctx context.Context, webhookID int64, status string, limit int) ([]storage.Delivery, error) {
+	const op = "storage.sqlite.Deliveries"
+	ctx, done := track(ctx, "Deliveries")
+	defer done()
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM webhook WHERE id = ?", webhookID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+deliveryColumns+` FROM webhook_delivery d
+	WHERE d.webhook_id = ? AND (? = '' OR d.status = ?)
+	ORDER BY d.id DESC LIMIT ?`, webhookID, status, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []storage.Delivery
+	for rows.Next() {
+		var (
+			d           storage.Delivery
+			deliveredAt sql.NullTime
+		)
+
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &deliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// Watermark returns up to when event was published for changes found by
+// polling, zero when it never was.
+func (s *Storage) Watermark(ctx context.Context, event string) (time.Time, error) {
+	const op = "storage.sqlite.Watermark"
+	ctx, done := track(ctx, "Watermark")
+	defer done()
+
+	var until time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT until_at FROM webhook_watermark WHERE event = ?", event).Scan(&until)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return until, nil
+}
+
+// SetWatermark moves the watermark of event to until and queues events in
+// the same transaction, so the events of a window are neither lost nor
+// queued twice.
+func (s *Storage) SetWatermark(ctx context.Context, event string, until time.Time, events ...storage.Event) error {
+	const op = "storage.sqlite.SetWatermark"
+	ctx, done := track(ctx, "SetWatermark")
+	defer done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO webhook_watermark (event, until_at) VALUES (?, ?)
+	ON CONFLICT(event) DO UPDATE SET until_at = excluded.until_at`, event, until.UTC())
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if err := enqueueEvents(ctx, tx, events); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+// LinksExpiredBetween returns the links whose active_until is in (from, to].
+func (s *Storage) LinksExpiredBetween(ctx context.Context, from, to time.Time) ([]storage.Link, error) {
+	const op = "storage.sqlite.LinksExpiredBetween"
+	ctx, done := track(ctx, "LinksExpiredBetween")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+linkColumns+" FROM url WHERE active_until > ? AND active_until <= ? ORDER BY id",
+		from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return links, nil
+}
@@ -1,8 +1,262 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrUrlNotFound = errors.New("url not found")
 	ErrUrlExists   = errors.New("url exists")
+	// ErrClicksExhausted is returned when a click-limited link has no clicks left.
+	ErrClicksExhausted = errors.New("clicks exhausted")
+	ErrKeyNotFound     = errors.New("api key not found")
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// Policies for query keys present both in the stored url and in the request.
+const (
+	QueryConflictLink    = "link"
+	QueryConflictRequest = "request"
+	QueryConflictBoth    = "both"
+)
+
+// LinkOptions are per-link settings stored alongside the url.
+type LinkOptions struct {
+	// RedirectType is the HTTP status used for the redirect, zero means the configured default.
+	RedirectType int
+	// PasswordHash protects the link when set. Updates keep the stored hash when empty
+	// unless RemovePassword is set, which drops it.
+	PasswordHash   string
+	RemovePassword bool
+
+	// ForwardQuery merges the query string of the request into the url,
+	// QueryConflict decides which value wins when both have the same key,
+	// the url's one by default.
+	ForwardQuery  bool
+	QueryConflict string
+	// ForwardPath appends whatever follows the alias in the request path to the url.
+	ForwardPath bool
+
+	// Rules are evaluated in order before falling back to the url.
+	Rules []Rule
+
+	// Destinations split the traffic not caught by a rule by weight, the url
+	// is not redirected to while there are any.
+	Destinations []Destination
+	// StickySplit keeps a visitor on the same destination with a cookie.
+	StickySplit bool
+
+	// MaxClicks limits how many times the link can be followed, 0 means unlimited.
+	// A link with MaxClicks 1 is a one-time link.
+	MaxClicks int
+
+	// ActiveFrom and ActiveUntil limit when the link resolves, nil means no bound.
+	ActiveFrom  *time.Time
+	ActiveUntil *time.Time
+	// FallbackURL is redirected to outside of the activation window.
+	FallbackURL string
+
+	// Keep leaves the options an update request didn't send as they are
+	// stored, saves ignore it.
+	Keep Keep
+}
+
+// Keep names the options an update leaves as they are stored.
+type Keep struct {
+	RedirectType bool
+	MaxClicks    bool
+	ActiveFrom   bool
+	ActiveUntil  bool
+}
+
+// Destination is one variant of an A/B split.
+type Destination struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Rule redirects to URL when all of its conditions match the request,
+// empty conditions match anything.
+type Rule struct {
+	URL string `json:"url"`
+	// Platforms derived from the user agent: ios, android, windows, macos, linux.
+	Platforms []string `json:"platforms,omitempty"`
+	// Languages from Accept-Language, "de" also matches "de-AT".
+	Languages []string `json:"languages,omitempty"`
+	// Query and Headers must have the given values, an empty value only requires presence.
+	Query   map[string]string `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	From    *time.Time        `json:"from,omitempty"`
+	Until   *time.Time        `json:"until,omitempty"`
+}
+
+type Link struct {
+	ID int64
+	// Domain is the short domain the alias belongs to, empty for the primary one.
+	Domain string
+	Alias  string
+	URL    string
+	LinkOptions
+
+	// CreatedAt is zero for links saved before it was tracked.
+	CreatedAt time.Time
+	// ClicksLeft is only meaningful for links with MaxClicks.
+	ClicksLeft int
+}
+
+// URLs lists every url the link can redirect to, the url of the link first.
+func (l Link) URLs() []string {
+	urls := []string{l.URL}
+	for _, rule := range l.Rules {
+		urls = append(urls, rule.URL)
+	}
+	for _, dest := range l.Destinations {
+		urls = append(urls, dest.URL)
+	}
+	if l.FallbackURL != "" {
+		urls = append(urls, l.FallbackURL)
+	}
+
+	return urls
+}
+
+type Click struct {
+	URLID     int64
+	ClickedAt time.Time
+	// Variant is the name of the destination served, empty without a split.
+	Variant string
+}
+
+// Check is the result of the last health check of a destination url.
+type Check struct {
+	URL string
+	// StatusCode is 0 when no response was received, Error tells why.
+	StatusCode int
+	Error      string
+	Latency    time.Duration
+	CheckedAt  time.Time
+}
+
+// Broken reports whether the destination failed its check.
+func (c Check) Broken() bool {
+	return c.Error != "" || c.StatusCode >= 400
+}
+
+// BrokenLink is a link with a destination that failed its last check.
+type BrokenLink struct {
+	Domain string
+	Alias  string
+	Check
+}
+
+// APIKey is a credential for the management API, the key itself is only
+// stored as a hash.
+type APIKey struct {
+	ID   int64
+	Name string
+	// Prefix is the beginning of the key, to tell keys apart in listings.
+	Prefix string
+	Scopes []string
+
+	CreatedAt time.Time
+	// ExpiresAt is nil for keys that don't expire.
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// Active reports whether the key can be used at now.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Actions of the audit log.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
 )
+
+// AuditEntry records a change of a link made through the API.
+type AuditEntry struct {
+	ID int64
+	// Actor is the subject of the identity that made the change.
+	Actor  string
+	Action string
+	Domain string
+	Alias  string
+	// Before and After are JSON snapshots of the link, empty when it didn't
+	// exist before or doesn't exist after the change.
+	Before     string
+	After      string
+	RequestID  string
+	RemoteAddr string
+	CreatedAt  time.Time
+}
+
+// AuditFilter selects audit entries, empty fields match everything.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Domain string
+	Alias  string
+	From   *time.Time
+	Until  *time.Time
+	// BeforeID pages through the log, only older entries are returned.
+	BeforeID int64
+	Limit    int
+}
+
+// Webhook subscribes an url to link events.
+type Webhook struct {
+	ID  int64
+	URL string
+	// Secret signs the payloads sent to the url.
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+// Event is a webhook event queued as a delivery for every webhook subscribed
+// to it. Events of a link change are queued in the transaction of the change.
+type Event struct {
+	ID      string
+	Name    string
+	Payload string
+	At      time.Time
+}
+
+// States of a delivery.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead deliveries ran out of attempts and are not retried.
+	DeliveryDead = "dead"
+)
+
+// Delivery is an event for a webhook, waiting in the outbox or already sent.
+type Delivery struct {
+	ID        int64
+	WebhookID int64
+	// EventID is shared by the deliveries of the same event to different webhooks.
+	EventID string
+	Event   string
+	Payload string
+	Status  string
+	// Attempts counts the requests made so far, NextAttemptAt is when the
+	// next one is due for pending deliveries.
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// PendingDelivery is a delivery due to be sent with the webhook it goes to.
+type PendingDelivery struct {
+	Delivery
+	Webhook Webhook
+}
@@ -0,0 +1,302 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/lib/tracing"
+	"golang-url-shortener/internal/screening"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Store interface {
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]storage.PendingDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int, at time.Time) error
+	MarkFailed(ctx context.Context, id int64, statusCode int, errMsg string, nextAttempt *time.Time) error
+	LinksExpiredBetween(ctx context.Context, from, to time.Time) ([]storage.Link, error)
+	Watermark(ctx context.Context, event string) (time.Time, error)
+	SetWatermark(ctx context.Context, event string, until time.Time, events ...storage.Event) error
+}
+
+type Options struct {
+	// PollInterval between two looks at the outbox, 1s when zero.
+	PollInterval time.Duration
+	// BatchSize is how many deliveries are fetched at once, 20 when zero.
+	BatchSize int
+	// Timeout of a single request, 10s when zero.
+	Timeout time.Duration
+	// MaxAttempts before a delivery goes to the dead letters, 8 when zero.
+	MaxAttempts int
+	// BackoffBase is the delay before the first retry, doubled for each
+	// following one up to BackoffMax. 10s and 1h when zero.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// UserAgent sent with the requests.
+	UserAgent string
+
+	// Screener rejects webhook urls that are no longer allowed. With a
+	// Screener the default client refuses to connect to private addresses,
+	// names resolving to them included.
+	Screener *screening.Screener
+
+	// Client replaces the default http client, e.g. in tests. Its transport
+	// is used as is.
+	Client *http.Client
+}
+
+func (o Options) withDefaults() Options {
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 20
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.BackoffBase <= 0 {
+		o.BackoffBase = 10 * time.Second
+	}
+	if o.BackoffMax <= 0 {
+		o.BackoffMax = time.Hour
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = "url-shortener-webhooks/1.0"
+	}
+
+	return o
+}
+
+// Sender delivers the events queued in the outbox and publishes link.expired
+// for links passing their active_until. Only one Sender may run per outbox.
+type Sender struct {
+	store  Store
+	events *Dispatcher
+	opts   Options
+	client *http.Client
+}
+
+// NewSender builds a Sender, events is used to publish link.expired.
+func NewSender(store Store, events *Dispatcher, opts Options) *Sender {
+	opts = opts.withDefaults()
+
+	client := &http.Client{}
+	if opts.Client != nil {
+		*client = *opts.Client
+	} else if opts.Screener != nil {
+		client.Transport = screening.Transport()
+	}
+	client.Timeout = opts.Timeout
+	// a redirect is an error of the receiver, following it could reach hosts
+	// the url was screened against
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Sender{store: store, events: events, opts: opts, client: client}
+}
+
+// Run delivers due events every PollInterval until ctx is done.
+func (s *Sender) Run(ctx context.Context, log *slog.Logger) {
+	const op = "webhooks.Run"
+
+	log = log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.PublishExpired(ctx, time.Now()); err != nil {
+			log.Error("failed to publish expired links", sl.Err(err))
+		}
+
+		if err := s.DeliverDue(ctx, log); err != nil {
+			log.Error("failed to deliver webhooks", sl.Err(err))
+		}
+	}
+}
+
+// PublishExpired publishes link.expired for the links whose active_until
+// passed since the stored watermark, which then moves to now. The watermark
+// outlives restarts, so links expiring while the service is down are caught
+// up on. The first call only sets it.
+func (s *Sender) PublishExpired(ctx context.Context, now time.Time) error {
+	const op = "webhooks.PublishExpired"
+
+	from, err := s.store.Watermark(ctx, EventLinkExpired)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	if from.IsZero() {
+		if err := s.store.SetWatermark(ctx, EventLinkExpired, now); err != nil {
+			return fmt.Errorf("%s : %w", op, err)
+		}
+		return nil
+	}
+
+	if !now.After(from) {
+		return nil
+	}
+
+	links, err := s.store.LinksExpiredBetween(ctx, from, now)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	var events []storage.Event
+	for _, link := range links {
+		e, ok, err := s.events.Event(EventLinkExpired, Expired{Domain: link.Domain, Alias: link.Alias, Reason: ReasonActiveUntil})
+		if err != nil {
+			return fmt.Errorf("%s : %w", op, err)
+		}
+		if ok {
+			events = append(events, e)
+		}
+	}
+
+	if err := s.store.SetWatermark(ctx, EventLinkExpired, now, events...); err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+
+	return nil
+}
+
+// DeliverDue sends the deliveries due now and returns once the outbox has
+// none left. Failed ones are retried later with an exponential backoff.
+func (s *Sender) DeliverDue(ctx context.Context, log *slog.Logger) error {
+	const op = "webhooks.DeliverDue"
+
+	for ctx.Err() == nil {
+		deliveries, err := s.store.DueDeliveries(ctx, time.Now(), s.opts.BatchSize)
+		if err != nil {
+			return fmt.Errorf("%s : %w", op, err)
+		}
+
+		for _, d := range deliveries {
+			if err := s.deliver(ctx, log, d); err != nil {
+				return fmt.Errorf("%s : %w", op, err)
+			}
+		}
+
+		if len(deliveries) < s.opts.BatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *Sender) deliver(ctx context.Context, log *slog.Logger, d storage.PendingDelivery) error {
+	ctx, span := tracing.Start(ctx, "webhooks.deliver",
+		attribute.Int64("webhook.id", d.WebhookID),
+		attribute.Int64("webhook.delivery_id", d.ID),
+		attribute.String("webhook.event", d.Event),
+	)
+	defer span.End()
+
+	log = log.With(
+		slog.Int64("delivery_id", d.ID),
+		slog.Int64("webhook_id", d.WebhookID),
+		slog.String("event", d.Event),
+	)
+
+	statusCode, err := s.send(ctx, d)
+	now := time.Now()
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	// the outcome of a sent delivery is recorded even when shutting down
+	markCtx := context.WithoutCancel(ctx)
+
+	if err == nil {
+		log.Debug("webhook delivered", slog.Int("status", statusCode))
+		return s.store.MarkDelivered(markCtx, d.ID, statusCode, now)
+	}
+
+	if ctx.Err() != nil {
+		// shutting down, the delivery stays due for the next run
+		return nil
+	}
+
+	attempts := d.Attempts + 1
+	if attempts >= s.opts.MaxAttempts {
+		log.Warn("webhook delivery dead", sl.Err(err), slog.Int("attempts", attempts))
+		return s.store.MarkFailed(markCtx, d.ID, statusCode, err.Error(), nil)
+	}
+
+	next := now.Add(s.backoff(attempts))
+	log.Info("webhook delivery failed", sl.Err(err), slog.Int("attempts", attempts), slog.Time("next_attempt", next))
+
+	return s.store.MarkFailed(markCtx, d.ID, statusCode, err.Error(), &next)
+}
+
+// send makes one attempt, it returns the status code of the response and
+// an error unless it's 2xx.
+func (s *Sender) send(ctx context.Context, d storage.PendingDelivery) (int, error) {
+	if err := s.opts.Screener.Check(d.Webhook.URL); err != nil {
+		return 0, err
+	}
+
+	body := []byte(d.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.opts.UserAgent)
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderID, d.EventID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(d.Webhook.Secret, timestamp, body))
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// drain a little so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New("unexpected status " + resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff is the delay before the retry following attempts failed attempts.
+func (s *Sender) backoff(attempts int) time.Duration {
+	delay := s.opts.BackoffBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= s.opts.BackoffMax {
+			return s.opts.BackoffMax
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"golang-url-shortener/internal/lib/logger/sl"
+	"golang-url-shortener/internal/storage"
+	"golang.org/x/exp/slog"
+	"strconv"
+	"time"
+)
+
+// Events webhooks can subscribe to.
+const (
+	EventLinkCreated = "link.created"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
+	// EventLinkExpired is sent when a link used up its clicks or passed its
+	// active_until.
+	EventLinkExpired = "link.expired"
+	EventLinkClicked = "link.clicked"
+)
+
+// Events lists every event a webhook can subscribe to.
+var Events = []string{EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkExpired, EventLinkClicked}
+
+// Headers of the requests sent to webhooks.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is "sha256=" followed by the hex HMAC-SHA256 of
+	// "<timestamp>.<body>" keyed with the secret of the webhook.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Payload is the JSON body sent to webhooks.
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type EventStore interface {
+	EnqueueEvent(ctx context.Context, eventID, event, payload string, at time.Time) error
+}
+
+// Dispatcher queues events in the outbox for the subscribed webhooks, the
+// Sender delivers them. A nil *Dispatcher drops every event.
+type Dispatcher struct {
+	log   *slog.Logger
+	store EventStore
+}
+
+func NewDispatcher(log *slog.Logger, store EventStore) *Dispatcher {
+	return &Dispatcher{
+		log:   log.With(slog.String("component", "webhooks")),
+		store: store,
+	}
+}
+
+// Publish queues event with data as the payload data. Failures are logged,
+// they must not fail what triggered the event.
+func (d *Dispatcher) Publish(ctx context.Context, event string, data interface{}) {
+	e, ok, err := d.Event(event, data)
+	if !ok {
+		return
+	}
+
+	log := d.log.With(slog.String("event", event))
+
+	if err != nil {
+		log.Error("failed to build event", sl.Err(err))
+		return
+	}
+
+	if err := d.store.EnqueueEvent(ctx, e.ID, e.Name, e.Payload, e.At); err != nil {
+		log.Error("failed to enqueue event", sl.Err(err))
+	}
+}
+
+// Event builds event with data as the payload data without queueing it, for
+// the storage to queue it in the transaction of the change it describes.
+// ok is false for a nil *Dispatcher.
+func (d *Dispatcher) Event(event string, data interface{}) (e storage.Event, ok bool, err error) {
+	const op = "webhooks.Event"
+
+	if d == nil {
+		return storage.Event{}, false, nil
+	}
+
+	id, err := newID()
+	if err != nil {
+		return storage.Event{}, true, fmt.Errorf("%s : %w", op, err)
+	}
+
+	now := time.Now().UTC()
+
+	payload, err := json.Marshal(Payload{ID: id, Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return storage.Event{}, true, fmt.Errorf("%s : %w", op, err)
+	}
+
+	return storage.Event{ID: id, Name: event, Payload: string(payload), At: now}, true, nil
+}
+
+// ValidEvent reports whether event is one of Events.
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sign returns the value of HeaderSignature for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random secret for a webhook.
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// Reasons of link.expired events.
+const (
+	ReasonClickLimit  = "click_limit"
+	ReasonActiveUntil = "active_until"
+)
+
+// Expired is the data of link.expired events.
+type Expired struct {
+	Domain string `json:"domain,omitempty"`
+	Alias  string `json:"alias"`
+	Reason string `json:"reason"`
+}
+
+// Clicked is the data of link.clicked events.
+type Clicked struct {
+	Domain    string    `json:"domain,omitempty"`
+	Alias     string    `json:"alias"`
+	URL       string    `json:"url"`
+	Variant   string    `json:"variant,omitempty"`
+	ClickedAt time.Time `json:"clicked_at"`
+}
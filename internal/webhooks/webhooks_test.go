@@ -0,0 +1,306 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"golang-url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"golang-url-shortener/internal/screening"
+	"golang-url-shortener/internal/storage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	mu         sync.Mutex
+	deliveries []*storage.PendingDelivery
+	webhooks   []storage.Webhook
+	expired    []storage.Link
+	watermarks map[string]time.Time
+}
+
+func (s *memStore) EnqueueEvent(_ context.Context, eventID, event, payload string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, hook := range s.webhooks {
+		for _, e := range hook.Events {
+			if e != event {
+				continue
+			}
+			s.deliveries = append(s.deliveries, &storage.PendingDelivery{
+				Delivery: storage.Delivery{
+					ID: int64(len(s.deliveries) + 1), WebhookID: hook.ID, EventID: eventID, Event: event,
+					Payload: payload, Status: storage.DeliveryPending, NextAttemptAt: at, CreatedAt: at,
+				},
+				Webhook: hook,
+			})
+		}
+	}
+
+	return nil
+}
+
+func (s *memStore) DueDeliveries(_ context.Context, now time.Time, limit int) ([]storage.PendingDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []storage.PendingDelivery
+	for _, d := range s.deliveries {
+		if d.Status == storage.DeliveryPending && !d.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, *d)
+		}
+	}
+
+	return due, nil
+}
+
+func (s *memStore) MarkDelivered(_ context.Context, id int64, statusCode int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.deliveries[id-1]
+	d.Status, d.Attempts, d.LastStatusCode, d.DeliveredAt = storage.DeliveryDelivered, d.Attempts+1, statusCode, &at
+	return nil
+}
+
+func (s *memStore) MarkFailed(_ context.Context, id int64, statusCode int, errMsg string, nextAttempt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.deliveries[id-1]
+	d.Attempts, d.LastStatusCode, d.LastError = d.Attempts+1, statusCode, errMsg
+	if nextAttempt == nil {
+		d.Status = storage.DeliveryDead
+	} else {
+		d.NextAttemptAt = *nextAttempt
+	}
+	return nil
+}
+
+func (s *memStore) LinksExpiredBetween(_ context.Context, from, to time.Time) ([]storage.Link, error) {
+	var links []storage.Link
+	for _, link := range s.expired {
+		if link.ActiveUntil.After(from) && !link.ActiveUntil.After(to) {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+func (s *memStore) Watermark(_ context.Context, event string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.watermarks[event], nil
+}
+
+func (s *memStore) SetWatermark(ctx context.Context, event string, until time.Time, events ...storage.Event) error {
+	s.mu.Lock()
+	if s.watermarks == nil {
+		s.watermarks = make(map[string]time.Time)
+	}
+	s.watermarks[event] = until
+	s.mu.Unlock()
+
+	for _, e := range events {
+		if err := s.EnqueueEvent(ctx, e.ID, e.Name, e.Payload, e.At); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// due makes every pending delivery due right away.
+func (s *memStore) due() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.deliveries {
+		d.NextAttemptAt = time.Time{}
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"link.created"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1714564800." + string(body)))
+
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), Sign("secret", 1714564800, body))
+	require.NotEqual(t, Sign("secret", 1714564800, body), Sign("other", 1714564800, body))
+	require.NotEqual(t, Sign("secret", 1714564800, body), Sign("secret", 1714564801, body))
+}
+
+func TestDeliverDue(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+
+	var (
+		mu   sync.Mutex
+		got  []received
+		fail = true
+	)
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		got = append(got, received{header: r.Header, body: body})
+		mu.Unlock()
+	}))
+	defer ok.Close()
+
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	store := &memStore{webhooks: []storage.Webhook{
+		{ID: 1, URL: ok.URL, Secret: "secret-one", Events: []string{EventLinkCreated}},
+		{ID: 2, URL: flaky.URL, Secret: "secret-two", Events: []string{EventLinkCreated}},
+		{ID: 3, URL: down.URL, Secret: "secret-three", Events: []string{EventLinkCreated}},
+		{ID: 4, URL: ok.URL, Secret: "secret-four", Events: []string{EventLinkClicked}},
+	}}
+	log := slogdiscard.NewDiscardLogger()
+
+	NewDispatcher(log, store).Publish(context.Background(), EventLinkCreated, map[string]string{"alias": "sale"})
+	require.Len(t, store.deliveries, 3)
+
+	sender := NewSender(store, nil, Options{MaxAttempts: 2, BackoffBase: time.Minute})
+	start := time.Now()
+	require.NoError(t, sender.DeliverDue(context.Background(), log))
+
+	// signed with the secret of the webhook
+	require.Len(t, got, 1)
+	timestamp, err := strconv.ParseInt(got[0].header.Get(HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	require.Equal(t, Sign("secret-one", timestamp, got[0].body), got[0].header.Get(HeaderSignature))
+	require.Equal(t, EventLinkCreated, got[0].header.Get(HeaderEvent))
+	require.Equal(t, store.deliveries[0].EventID, got[0].header.Get(HeaderID))
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal(got[0].body, &payload))
+	require.Equal(t, EventLinkCreated, payload.Event)
+	require.Equal(t, map[string]interface{}{"alias": "sale"}, payload.Data)
+
+	delivered, flakyDelivery, downDelivery := store.deliveries[0], store.deliveries[1], store.deliveries[2]
+
+	require.Equal(t, storage.DeliveryDelivered, delivered.Status)
+	require.Equal(t, storage.DeliveryPending, flakyDelivery.Status)
+	require.Equal(t, http.StatusServiceUnavailable, flakyDelivery.LastStatusCode)
+	require.WithinDuration(t, start.Add(time.Minute), flakyDelivery.NextAttemptAt, 5*time.Second)
+	require.Equal(t, storage.DeliveryPending, downDelivery.Status)
+	require.NotEmpty(t, downDelivery.LastError)
+
+	// not due yet
+	require.NoError(t, sender.DeliverDue(context.Background(), log))
+	require.Equal(t, 1, flakyDelivery.Attempts)
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	store.due()
+	require.NoError(t, sender.DeliverDue(context.Background(), log))
+
+	require.Equal(t, storage.DeliveryDelivered, flakyDelivery.Status)
+	require.Equal(t, 2, flakyDelivery.Attempts)
+	require.Equal(t, storage.DeliveryDead, downDelivery.Status)
+	require.Equal(t, 2, downDelivery.Attempts)
+	require.Len(t, got, 1)
+}
+
+func TestSenderPrivateAddress(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	screener, err := screening.New(screening.Config{})
+	require.NoError(t, err)
+
+	// a name the screener would accept can still resolve to a private
+	// address, the client refuses it when connecting
+	sender := NewSender(&memStore{}, nil, Options{Screener: screener})
+	_, err = sender.client.Post(strings.Replace(server.URL, "127.0.0.1", "localhost", 1), "application/json", nil)
+	require.ErrorIs(t, err, screening.ErrPrivateAddress)
+	require.Zero(t, atomic.LoadInt32(&hits))
+}
+
+func TestBackoff(t *testing.T) {
+	sender := NewSender(&memStore{}, nil, Options{BackoffBase: 10 * time.Second, BackoffMax: time.Minute})
+
+	for attempts, delay := range []time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 4: time.Minute, 9: time.Minute} {
+		if delay == 0 {
+			continue
+		}
+		require.Equal(t, delay, sender.backoff(attempts), attempts)
+	}
+}
+
+func TestPublishExpired(t *testing.T) {
+	now := time.Now()
+	past, soon, later := now.Add(-time.Hour), now.Add(time.Minute), now.Add(time.Hour)
+
+	store := &memStore{
+		webhooks: []storage.Webhook{{ID: 1, Events: []string{EventLinkExpired}}},
+		expired: []storage.Link{
+			{Alias: "old", LinkOptions: storage.LinkOptions{ActiveUntil: &past}},
+			{Alias: "soon", Domain: "go.brand-a.com", LinkOptions: storage.LinkOptions{ActiveUntil: &soon}},
+			{Alias: "later", LinkOptions: storage.LinkOptions{ActiveUntil: &later}},
+		},
+	}
+	events := NewDispatcher(slogdiscard.NewDiscardLogger(), store)
+
+	// the first run only sets the watermark, links expired before aren't reported
+	sender := NewSender(store, events, Options{})
+	require.NoError(t, sender.PublishExpired(context.Background(), now))
+	require.Empty(t, store.deliveries)
+	require.Equal(t, now, store.watermarks[EventLinkExpired])
+
+	require.NoError(t, sender.PublishExpired(context.Background(), now.Add(2*time.Minute)))
+	require.Len(t, store.deliveries, 1)
+
+	var payload struct {
+		Data Expired `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(store.deliveries[0].Payload), &payload))
+	require.Equal(t, Expired{Domain: "go.brand-a.com", Alias: "soon", Reason: ReasonActiveUntil}, payload.Data)
+
+	// each link is published once
+	require.NoError(t, sender.PublishExpired(context.Background(), now.Add(3*time.Minute)))
+	require.Len(t, store.deliveries, 1)
+
+	// links expiring while the service was down are caught up on after a restart
+	restarted := NewSender(store, events, Options{})
+	require.NoError(t, restarted.PublishExpired(context.Background(), now.Add(2*time.Hour)))
+	require.Len(t, store.deliveries, 2)
+
+	var nilDispatcher *Dispatcher
+	nilDispatcher.Publish(context.Background(), EventLinkExpired, nil)
+	_, ok, err := nilDispatcher.Event(EventLinkExpired, nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -1,16 +1,21 @@
 package e2e
 
 import (
+	"context"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"golang-url-shortener/internal/config"
+	"golang-url-shortener/internal/http-server/handlers/preview"
 	"golang-url-shortener/internal/http-server/handlers/redirect"
 	"golang-url-shortener/internal/http-server/handlers/url/delete"
 	"golang-url-shortener/internal/http-server/handlers/url/save"
 	"golang-url-shortener/internal/http-server/handlers/url/update"
 	"golang-url-shortener/internal/http-server/middleware/logger"
+	"golang-url-shortener/internal/lib/domains"
+	"golang-url-shortener/internal/lib/loops"
+	"golang-url-shortener/internal/screening"
 	"golang-url-shortener/internal/storage/sqlite"
 	"golang.org/x/exp/slog"
 	"io"
@@ -60,26 +65,35 @@ func (s *UrlShortenerE2ESuite) TearDownTest() {
 
 	s.server.Close()
 
-	s.test.NoError(s.storage.ClearDB())
+	s.test.NoError(s.storage.ClearDB(context.Background()))
+	s.test.NoError(s.storage.Close())
 }
 
 func (s *UrlShortenerE2ESuite) setupRouter(storage *sqlite.Storage) *chi.Mux {
 	router := chi.NewRouter()
 
 	nopLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry := domains.New("http://localhost", nil)
+
+	screener, err := screening.New(screening.Config{})
+	s.Require().NoError(err)
+
+	detector := loops.New(registry, storage, 0)
 
 	router.Use(middleware.RequestID)
 	router.Use(logger.New(nopLogger))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
+	router.Use(registry.Middleware)
 
 	router.Route("/url", func(r chi.Router) {
-		r.Post("/", save.New(nopLogger, storage))
-		r.Delete("/{alias}", delete.New(nopLogger, storage))
-		r.Put("/", update.New(nopLogger, storage))
+		r.Post("/", save.New(nopLogger, storage, registry, nil, screener, detector, nil))
+		r.Delete("/{alias}", delete.New(nopLogger, storage, registry, nil))
+		r.Put("/", update.New(nopLogger, storage, registry, nil, screener, detector, nil))
 	})
 
-	router.Get("/{alias}", redirect.New(nopLogger, storage))
+	router.Get("/{alias}", redirect.New(nopLogger, storage, storage, redirect.Options{Screener: screener}))
+	router.Get("/{alias}+", preview.New(nopLogger, storage))
 
 	return router
 }
@@ -2,14 +2,41 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang-url-shortener/internal/config"
+	"golang-url-shortener/internal/health"
+	"golang-url-shortener/internal/healthcheck"
+	"golang-url-shortener/internal/http-server/handlers/audit"
+	"golang-url-shortener/internal/http-server/handlers/keys/create"
+	"golang-url-shortener/internal/http-server/handlers/keys/list"
+	"golang-url-shortener/internal/http-server/handlers/url/broken"
+	"golang-url-shortener/internal/http-server/handlers/url/options"
 	"golang-url-shortener/internal/http-server/handlers/url/save"
 	"golang-url-shortener/internal/http-server/handlers/url/update"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/deliveries"
+	"golang-url-shortener/internal/http-server/handlers/webhooks/subscribe"
+	"golang-url-shortener/internal/http-server/router"
+	"golang-url-shortener/internal/lib/api"
 	"golang-url-shortener/internal/lib/api/response"
+	"golang-url-shortener/internal/lib/apikey"
+	"golang-url-shortener/internal/lib/auditlog"
+	"golang-url-shortener/internal/lib/domains"
+	"golang-url-shortener/internal/lib/password"
 	"golang-url-shortener/internal/storage"
+	"golang-url-shortener/internal/webhooks"
+	"golang.org/x/exp/slog"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
 )
 
 const contentType = "application/json"
@@ -37,7 +64,7 @@ func (s *UrlShortenerSuite) TestSaveSuccess() {
 	s.test.Equal(http.StatusOK, saveResp.StatusCode)
 	defer saveResp.Body.Close()
 
-	actualURL, err := s.storage.GetURL(testAlias)
+	actualURL, err := s.storage.GetURL(context.Background(), domains.Default, testAlias)
 	s.test.NoError(err)
 	s.test.Equal(testURL, actualURL)
 }
@@ -84,7 +111,7 @@ func (s *UrlShortenerSuite) TestSaveFailed_ErrorAlreadyExists() {
 	s.test.Equal(respCore.Status, response.StatusError)
 	s.test.Equal(respCore.Error, "url already exists")
 
-	actualURL, err := s.storage.GetURL(testAlias)
+	actualURL, err := s.storage.GetURL(context.Background(), domains.Default, testAlias)
 	s.test.NoError(err)
 	s.test.Equal(testURL, actualURL)
 }
@@ -115,7 +142,7 @@ func (s *UrlShortenerSuite) TestUpdateSuccess() {
 	defer saveResp.Body.Close()
 
 	// Проверяем, что url и alias вставились
-	actualURL, err := s.storage.GetURL(testAlias)
+	actualURL, err := s.storage.GetURL(context.Background(), domains.Default, testAlias)
 	s.test.NoError(err)
 	s.test.Equal(testURL, actualURL)
 
@@ -145,10 +172,10 @@ func (s *UrlShortenerSuite) TestUpdateSuccess() {
 	s.test.Equal(respCore.Error, "")
 
 	// Проверяем, что alias обновился
-	_, err = s.storage.GetURL(testAlias)
+	_, err = s.storage.GetURL(context.Background(), domains.Default, testAlias)
 	s.test.ErrorIs(err, storage.ErrUrlNotFound)
 
-	actualURL, err = s.storage.GetURL(testNewAlias)
+	actualURL, err = s.storage.GetURL(context.Background(), domains.Default, testNewAlias)
 	s.test.NoError(err)
 	s.test.Equal(testURL, actualURL)
 }
@@ -211,7 +238,7 @@ func (s *UrlShortenerSuite) TestDeleteSuccess() {
 	defer saveResp.Body.Close()
 
 	// Проверяем, что url и alias вставились
-	actualURL, err := s.storage.GetURL(testAlias)
+	actualURL, err := s.storage.GetURL(context.Background(), domains.Default, testAlias)
 	s.test.NoError(err)
 	s.test.Equal(testURL, actualURL)
 
@@ -233,6 +260,1457 @@ func (s *UrlShortenerSuite) TestDeleteSuccess() {
 	s.test.Equal(respCore.Error, "")
 
 	// Проверяем, что alias удалился
-	_, err = s.storage.GetURL(testAlias)
+	_, err = s.storage.GetURL(context.Background(), domains.Default, testAlias)
 	s.test.ErrorIs(err, storage.ErrUrlNotFound)
 }
+
+func (s *UrlShortenerSuite) TestSaveAndUpdateRedirectType() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://mail.google.com/"
+	testAlias := "mail"
+	permanent, temporary, byDefault := http.StatusMovedPermanently, http.StatusTemporaryRedirect, 0
+
+	req := save.Request{
+		URL:   testURL,
+		Alias: testAlias,
+		Options: options.Options{
+			RedirectType: &permanent,
+		},
+	}
+
+	marshalledSaveReq, err := json.Marshal(req)
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	s.test.Equal(http.StatusOK, saveResp.StatusCode)
+	defer saveResp.Body.Close()
+
+	link, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.Equal(http.StatusMovedPermanently, link.RedirectType)
+
+	// Меняем тип редиректа, alias оставляем прежним
+	updateRequest := update.Request{
+		URL:      testURL,
+		OldAlias: testAlias,
+		NewAlias: testAlias,
+		Options: options.Options{
+			RedirectType: &temporary,
+		},
+	}
+
+	marshalledUpdateReq, err := json.Marshal(updateRequest)
+	s.test.NoError(err)
+
+	updateReq, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.test.NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	updateResp, err := s.httpClient.Do(updateReq)
+	s.test.NoError(err)
+	defer updateResp.Body.Close()
+
+	link, err = s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.Equal(http.StatusTemporaryRedirect, link.RedirectType)
+
+	// Переименование без redirect_type оставляет тип редиректа
+	newAlias := "mail2"
+	marshalledUpdateReq, err = json.Marshal(update.Request{
+		URL:      testURL,
+		OldAlias: testAlias,
+		NewAlias: newAlias,
+	})
+	s.test.NoError(err)
+
+	updateReq, err = http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.test.NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	renameResp, err := s.httpClient.Do(updateReq)
+	s.test.NoError(err)
+	defer renameResp.Body.Close()
+
+	link, err = s.storage.GetLink(context.Background(), domains.Default, newAlias)
+	s.test.NoError(err)
+	s.test.Equal(http.StatusTemporaryRedirect, link.RedirectType)
+
+	// Ноль возвращает тип редиректа по умолчанию
+	marshalledUpdateReq, err = json.Marshal(update.Request{
+		URL:      testURL,
+		OldAlias: newAlias,
+		NewAlias: newAlias,
+		Options: options.Options{
+			RedirectType: &byDefault,
+		},
+	})
+	s.test.NoError(err)
+
+	updateReq, err = http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.test.NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	resetResp, err := s.httpClient.Do(updateReq)
+	s.test.NoError(err)
+	defer resetResp.Body.Close()
+
+	link, err = s.storage.GetLink(context.Background(), domains.Default, newAlias)
+	s.test.NoError(err)
+	s.test.Zero(link.RedirectType)
+}
+
+func (s *UrlShortenerSuite) TestUpdateKeepsPassword() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://intranet.example.com/docs"
+	testAlias := "docs"
+
+	req := save.Request{
+		URL:   testURL,
+		Alias: testAlias,
+		Options: options.Options{
+			Password: "secret",
+		},
+	}
+
+	marshalledSaveReq, err := json.Marshal(req)
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	defer saveResp.Body.Close()
+
+	link, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.True(password.Verify(link.PasswordHash, "secret"))
+
+	// Обновляем без пароля - пароль должен остаться прежним
+	temporary := http.StatusTemporaryRedirect
+	updateRequest := update.Request{
+		URL:      testURL,
+		OldAlias: testAlias,
+		NewAlias: testAlias,
+		Options: options.Options{
+			RedirectType: &temporary,
+		},
+	}
+
+	marshalledUpdateReq, err := json.Marshal(updateRequest)
+	s.test.NoError(err)
+
+	updateReq, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.test.NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	updateResp, err := s.httpClient.Do(updateReq)
+	s.test.NoError(err)
+	defer updateResp.Body.Close()
+
+	updated, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.Equal(link.PasswordHash, updated.PasswordHash)
+	s.test.Equal(http.StatusTemporaryRedirect, updated.RedirectType)
+}
+
+func (s *UrlShortenerSuite) TestUpdateRemovesPassword() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://intranet.example.com/handbook"
+	testAlias := "handbook"
+
+	req := save.Request{
+		URL:   testURL,
+		Alias: testAlias,
+		Options: options.Options{
+			Password: "secret",
+		},
+	}
+
+	marshalledSaveReq, err := json.Marshal(req)
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	defer saveResp.Body.Close()
+
+	link, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.NotEmpty(link.PasswordHash)
+
+	// Снимаем пароль явно
+	updateRequest := update.Request{
+		URL:            testURL,
+		OldAlias:       testAlias,
+		NewAlias:       testAlias,
+		RemovePassword: true,
+	}
+
+	marshalledUpdateReq, err := json.Marshal(updateRequest)
+	s.test.NoError(err)
+
+	updateReq, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.test.NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	updateResp, err := s.httpClient.Do(updateReq)
+	s.test.NoError(err)
+	defer updateResp.Body.Close()
+
+	var resp update.Response
+	s.test.NoError(json.NewDecoder(updateResp.Body).Decode(&resp))
+	s.test.Empty(resp.Error)
+
+	updated, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.Empty(updated.PasswordHash)
+}
+
+func (s *UrlShortenerSuite) TestPreviewCountsClicks() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://mail.google.com/"
+	testAlias := "mail"
+
+	req := save.Request{
+		URL:   testURL,
+		Alias: testAlias,
+	}
+
+	marshalledSaveReq, err := json.Marshal(req)
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	defer saveResp.Body.Close()
+
+	// Переходим по ссылке дважды, не следуя редиректу
+	for i := 0; i < 2; i++ {
+		location, err := api.GetRedirect(fmt.Sprintf("%s/%s", s.server.URL, testAlias))
+		s.test.NoError(err)
+		s.test.Equal(testURL, location)
+	}
+
+	previewResp, err := s.httpClient.Get(fmt.Sprintf("%s/%s+", s.server.URL, testAlias))
+	s.test.NoError(err)
+	defer previewResp.Body.Close()
+	s.test.Equal(http.StatusOK, previewResp.StatusCode)
+
+	body, err := io.ReadAll(previewResp.Body)
+	s.test.NoError(err)
+	s.test.Contains(string(body), testURL)
+	s.test.Contains(string(body), "Clicks: 2")
+
+	// Превью не считается переходом
+	link, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.False(link.CreatedAt.IsZero())
+
+	clicks, err := s.storage.CountClicks(context.Background(), link.ID)
+	s.test.NoError(err)
+	s.test.Equal(int64(2), clicks)
+}
+
+func (s *UrlShortenerSuite) TestSaveRulesAndRedirect() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://example.com/"
+	testAlias := "app"
+
+	req := save.Request{
+		URL:   testURL,
+		Alias: testAlias,
+		Options: options.Options{
+			Rules: []options.Rule{
+				{URL: "https://apps.apple.com/app", Platforms: []string{"ios"}},
+				{URL: "https://example.com/twitter", Query: map[string]string{"ref": "twitter"}},
+			},
+		},
+	}
+
+	marshalledSaveReq, err := json.Marshal(req)
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	defer saveResp.Body.Close()
+
+	link, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.Len(link.Rules, 2)
+	s.test.Equal([]string{"ios"}, link.Rules[0].Platforms)
+
+	// Правило по query-параметру
+	location, err := api.GetRedirect(fmt.Sprintf("%s/%s?ref=twitter", s.server.URL, testAlias))
+	s.test.NoError(err)
+	s.test.Equal("https://example.com/twitter", location)
+
+	// Ни одно правило не подошло - ссылка по умолчанию
+	location, err = api.GetRedirect(fmt.Sprintf("%s/%s", s.server.URL, testAlias))
+	s.test.NoError(err)
+	s.test.Equal(testURL, location)
+}
+
+func (s *UrlShortenerSuite) TestSaveSplitAndRedirect() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://example.com/"
+	testAlias := "landing"
+
+	req := save.Request{
+		URL:   testURL,
+		Alias: testAlias,
+		Options: options.Options{
+			Destinations: []options.Destination{
+				{Name: "a", URL: "https://example.com/a", Weight: 1},
+				{Name: "b", URL: "https://example.com/b", Weight: 0},
+			},
+			StickySplit: true,
+		},
+	}
+
+	marshalledSaveReq, err := json.Marshal(req)
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	defer saveResp.Body.Close()
+
+	link, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.Len(link.Destinations, 2)
+	s.test.True(link.StickySplit)
+
+	// Весь трафик уходит на вариант с ненулевым весом
+	location, err := api.GetRedirect(fmt.Sprintf("%s/%s", s.server.URL, testAlias))
+	s.test.NoError(err)
+	s.test.Equal("https://example.com/a", location)
+}
+
+func (s *UrlShortenerSuite) TestClickLimitConcurrent() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://example.com/download"
+	testAlias := "download"
+	maxClicks := 3
+
+	req := save.Request{
+		URL:   testURL,
+		Alias: testAlias,
+		Options: options.Options{
+			MaxClicks: &maxClicks,
+		},
+	}
+
+	marshalledSaveReq, err := json.Marshal(req)
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	defer saveResp.Body.Close()
+
+	// Параллельные переходы не должны превысить лимит
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		redirects int
+		gone      int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, status, _ := api.GetRedirectWithStatus(fmt.Sprintf("%s/%s", s.server.URL, testAlias))
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch status {
+			case http.StatusFound:
+				redirects++
+			case http.StatusGone:
+				gone++
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.test.Equal(maxClicks, redirects)
+	s.test.Equal(10-maxClicks, gone)
+
+	link, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.Equal(0, link.ClicksLeft)
+
+	clicks, err := s.storage.CountClicks(context.Background(), link.ID)
+	s.test.NoError(err)
+	s.test.Equal(int64(maxClicks), clicks)
+}
+
+func (s *UrlShortenerSuite) TestUpdateKeepsClickLimit() {
+	ctx := context.Background()
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://example.com/ebook"
+	testAlias := "ebook"
+	maxClicks := 3
+
+	_, err := s.storage.SaveURL(ctx, testURL, domains.Default, testAlias, storage.LinkOptions{MaxClicks: maxClicks})
+	s.Require().NoError(err)
+
+	_, status, err := api.GetRedirectWithStatus(fmt.Sprintf("%s/%s", s.server.URL, testAlias))
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusFound, status)
+
+	// Обновление без max_clicks в хранилище оставляет лимит и остаток
+	err = s.storage.UpdateURL(ctx, testURL, domains.Default, testAlias, testAlias,
+		storage.LinkOptions{Keep: storage.Keep{MaxClicks: true}})
+	s.Require().NoError(err)
+
+	link, err := s.storage.GetLink(ctx, domains.Default, testAlias)
+	s.Require().NoError(err)
+	s.test.Equal(maxClicks, link.MaxClicks)
+	s.test.Equal(maxClicks-1, link.ClicksLeft)
+
+	// Переименование через API тоже не снимает лимит
+	newAlias := "ebook2"
+	marshalledUpdateReq, err := json.Marshal(update.Request{
+		URL:      testURL,
+		OldAlias: testAlias,
+		NewAlias: newAlias,
+	})
+	s.Require().NoError(err)
+
+	updateReq, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.Require().NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	updateResp, err := s.httpClient.Do(updateReq)
+	s.Require().NoError(err)
+	defer updateResp.Body.Close()
+
+	link, err = s.storage.GetLink(ctx, domains.Default, newAlias)
+	s.Require().NoError(err)
+	s.test.Equal(maxClicks, link.MaxClicks)
+	s.test.Equal(maxClicks-1, link.ClicksLeft)
+
+	// Переданный лимит учитывает уже сделанные переходы
+	limit := 5
+	marshalledUpdateReq, err = json.Marshal(update.Request{
+		URL:      testURL,
+		OldAlias: newAlias,
+		NewAlias: newAlias,
+		Options:  options.Options{MaxClicks: &limit},
+	})
+	s.Require().NoError(err)
+
+	updateReq, err = http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.Require().NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	limitResp, err := s.httpClient.Do(updateReq)
+	s.Require().NoError(err)
+	defer limitResp.Body.Close()
+
+	link, err = s.storage.GetLink(ctx, domains.Default, newAlias)
+	s.Require().NoError(err)
+	s.test.Equal(limit, link.MaxClicks)
+	s.test.Equal(limit-1, link.ClicksLeft)
+}
+
+func (s *UrlShortenerSuite) TestUpdateActiveWindow() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testURL := "https://example.com/campaign"
+	testAlias := "campaign"
+	launch := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	req := save.Request{
+		URL:   testURL,
+		Alias: testAlias,
+		Options: options.Options{
+			ActiveFrom: &launch,
+		},
+	}
+
+	marshalledSaveReq, err := json.Marshal(req)
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	defer saveResp.Body.Close()
+
+	link, err := s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.NotNil(link.ActiveFrom)
+	s.test.True(launch.Equal(*link.ActiveFrom))
+
+	// До начала кампании ссылка не работает
+	_, status, err := api.GetRedirectWithStatus(fmt.Sprintf("%s/%s", s.server.URL, testAlias))
+	s.test.Error(err)
+	s.test.Equal(http.StatusNotFound, status)
+
+	// Обновление без active_from оставляет окно прежним
+	marshalledUpdateReq, err := json.Marshal(update.Request{
+		URL:      testURL,
+		OldAlias: testAlias,
+		NewAlias: testAlias,
+	})
+	s.test.NoError(err)
+
+	updateReq, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.test.NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	updateResp, err := s.httpClient.Do(updateReq)
+	s.test.NoError(err)
+	defer updateResp.Body.Close()
+
+	link, err = s.storage.GetLink(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.NotNil(link.ActiveFrom)
+
+	_, status, err = api.GetRedirectWithStatus(fmt.Sprintf("%s/%s", s.server.URL, testAlias))
+	s.test.Error(err)
+	s.test.Equal(http.StatusNotFound, status)
+
+	// Запускаем кампанию раньше, убирая active_from
+	marshalledUpdateReq, err = json.Marshal(update.Request{
+		URL:              testURL,
+		OldAlias:         testAlias,
+		NewAlias:         testAlias,
+		RemoveActiveFrom: true,
+	})
+	s.test.NoError(err)
+
+	updateReq, err = http.NewRequest(http.MethodPut, url, bytes.NewBuffer(marshalledUpdateReq))
+	s.test.NoError(err)
+	updateReq.Header.Set("Content-Type", contentType)
+
+	removeResp, err := s.httpClient.Do(updateReq)
+	s.test.NoError(err)
+	defer removeResp.Body.Close()
+
+	location, err := api.GetRedirect(fmt.Sprintf("%s/%s", s.server.URL, testAlias))
+	s.test.NoError(err)
+	s.test.Equal(testURL, location)
+}
+
+func (s *UrlShortenerSuite) TestSameAliasOnTwoDomains() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	testAlias := "sale"
+	primaryURL := "https://shop.example.com/sale"
+	brandURL := "https://brand-a.example.com/sale"
+
+	for _, req := range []save.Request{
+		{URL: primaryURL, Alias: testAlias},
+		{URL: brandURL, Alias: testAlias, Domain: brandDomain},
+	} {
+		marshalledSaveReq, err := json.Marshal(req)
+		s.test.NoError(err)
+
+		saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+		s.test.NoError(err)
+		saveReq.Header.Set("Content-Type", contentType)
+
+		saveResp, err := s.httpClient.Do(saveReq)
+		s.test.NoError(err)
+		defer saveResp.Body.Close()
+
+		var resp save.Response
+		s.test.NoError(json.NewDecoder(saveResp.Body).Decode(&resp))
+		s.test.Equal(response.StatusOK, resp.Status)
+		s.test.Equal(req.Domain, resp.Domain)
+	}
+
+	// Один alias - разные ссылки в зависимости от Host
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	for host, expected := range map[string]string{
+		"":                   primaryURL,
+		brandDomain:          brandURL,
+		brandDomain + ":443": brandURL,
+		"unknown.example":    primaryURL,
+	} {
+		redirectReq, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s", s.server.URL, testAlias), nil)
+		s.test.NoError(err)
+		if host != "" {
+			redirectReq.Host = host
+		}
+
+		redirectResp, err := client.Do(redirectReq)
+		s.test.NoError(err)
+		redirectResp.Body.Close()
+
+		s.test.Equal(expected, redirectResp.Header.Get("Location"), host)
+	}
+
+	// Неизвестный домен в API
+	marshalledSaveReq, err := json.Marshal(save.Request{URL: brandURL, Alias: testAlias, Domain: "go.other.com"})
+	s.test.NoError(err)
+
+	saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+	s.test.NoError(err)
+	saveReq.Header.Set("Content-Type", contentType)
+
+	saveResp, err := s.httpClient.Do(saveReq)
+	s.test.NoError(err)
+	defer saveResp.Body.Close()
+
+	var resp save.Response
+	s.test.NoError(json.NewDecoder(saveResp.Body).Decode(&resp))
+	s.test.Equal("unknown domain", resp.Error)
+
+	// Удаление на одном домене не трогает другой
+	deleteReq, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%s?domain=%s", url, testAlias, brandDomain), nil)
+	s.test.NoError(err)
+
+	deleteResp, err := s.httpClient.Do(deleteReq)
+	s.test.NoError(err)
+	defer deleteResp.Body.Close()
+
+	_, err = s.storage.GetURL(context.Background(), brandDomain, testAlias)
+	s.test.ErrorIs(err, storage.ErrUrlNotFound)
+
+	actualURL, err := s.storage.GetURL(context.Background(), domains.Default, testAlias)
+	s.test.NoError(err)
+	s.test.Equal(primaryURL, actualURL)
+}
+
+func (s *UrlShortenerSuite) TestScreening() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+
+	for rawURL, reason := range map[string]string{
+		"https://login." + blockedDomain + "/account": "domain login." + blockedDomain + " is blocklisted by " + blockedDomain,
+		"http://10.0.0.5/admin":                       "private network addresses are not allowed",
+		"ftp://files.example.com/setup.exe":           `scheme "ftp" is not allowed`,
+	} {
+		marshalledSaveReq, err := json.Marshal(save.Request{URL: rawURL, Alias: "screened"})
+		s.test.NoError(err)
+
+		saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+		s.test.NoError(err)
+		saveReq.Header.Set("Content-Type", contentType)
+
+		saveResp, err := s.httpClient.Do(saveReq)
+		s.test.NoError(err)
+
+		var resp save.Response
+		s.test.NoError(json.NewDecoder(saveResp.Body).Decode(&resp))
+		saveResp.Body.Close()
+
+		s.test.Equal(response.StatusError, resp.Status, rawURL)
+		s.test.Equal("url "+rawURL+" is not allowed: "+reason, resp.Error)
+	}
+
+	_, err := s.storage.GetURL(context.Background(), domains.Default, "screened")
+	s.test.ErrorIs(err, storage.ErrUrlNotFound)
+
+	// Ссылка, сохраненная до попадания домена в blocklist, больше не редиректит
+	_, err = s.storage.SaveURL(context.Background(), "https://"+blockedDomain+"/promo", domains.Default, "old-promo", storage.LinkOptions{})
+	s.test.NoError(err)
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	redirectResp, err := client.Get(fmt.Sprintf("%s/old-promo", s.server.URL))
+	s.test.NoError(err)
+	defer redirectResp.Body.Close()
+
+	s.test.Equal(http.StatusForbidden, redirectResp.StatusCode)
+	s.test.Empty(redirectResp.Header.Get("Location"))
+}
+
+func (s *UrlShortenerSuite) TestHealthCheckBrokenLinks() {
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/removed" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer destination.Close()
+
+	// Ссылки сохраняются напрямую: адреса httptest не проходят screening
+	_, err := s.storage.SaveURL(context.Background(), destination.URL+"/page", domains.Default, "alive", storage.LinkOptions{})
+	s.test.NoError(err)
+	_, err = s.storage.SaveURL(context.Background(), destination.URL+"/removed", domains.Default, "dead", storage.LinkOptions{})
+	s.test.NoError(err)
+	_, err = s.storage.SaveURL(context.Background(), destination.URL+"/page", brandDomain, "split", storage.LinkOptions{
+		Destinations: []storage.Destination{
+			{Name: "a", URL: destination.URL + "/page", Weight: 1},
+			{Name: "b", URL: destination.URL + "/removed", Weight: 1},
+		},
+	})
+	s.test.NoError(err)
+
+	checker := healthcheck.New(s.storage, healthcheck.Options{HostDelay: time.Millisecond})
+	s.test.NoError(checker.CheckAll(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	brokenResp, err := s.httpClient.Get(fmt.Sprintf("%s/url/broken", s.server.URL))
+	s.test.NoError(err)
+	defer brokenResp.Body.Close()
+
+	var resp broken.Response
+	s.test.NoError(json.NewDecoder(brokenResp.Body).Decode(&resp))
+	s.test.Equal(response.StatusOK, resp.Status)
+
+	s.test.Len(resp.Links, 2)
+	for _, link := range resp.Links {
+		s.test.Equal(destination.URL+"/removed", link.URL)
+		s.test.Equal(http.StatusNotFound, link.StatusCode)
+		s.test.False(link.CheckedAt.IsZero())
+	}
+	s.test.Equal("dead", resp.Links[0].Alias)
+	s.test.Equal("split", resp.Links[1].Alias)
+	s.test.Equal(brandDomain, resp.Links[1].Domain)
+
+	// Проверки адресов, на которые больше не ведёт ни одна ссылка, удаляются
+	s.test.NoError(s.storage.DeleteURL(context.Background(), domains.Default, "dead"))
+	s.test.NoError(s.storage.UpdateURL(context.Background(), destination.URL+"/page", brandDomain, "split", "split",
+		storage.LinkOptions{}))
+	s.test.NoError(checker.CheckAll(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	links, err := s.storage.BrokenLinks(context.Background())
+	s.test.NoError(err)
+	s.test.Empty(links)
+
+	// Осталась только проверка /page
+	pruned, err := s.storage.PruneChecks(context.Background(), nil)
+	s.test.NoError(err)
+	s.test.Equal(int64(1), pruned)
+}
+
+func (s *UrlShortenerSuite) TestRedirectLoop() {
+	url := fmt.Sprintf("%s/url", s.server.URL)
+	brandURL := func(alias string) string {
+		return fmt.Sprintf("https://%s/%s", brandDomain, alias)
+	}
+
+	for _, tc := range []struct {
+		req       save.Request
+		respError string
+	}{
+		// Ссылка на саму себя
+		{
+			req:       save.Request{URL: brandURL("one"), Alias: "one", Domain: brandDomain},
+			respError: "url " + brandURL("one") + " is not allowed: it points to the link itself",
+		},
+		// Цепочка без цикла разрешена
+		{
+			req: save.Request{URL: brandURL("two"), Alias: "one", Domain: brandDomain},
+		},
+		// Тот же alias на основном домене - другая ссылка
+		{
+			req: save.Request{URL: brandURL("one"), Alias: "one"},
+		},
+		// Цикл через другую ссылку
+		{
+			req:       save.Request{URL: brandURL("one"), Alias: "two", Domain: brandDomain},
+			respError: "url " + brandURL("one") + " is not allowed: it creates a redirect loop",
+		},
+	} {
+		marshalledSaveReq, err := json.Marshal(tc.req)
+		s.test.NoError(err)
+
+		saveReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(marshalledSaveReq))
+		s.test.NoError(err)
+		saveReq.Header.Set("Content-Type", contentType)
+
+		saveResp, err := s.httpClient.Do(saveReq)
+		s.test.NoError(err)
+
+		var resp save.Response
+		s.test.NoError(json.NewDecoder(saveResp.Body).Decode(&resp))
+		saveResp.Body.Close()
+
+		s.test.Equal(tc.respError, resp.Error, tc.req.URL)
+	}
+
+	_, err := s.storage.GetURL(context.Background(), brandDomain, "two")
+	s.test.ErrorIs(err, storage.ErrUrlNotFound)
+}
+
+func (s *UrlShortenerSuite) TestAPIKeys() {
+	cfg := &config.Config{HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"}}
+	server := httptest.NewServer(router.New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, s.storage, nil, nil, nil))
+	defer server.Close()
+
+	do := func(method, path, bearer string, body interface{}, resp interface{}) int {
+		var reader io.Reader
+		if body != nil {
+			marshalled, err := json.Marshal(body)
+			s.Require().NoError(err)
+			reader = bytes.NewReader(marshalled)
+		}
+
+		req, err := http.NewRequest(method, server.URL+path, reader)
+		s.Require().NoError(err)
+		req.Header.Set("Content-Type", contentType)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		} else {
+			req.SetBasicAuth("admin", "admin")
+		}
+
+		httpResp, err := http.DefaultClient.Do(req)
+		s.Require().NoError(err)
+		defer httpResp.Body.Close()
+
+		s.Require().NoError(json.NewDecoder(httpResp.Body).Decode(resp))
+
+		return httpResp.StatusCode
+	}
+
+	// Ключ создается через basic auth администратора
+	var created create.Response
+	s.test.Equal(http.StatusOK, do(http.MethodPost, "/admin/keys", "",
+		map[string]interface{}{"name": "ci", "scopes": []string{"links:write"}}, &created))
+	s.test.Equal(response.StatusOK, created.Status)
+	s.test.NotEmpty(created.Key)
+
+	// Ключ со scope links:write может сохранять ссылки
+	var saved save.Response
+	s.test.Equal(http.StatusOK, do(http.MethodPost, "/url", created.Key,
+		save.Request{URL: "https://example.com/ci", Alias: "ci"}, &saved))
+	s.test.Equal(response.StatusOK, saved.Status)
+
+	// Но не удалять их и не управлять ключами
+	var resp response.Response
+	s.test.Equal(http.StatusForbidden, do(http.MethodDelete, "/url/ci", created.Key, nil, &resp))
+	s.test.Equal("missing scope links:delete", resp.Error)
+	s.test.Equal(http.StatusForbidden, do(http.MethodGet, "/admin/keys", created.Key, nil, &resp))
+
+	var keys list.Response
+	s.test.Equal(http.StatusOK, do(http.MethodGet, "/admin/keys", "", nil, &keys))
+	s.test.Len(keys.Keys, 1)
+	s.test.Equal(created.Prefix, keys.Keys[0].Prefix)
+	s.test.NotNil(keys.Keys[0].LastUsedAt)
+	s.test.True(keys.Keys[0].Active)
+
+	// Отозванный ключ сразу перестает работать
+	s.test.Equal(http.StatusOK, do(http.MethodDelete, fmt.Sprintf("/admin/keys/%d", created.ID), "", nil, &resp))
+	s.test.Equal(response.StatusOK, resp.Status)
+
+	s.test.Equal(http.StatusUnauthorized, do(http.MethodPost, "/url", created.Key,
+		save.Request{URL: "https://example.com/ci2", Alias: "ci2"}, &resp))
+}
+
+func (s *UrlShortenerSuite) TestRateLimit() {
+	cfg := &config.Config{
+		HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"},
+		RateLimit: config.RateLimit{
+			Auth:     config.Limit{Requests: 4, Period: time.Minute},
+			API:      config.Limit{Requests: 1, Period: time.Minute},
+			Redirect: config.Limit{Requests: 2, Period: time.Minute},
+		},
+	}
+	server := httptest.NewServer(router.New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, s.storage, nil, nil, nil))
+	defer server.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	key, err := apikey.Generate()
+	s.Require().NoError(err)
+	_, err = s.storage.CreateAPIKey(context.Background(), storage.APIKey{
+		Name:      "limits",
+		Prefix:    apikey.Prefix(key),
+		Scopes:    []string{apikey.ScopeLinksWrite},
+		CreatedAt: time.Now(),
+	}, apikey.Hash(key))
+	s.Require().NoError(err)
+
+	saveLink := func(alias, bearer string) *http.Response {
+		marshalledSaveReq, err := json.Marshal(save.Request{URL: "https://example.com/" + alias, Alias: alias})
+		s.Require().NoError(err)
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/url", bytes.NewReader(marshalledSaveReq))
+		s.Require().NoError(err)
+		req.Header.Set("Content-Type", contentType)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		} else {
+			req.SetBasicAuth("admin", "admin")
+		}
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	// Лимит API считается по пользователю
+	resp := saveLink("limited", "")
+	s.test.Equal(http.StatusOK, resp.StatusCode)
+	s.test.Equal("1", resp.Header.Get("X-RateLimit-Limit"))
+	s.test.Equal("0", resp.Header.Get("X-RateLimit-Remaining"))
+
+	resp = saveLink("limited2", "")
+	s.test.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	s.test.Equal("60", resp.Header.Get("Retry-After"))
+
+	// У ключа API свой лимит, хотя запросы идут с того же IP
+	resp = saveLink("limited3", key)
+	s.test.Equal(http.StatusOK, resp.StatusCode)
+
+	// До авторизации запросы ограничиваются по IP клиента и общему для всех
+	// маршрутов API лимиту, так что подбор паролей тоже ограничен
+	for i, path := range []string{"/url/broken", "/admin/keys", "/audit", "/admin/webhooks"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		s.Require().NoError(err)
+		req.SetBasicAuth("admin", "wrong")
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		resp.Body.Close()
+
+		code := http.StatusTooManyRequests
+		if i == 0 {
+			code = http.StatusUnauthorized
+		}
+		s.test.Equal(code, resp.StatusCode, path)
+	}
+
+	// Редиректы ограничиваются отдельно, по IP клиента
+	for i, code := range []int{http.StatusFound, http.StatusFound, http.StatusTooManyRequests} {
+		resp, err := client.Get(server.URL + "/limited")
+		s.Require().NoError(err)
+		resp.Body.Close()
+
+		s.test.Equal(code, resp.StatusCode, i)
+	}
+}
+
+func (s *UrlShortenerSuite) TestAuditLog() {
+	cfg := &config.Config{HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"}}
+	server := httptest.NewServer(router.New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, s.storage, nil, nil, nil))
+	defer server.Close()
+
+	do := func(method, path string, body interface{}, resp interface{}) {
+		var reader io.Reader
+		if body != nil {
+			marshalled, err := json.Marshal(body)
+			s.Require().NoError(err)
+			reader = bytes.NewReader(marshalled)
+		}
+
+		req, err := http.NewRequest(method, server.URL+path, reader)
+		s.Require().NoError(err)
+		req.Header.Set("Content-Type", contentType)
+		req.SetBasicAuth("admin", "admin")
+
+		httpResp, err := http.DefaultClient.Do(req)
+		s.Require().NoError(err)
+		defer httpResp.Body.Close()
+
+		s.Require().Equal(http.StatusOK, httpResp.StatusCode)
+		s.Require().NoError(json.NewDecoder(httpResp.Body).Decode(resp))
+	}
+
+	const link = "https://example.com/audited"
+
+	var resp response.Response
+	do(http.MethodPost, "/url", save.Request{URL: link, Alias: "audited"}, &resp)
+	s.Require().Equal(response.StatusOK, resp.Status)
+	do(http.MethodPut, "/url", update.Request{URL: link, OldAlias: "audited", NewAlias: "audited2",
+		Options: options.Options{Password: "secret-password"}}, &resp)
+	s.Require().Equal(response.StatusOK, resp.Status)
+	do(http.MethodDelete, "/url/audited2", nil, &resp)
+	s.Require().Equal(response.StatusOK, resp.Status)
+
+	// Записи идут от новых к старым, с автором и состоянием ссылки до и после
+	var auditResp audit.Response
+	do(http.MethodGet, "/audit", nil, &auditResp)
+	s.Require().Len(auditResp.Entries, 3)
+
+	deleted, updated, created := auditResp.Entries[0], auditResp.Entries[1], auditResp.Entries[2]
+
+	s.test.Equal(storage.AuditCreate, created.Action)
+	s.test.Equal("admin", created.Actor)
+	s.test.Equal("audited", created.Alias)
+	s.test.Empty(created.Before)
+	s.test.JSONEq(`{"url":"`+link+`","alias":"audited"}`, string(created.After))
+	s.test.NotEmpty(created.RemoteAddr)
+
+	s.test.Equal(storage.AuditUpdate, updated.Action)
+	s.test.Equal("audited2", updated.Alias)
+	s.test.JSONEq(`{"url":"`+link+`","alias":"audited"}`, string(updated.Before))
+	s.test.JSONEq(`{"url":"`+link+`","alias":"audited2","password":true}`, string(updated.After))
+
+	s.test.Equal(storage.AuditDelete, deleted.Action)
+	s.test.JSONEq(string(updated.After), string(deleted.Before))
+	s.test.Empty(deleted.After)
+
+	// Фильтры
+	do(http.MethodGet, "/audit?action=delete&alias=audited2", nil, &auditResp)
+	s.test.Len(auditResp.Entries, 1)
+	do(http.MethodGet, fmt.Sprintf("/audit?before_id=%d", created.ID), nil, &auditResp)
+	s.test.Empty(auditResp.Entries)
+}
+
+func (s *UrlShortenerSuite) TestUpdateAliasTaken() {
+	cfg := &config.Config{HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"}}
+	server := httptest.NewServer(router.New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, s.storage, nil, nil, nil))
+	defer server.Close()
+
+	do := func(method, path string, body interface{}, resp interface{}) {
+		var reader io.Reader
+		if body != nil {
+			marshalled, err := json.Marshal(body)
+			s.Require().NoError(err)
+			reader = bytes.NewReader(marshalled)
+		}
+
+		req, err := http.NewRequest(method, server.URL+path, reader)
+		s.Require().NoError(err)
+		req.Header.Set("Content-Type", contentType)
+		req.SetBasicAuth("admin", "admin")
+
+		httpResp, err := http.DefaultClient.Do(req)
+		s.Require().NoError(err)
+		defer httpResp.Body.Close()
+
+		s.Require().NoError(json.NewDecoder(httpResp.Body).Decode(resp))
+	}
+
+	var resp response.Response
+	do(http.MethodPost, "/url", save.Request{URL: "https://example.com/first", Alias: "first"}, &resp)
+	s.Require().Equal(response.StatusOK, resp.Status)
+	do(http.MethodPost, "/url", save.Request{URL: "https://example.com/second", Alias: "second"}, &resp)
+	s.Require().Equal(response.StatusOK, resp.Status)
+
+	// Переименование в занятый алиас отклоняется
+	do(http.MethodPut, "/url", update.Request{URL: "https://example.com/first", OldAlias: "first", NewAlias: "second"}, &resp)
+	s.test.Equal(response.StatusError, resp.Status)
+	s.test.Equal("alias already exists", resp.Error)
+
+	link, err := s.storage.GetLink(context.Background(), domains.Default, "first")
+	s.Require().NoError(err)
+	s.test.Equal("https://example.com/first", link.URL)
+
+	// и не попадает в журнал
+	var auditResp audit.Response
+	do(http.MethodGet, "/audit?action=update", nil, &auditResp)
+	s.test.Empty(auditResp.Entries)
+}
+
+func (s *UrlShortenerSuite) TestWebhooks() {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{
+		HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"},
+		Webhooks:   config.Webhooks{Enabled: true},
+	}
+	server := httptest.NewServer(router.New(log, cfg, s.storage, nil, nil, nil))
+	defer server.Close()
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+
+	var (
+		mu  sync.Mutex
+		got []received
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		got = append(got, received{header: r.Header, body: body})
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	do := func(method, path string, body interface{}, resp interface{}) {
+		var reader io.Reader
+		if body != nil {
+			marshalled, err := json.Marshal(body)
+			s.Require().NoError(err)
+			reader = bytes.NewReader(marshalled)
+		}
+
+		req, err := http.NewRequest(method, server.URL+path, reader)
+		s.Require().NoError(err)
+		req.Header.Set("Content-Type", contentType)
+		req.SetBasicAuth("admin", "admin")
+
+		httpResp, err := client.Do(req)
+		s.Require().NoError(err)
+		defer httpResp.Body.Close()
+
+		s.Require().Equal(http.StatusOK, httpResp.StatusCode)
+		s.Require().NoError(json.NewDecoder(httpResp.Body).Decode(resp))
+	}
+
+	var hook subscribe.Response
+	do(http.MethodPost, "/admin/webhooks", subscribe.Request{
+		URL:    receiver.URL,
+		Events: []string{webhooks.EventLinkCreated, webhooks.EventLinkClicked},
+	}, &hook)
+	s.Require().Equal(response.StatusOK, hook.Status)
+	s.Require().NotEmpty(hook.Secret)
+
+	var resp response.Response
+	do(http.MethodPost, "/url", save.Request{URL: "https://example.com/hooked", Alias: "hooked"}, &resp)
+	s.Require().Equal(response.StatusOK, resp.Status)
+
+	redirectResp, err := client.Get(server.URL + "/hooked")
+	s.Require().NoError(err)
+	redirectResp.Body.Close()
+	s.Require().Equal(http.StatusFound, redirectResp.StatusCode)
+
+	// События лежат в outbox, пока их не отправит sender
+	s.Require().Empty(got)
+
+	sender := webhooks.NewSender(s.storage, webhooks.NewDispatcher(log, s.storage), webhooks.Options{})
+	s.Require().NoError(sender.DeliverDue(context.Background(), log))
+
+	// Каждое событие подписано секретом вебхука
+	s.Require().Len(got, 2)
+	events := make([]string, 0, len(got))
+	for _, r := range got {
+		timestamp, err := strconv.ParseInt(r.header.Get(webhooks.HeaderTimestamp), 10, 64)
+		s.Require().NoError(err)
+		s.test.Equal(webhooks.Sign(hook.Secret, timestamp, r.body), r.header.Get(webhooks.HeaderSignature))
+
+		var payload webhooks.Payload
+		s.Require().NoError(json.Unmarshal(r.body, &payload))
+		s.test.Equal(r.header.Get(webhooks.HeaderEvent), payload.Event)
+		s.test.Equal(r.header.Get(webhooks.HeaderID), payload.ID)
+		events = append(events, payload.Event)
+	}
+	s.test.ElementsMatch([]string{webhooks.EventLinkCreated, webhooks.EventLinkClicked}, events)
+
+	// Доставки видны в админке
+	var deliveriesResp deliveries.Response
+	do(http.MethodGet, fmt.Sprintf("/admin/webhooks/%d/deliveries?status=delivered", hook.ID), nil, &deliveriesResp)
+	s.Require().Len(deliveriesResp.Deliveries, 2)
+	for _, d := range deliveriesResp.Deliveries {
+		s.test.Equal(http.StatusOK, d.LastStatusCode)
+		s.test.NotNil(d.DeliveredAt)
+	}
+
+	// После отписки новые события не ставятся в очередь
+	do(http.MethodDelete, fmt.Sprintf("/admin/webhooks/%d", hook.ID), nil, &resp)
+	s.Require().Equal(response.StatusOK, resp.Status)
+
+	redirectResp, err = client.Get(server.URL + "/hooked")
+	s.Require().NoError(err)
+	redirectResp.Body.Close()
+	s.Require().NoError(sender.DeliverDue(context.Background(), log))
+	s.test.Len(got, 2)
+}
+
+func (s *UrlShortenerSuite) TestWebhookEventsInTransaction() {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	_, err := s.storage.CreateWebhook(ctx, storage.Webhook{
+		URL:       "https://hooks.example.com/links",
+		Secret:    "secret",
+		Events:    []string{webhooks.EventLinkCreated, webhooks.EventLinkUpdated, webhooks.EventLinkDeleted},
+		CreatedAt: time.Now(),
+	})
+	s.Require().NoError(err)
+
+	dispatcher := webhooks.NewDispatcher(log, s.storage)
+	event := func(name string) storage.Event {
+		e, ok, err := dispatcher.Event(name, map[string]string{"alias": "outbox"})
+		s.Require().NoError(err)
+		s.Require().True(ok)
+		return e
+	}
+	pending := func() []storage.PendingDelivery {
+		due, err := s.storage.DueDeliveries(ctx, time.Now().Add(time.Minute), 100)
+		s.Require().NoError(err)
+		return due
+	}
+
+	// Событие ставится в очередь вместе со ссылкой
+	_, err = s.storage.SaveURL(ctx, "https://example.com/outbox", domains.Default, "outbox", storage.LinkOptions{},
+		event(webhooks.EventLinkCreated))
+	s.Require().NoError(err)
+	s.Require().Len(pending(), 1)
+
+	// Неудачное изменение не оставляет событий
+	_, err = s.storage.SaveURL(ctx, "https://example.com/other", domains.Default, "outbox", storage.LinkOptions{},
+		event(webhooks.EventLinkCreated))
+	s.Require().ErrorIs(err, storage.ErrUrlExists)
+	err = s.storage.UpdateURL(ctx, "https://example.com/outbox", domains.Default, "missing", "missing", storage.LinkOptions{},
+		event(webhooks.EventLinkUpdated))
+	s.Require().ErrorIs(err, storage.ErrUrlNotFound)
+	err = s.storage.DeleteURL(ctx, domains.Default, "missing", event(webhooks.EventLinkDeleted))
+	s.Require().ErrorIs(err, storage.ErrUrlNotFound)
+	s.Require().Len(pending(), 1)
+
+	// Изменение через API кладёт в событие состояние ссылки после него
+	cfg := &config.Config{
+		HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"},
+		Webhooks:   config.Webhooks{Enabled: true},
+	}
+	server := httptest.NewServer(router.New(log, cfg, s.storage, nil, nil, nil))
+	defer server.Close()
+
+	activeUntil := time.Now().Add(time.Hour).In(time.FixedZone("MSK", 3*60*60))
+	body, err := json.Marshal(update.Request{
+		URL:      "https://example.com/outbox",
+		OldAlias: "outbox",
+		NewAlias: "outbox",
+		Options:  options.Options{Password: "secret-password", ActiveUntil: &activeUntil},
+	})
+	s.Require().NoError(err)
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/url", bytes.NewReader(body))
+	s.Require().NoError(err)
+	req.Header.Set("Content-Type", contentType)
+	req.SetBasicAuth("admin", "admin")
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	resp.Body.Close()
+
+	due := pending()
+	s.Require().Len(due, 2)
+	s.Require().Equal(webhooks.EventLinkUpdated, due[1].Event)
+
+	var payload struct {
+		Data struct {
+			After json.RawMessage `json:"after"`
+		} `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal([]byte(due[1].Payload), &payload))
+
+	stored, err := json.Marshal(auditlog.New(log, s.storage, nil).Snapshot(ctx, domains.Default, "outbox"))
+	s.Require().NoError(err)
+	s.test.JSONEq(string(stored), string(payload.Data.After))
+}
+
+func (s *UrlShortenerSuite) TestExpiredAfterRestart() {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := s.storage.CreateWebhook(ctx, storage.Webhook{
+		URL:       "https://hooks.example.com/expired",
+		Secret:    "secret",
+		Events:    []string{webhooks.EventLinkExpired},
+		CreatedAt: now,
+	})
+	s.Require().NoError(err)
+
+	// Первый запуск только запоминает отметку
+	sender := webhooks.NewSender(s.storage, webhooks.NewDispatcher(log, s.storage), webhooks.Options{})
+	s.Require().NoError(sender.PublishExpired(ctx, now))
+
+	watermark, err := s.storage.Watermark(ctx, webhooks.EventLinkExpired)
+	s.Require().NoError(err)
+	s.test.True(now.Equal(watermark))
+
+	// Ссылка истекает, пока сервис выключен
+	activeUntil := now.Add(time.Minute)
+	_, err = s.storage.SaveURL(ctx, "https://example.com/sale", domains.Default, "sale",
+		storage.LinkOptions{ActiveUntil: &activeUntil})
+	s.Require().NoError(err)
+
+	// После перезапуска событие всё равно ставится в очередь, и только один раз
+	restarted := webhooks.NewSender(s.storage, webhooks.NewDispatcher(log, s.storage), webhooks.Options{})
+	s.Require().NoError(restarted.PublishExpired(ctx, now.Add(time.Hour)))
+	s.Require().NoError(restarted.PublishExpired(ctx, now.Add(2*time.Hour)))
+
+	due, err := s.storage.DueDeliveries(ctx, now.Add(3*time.Hour), 100)
+	s.Require().NoError(err)
+	s.Require().Len(due, 1)
+	s.test.Equal(webhooks.EventLinkExpired, due[0].Event)
+	s.test.Contains(due[0].Payload, `"alias":"sale"`)
+}
+
+func (s *UrlShortenerSuite) TestMetrics() {
+	cfg := &config.Config{
+		HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"},
+		Metrics:    config.Metrics{Enabled: true},
+	}
+	server := httptest.NewServer(router.New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, s.storage, nil, nil, nil))
+	defer server.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	marshalledSaveReq, err := json.Marshal(save.Request{URL: "https://example.com/measured", Alias: "measured"})
+	s.Require().NoError(err)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/url", bytes.NewReader(marshalledSaveReq))
+	s.Require().NoError(err)
+	req.Header.Set("Content-Type", contentType)
+	req.SetBasicAuth("admin", "admin")
+
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	resp.Body.Close()
+
+	for _, path := range []string{"/measured", "/unknown-alias"} {
+		resp, err := client.Get(server.URL + path)
+		s.Require().NoError(err)
+		resp.Body.Close()
+	}
+
+	resp, err = client.Get(server.URL + "/metrics")
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	s.Require().NoError(err)
+
+	// Запросы считаются по шаблону маршрута, а не по алиасу
+	s.test.Contains(string(body), `url_shortener_http_requests_total{method="GET",route="/{alias}",status="302"}`)
+	s.test.Contains(string(body), `url_shortener_http_requests_total{method="POST",route="/url/",status="200"}`)
+	s.test.NotContains(string(body), "measured")
+	s.test.Contains(string(body), `url_shortener_redirects_total{result="hit"}`)
+	s.test.Contains(string(body), `url_shortener_redirects_total{result="miss"}`)
+	s.test.Contains(string(body), `url_shortener_storage_operation_duration_seconds_count{method="SaveURL"}`)
+	s.test.Contains(string(body), "go_memstats_alloc_bytes")
+}
+
+func (s *UrlShortenerSuite) TestTracing() {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(previous)
+
+	var logs bytes.Buffer
+	cfg := &config.Config{HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"}}
+	server := httptest.NewServer(router.New(slog.New(slog.NewJSONHandler(&logs, nil)), cfg, s.storage, nil, nil, nil))
+	defer server.Close()
+
+	_, err := s.storage.SaveURL(context.Background(), "https://example.com/traced", domains.Default, "traced", storage.LinkOptions{})
+	s.Require().NoError(err)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/traced", nil)
+	s.Require().NoError(err)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Require().Equal(http.StatusFound, resp.StatusCode)
+
+	// Спаны SQLite — дочерние для спана запроса, в том же трейсе
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() == traceID {
+			spans[span.Name()] = span
+		}
+	}
+
+	serverSpan, ok := spans["GET /{alias}"]
+	s.Require().True(ok)
+	for _, name := range []string{"sqlite.GetLink", "sqlite.RecordClick"} {
+		span, ok := spans[name]
+		s.Require().True(ok, name)
+		s.test.Equal(serverSpan.SpanContext().SpanID(), span.Parent().SpanID(), name)
+	}
+
+	// trace_id попадает в логи рядом с request_id
+	s.test.Contains(logs.String(), `"trace_id":"`+traceID+`"`)
+}
+
+func (s *UrlShortenerSuite) TestHealth() {
+	probes := health.New(time.Second)
+	probes.AddCheck("database", s.storage.Ping)
+	probes.AddCheck("migrations", s.storage.CheckMigrations)
+
+	cfg := &config.Config{HTTPServer: config.HTTPServer{Login: "admin", Password: "admin"}}
+	server := httptest.NewServer(router.New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, s.storage, nil, nil, probes))
+	defer server.Close()
+
+	ready := func() (int, health.Report) {
+		resp, err := http.Get(server.URL + "/readyz")
+		s.Require().NoError(err)
+		defer resp.Body.Close()
+
+		var report health.Report
+		s.Require().NoError(json.NewDecoder(resp.Body).Decode(&report))
+
+		return resp.StatusCode, report
+	}
+
+	// Проверки открыты без авторизации
+	resp, err := http.Get(server.URL + "/healthz")
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.test.Equal(http.StatusOK, resp.StatusCode)
+
+	code, report := ready()
+	s.test.Equal(http.StatusOK, code)
+	s.test.Equal(health.StatusOK, report.Components["database"].Status)
+	s.test.Equal(health.StatusOK, report.Components["migrations"].Status)
+
+	// Во время остановки readiness падает, а liveness — нет
+	probes.Shutdown()
+
+	code, report = ready()
+	s.test.Equal(http.StatusServiceUnavailable, code)
+	s.test.Equal(health.StatusFailing, report.Components[health.ComponentShutdown].Status)
+
+	resp, err = http.Get(server.URL + "/healthz")
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.test.Equal(http.StatusOK, resp.StatusCode)
+}